@@ -8,6 +8,7 @@ import (
 	"runtime"
 
 	"github.com/codeready-toolchain/host-operator/pkg/apis"
+	toolchainconfig "github.com/codeready-toolchain/host-operator/pkg/config"
 	"github.com/codeready-toolchain/host-operator/pkg/controller"
 	"github.com/codeready-toolchain/host-operator/pkg/templates/nstemplatetiers"
 	"github.com/codeready-toolchain/host-operator/version"
@@ -32,6 +33,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/signals"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
 )
 
 // Change below variables to serve metrics on different host or port.
@@ -120,8 +122,22 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Setup Scheme for the KubeFedClusters, separately from the resources above so that
+	// no custom resource metrics are generated for them
+	if err := v1beta1.AddToScheme(mgr.GetScheme()); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
+	// Setup the toolchain configuration loader shared by all Controllers, which is refreshed when the ConfigMap changes
+	configLoader := toolchainconfig.NewLoader(mgr.GetClient(), mgr.GetEventRecorderFor("toolchain-config"))
+	if err := configLoader.InvalidateOnChange(mgr.GetCache()); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
 	// Setup all Controllers
-	if err := controller.AddToManager(mgr); err != nil {
+	if err := controller.AddToManager(mgr, configLoader); err != nil {
 		log.Error(err, "")
 		os.Exit(1)
 	}
@@ -0,0 +1,88 @@
+package banneduser
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base32"
+	"strings"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// BannedUserLabelKey the label of the ConfigMaps which make up the registry of banned users
+	BannedUserLabelKey = "toolchain.dev.openshift.com/banned-user"
+	// EmailHashLabelKey the label with the hash of the email address of the banned user
+	EmailHashLabelKey = "toolchain.dev.openshift.com/email-hash"
+)
+
+// Ban adds the user with the given ID and email address to the registry of banned users, in the given namespace.
+// Each banned user is recorded in a ConfigMap labelled with the user ID and the hash of the email address,
+// so that further signups from the same user can be refused without keeping the email address itself.
+func Ban(cl client.Client, namespace, userID, email string) error {
+	labels := map[string]string{
+		BannedUserLabelKey: "true",
+		toolchainv1alpha1.MasterUserRecordUserIDLabelKey: userID,
+	}
+	if email != "" {
+		labels[EmailHashLabelKey] = EmailHash(email)
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "banned-" + userID,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+	}
+	if err := cl.Create(context.TODO(), cm); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// Unban removes the user with the given ID from the registry of banned users, in the given namespace
+func Unban(cl client.Client, namespace, userID string) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "banned-" + userID,
+			Namespace: namespace,
+		},
+	}
+	if err := cl.Delete(context.TODO(), cm); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// IsBanned returns true if the given user ID or email address is in the registry of banned users of the given namespace
+func IsBanned(cl client.Client, namespace, userID, email string) (bool, error) {
+	banned, err := exists(cl, namespace, toolchainv1alpha1.MasterUserRecordUserIDLabelKey, userID)
+	if err != nil || banned || email == "" {
+		return banned, err
+	}
+	return exists(cl, namespace, EmailHashLabelKey, EmailHash(email))
+}
+
+// exists returns true if the registry of banned users has an entry with the given label
+func exists(cl client.Client, namespace, key, value string) (bool, error) {
+	cms := &corev1.ConfigMapList{}
+	if err := cl.List(context.TODO(), cms, client.InNamespace(namespace), client.MatchingLabels(map[string]string{
+		BannedUserLabelKey: "true",
+		key:                value,
+	})); err != nil {
+		return false, err
+	}
+	return len(cms.Items) > 0, nil
+}
+
+// EmailHash returns the SHA-256 hash of the given email address (case-insensitive), in a form which can be used as
+// a label value (ie, base32-encoded, since the hex-encoded hash would exceed the 63 characters allowed in a label value)
+func EmailHash(email string) string {
+	hash := sha256.Sum256([]byte(strings.ToLower(email)))
+	return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(hash[:]))
+}
@@ -0,0 +1,134 @@
+package banneduser
+
+import (
+	"context"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestBan(t *testing.T) {
+
+	t.Run("ban user", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+
+		// when
+		err := Ban(cl, test.HostOperatorNs, "123", "foo@redhat.com")
+
+		// then
+		require.NoError(t, err)
+		cm := &corev1.ConfigMap{}
+		err = cl.Get(context.TODO(), types.NamespacedName{Namespace: test.HostOperatorNs, Name: "banned-123"}, cm)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			BannedUserLabelKey: "true",
+			toolchainv1alpha1.MasterUserRecordUserIDLabelKey: "123",
+			EmailHashLabelKey: EmailHash("foo@redhat.com"),
+		}, cm.Labels)
+	})
+
+	t.Run("ban user twice", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		err := Ban(cl, test.HostOperatorNs, "123", "foo@redhat.com")
+		require.NoError(t, err)
+
+		// when
+		err = Ban(cl, test.HostOperatorNs, "123", "foo@redhat.com")
+
+		// then
+		require.NoError(t, err)
+	})
+}
+
+func TestUnban(t *testing.T) {
+
+	t.Run("unban user", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		err := Ban(cl, test.HostOperatorNs, "123", "foo@redhat.com")
+		require.NoError(t, err)
+
+		// when
+		err = Unban(cl, test.HostOperatorNs, "123")
+
+		// then
+		require.NoError(t, err)
+		banned, err := IsBanned(cl, test.HostOperatorNs, "123", "foo@redhat.com")
+		require.NoError(t, err)
+		assert.False(t, banned)
+	})
+
+	t.Run("unban user who is not banned", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+
+		// when
+		err := Unban(cl, test.HostOperatorNs, "123")
+
+		// then
+		require.NoError(t, err)
+	})
+}
+
+func TestEmailHash(t *testing.T) {
+	// when
+	hash := EmailHash("Foo@RedHat.com")
+
+	// then
+	assert.Equal(t, EmailHash("foo@redhat.com"), hash)
+	assert.Len(t, hash, 52)
+	assert.Regexp(t, "^[a-z2-7]+$", hash)
+}
+
+func TestIsBanned(t *testing.T) {
+	// given
+	cl := test.NewFakeClient(t)
+	err := Ban(cl, test.HostOperatorNs, "123", "foo@redhat.com")
+	require.NoError(t, err)
+	err = Ban(cl, test.HostOperatorNs, "456", "")
+	require.NoError(t, err)
+
+	t.Run("banned user ID", func(t *testing.T) {
+		// when
+		banned, err := IsBanned(cl, test.HostOperatorNs, "456", "bar@redhat.com")
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, banned)
+	})
+
+	t.Run("banned email", func(t *testing.T) {
+		// when
+		banned, err := IsBanned(cl, test.HostOperatorNs, "789", "FOO@redhat.com")
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, banned)
+	})
+
+	t.Run("not banned", func(t *testing.T) {
+		// when
+		banned, err := IsBanned(cl, test.HostOperatorNs, "789", "bar@redhat.com")
+
+		// then
+		require.NoError(t, err)
+		assert.False(t, banned)
+	})
+
+	t.Run("not banned without email", func(t *testing.T) {
+		// when
+		banned, err := IsBanned(cl, test.HostOperatorNs, "789", "")
+
+		// then
+		require.NoError(t, err)
+		assert.False(t, banned)
+	})
+}
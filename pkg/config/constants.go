@@ -3,8 +3,42 @@ package config
 const (
 	ToolchainConfigMapName = "toolchain-saas-config"
 
-	ToolchainConfigMapUserApprovalPolicy = "user-approval-policy"
+	ToolchainConfigMapUserApprovalPolicy         = "user-approval-policy"
+	ToolchainConfigMapAutoApprovalAllowedDomains = "auto-approval-allowed-domains" // comma-separated
+	ToolchainConfigMapAutoApprovalDeniedDomains  = "auto-approval-denied-domains"  // comma-separated
+	ToolchainConfigMapMaxActiveUsers             = "max-active-users"
+	ToolchainConfigMapMaxUsersPerCluster         = "max-users-per-cluster"
+	ToolchainConfigMapPlacementRules             = "placement-rules"
+	// ToolchainConfigMapDefaultTier the key for the name of the NSTemplateTier used when provisioning new users
+	ToolchainConfigMapDefaultTier = "default-tier"
+	// ToolchainConfigMapUserSelectableTiers the key for the comma-separated names of the NSTemplateTiers which
+	// users can request without being approved by an admin
+	ToolchainConfigMapUserSelectableTiers = "user-selectable-tiers"
+	// ToolchainConfigMapTierUpdateMaxUnavailable the key for the maximum number of MasterUserRecords of a tier which
+	// can be updated at the same time when the NSTemplateTier changes
+	ToolchainConfigMapTierUpdateMaxUnavailable      = "tier-update-max-unavailable"
+	ToolchainConfigMapUserLifetimeDays              = "user-lifetime-days"        // can be overridden per tier with `user-lifetime-days.<tier>`
+	ToolchainConfigMapForbiddenUsernames            = "forbidden-usernames"       // comma-separated, added to the default ones
+	ToolchainConfigMapNotificationSMTPAddress       = "notification-smtp-address" // host:port
+	ToolchainConfigMapNotificationSender            = "notification-sender"
+	ToolchainConfigMapNotificationExpiryWarningDays = "notification-expiry-warning-days"
+	ToolchainConfigMapMigrationMaxInFlight          = "migration-max-in-flight"
+	ToolchainConfigMapLostClusterTimeoutHours       = "lost-cluster-timeout-hours"
+	ToolchainConfigMapDriftAutoRepair               = "drift-auto-repair" // true (by default) or false
+	ToolchainConfigMapDriftDetectionPeriodMinutes   = "drift-detection-period-minutes"
+
+	NotificationSecretName     = "toolchain-notification-smtp"
+	NotificationSecretUsername = "username"
+	NotificationSecretPassword = "password"
 
 	UserApprovalPolicyManual    = "manual"
 	UserApprovalPolicyAutomatic = "automatic"
+
+	DefaultTierName = "basic"
+
+	DefaultTierUpdateMaxUnavailable = 5
+
+	DefaultMigrationMaxInFlight = 5
+
+	DefaultDriftDetectionPeriodMinutes = 10
 )
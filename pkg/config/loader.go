@@ -0,0 +1,134 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("config")
+
+const (
+	// invalidConfigReason the reason of the event recorded on the ConfigMap when some settings are invalid
+	invalidConfigReason = "InvalidToolchainConfig"
+	// configLoadedReason the reason of the event recorded on the ConfigMap when the configuration is (re)loaded
+	configLoadedReason = "ToolchainConfigLoaded"
+)
+
+// Loader loads the toolchain configuration from the `toolchain-saas-config` ConfigMap, and keeps it in a cache
+// until it is invalidated (ie, when the ConfigMap changed). A single Loader is shared by all the controllers.
+type Loader struct {
+	client   client.Client
+	recorder record.EventRecorder
+	mu       sync.RWMutex
+	cache    map[string]ToolchainConfig
+}
+
+// NewLoader returns a new Loader which uses the given client to read the ConfigMap, and the given recorder
+// to report the invalid settings and the settings in effect as events on the ConfigMap
+func NewLoader(cl client.Client, recorder record.EventRecorder) *Loader {
+	return &Loader{
+		client:   cl,
+		recorder: recorder,
+		cache:    map[string]ToolchainConfig{},
+	}
+}
+
+// Load returns the toolchain configuration of the given namespace. If the ConfigMap does not exist,
+// then the configuration has the default values for all the settings.
+// The invalid settings and the settings in effect are logged and recorded as events on the ConfigMap when the
+// configuration is (re)loaded.
+func (l *Loader) Load(namespace string) (ToolchainConfig, error) {
+	l.mu.RLock()
+	cfg, found := l.cache[namespace]
+	l.mu.RUnlock()
+	if found {
+		return cfg, nil
+	}
+	cm := &corev1.ConfigMap{}
+	exists := true
+	if err := l.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: ToolchainConfigMapName}, cm); err != nil {
+		if !errors.IsNotFound(err) {
+			return ToolchainConfig{}, err
+		}
+		exists = false
+	}
+	cfg = NewToolchainConfig(cm.Data)
+	settings := formatSettings(cfg.Settings())
+	if err := cfg.Validate(); err != nil {
+		log.Error(err, "invalid toolchain configuration", "Namespace", namespace)
+		if exists {
+			l.recorder.Eventf(cm, corev1.EventTypeWarning, invalidConfigReason, "Invalid settings are ignored: %s", err.Error())
+		}
+	}
+	log.Info("toolchain configuration loaded", "Namespace", namespace, "Settings", settings)
+	if exists {
+		l.recorder.Eventf(cm, corev1.EventTypeNormal, configLoadedReason, "Settings in effect: %s", settings)
+	}
+	l.mu.Lock()
+	l.cache[namespace] = cfg
+	l.mu.Unlock()
+	return cfg, nil
+}
+
+// Invalidate removes the toolchain configuration of the given namespace from the cache
+func (l *Loader) Invalidate(namespace string) {
+	l.mu.Lock()
+	delete(l.cache, namespace)
+	l.mu.Unlock()
+}
+
+// InvalidateOnChange invalidates the cached toolchain configuration whenever the toolchain ConfigMap is created,
+// updated or deleted, as notified by the given informers (ie, the cache of the manager)
+func (l *Loader) InvalidateOnChange(informers cache.Informers) error {
+	informer, err := informers.GetInformer(&corev1.ConfigMap{})
+	if err != nil {
+		return err
+	}
+	informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: l.invalidateIfToolchainConfigMap,
+		UpdateFunc: func(_, newObj interface{}) {
+			l.invalidateIfToolchainConfigMap(newObj)
+		},
+		DeleteFunc: l.invalidateIfToolchainConfigMap,
+	})
+	return nil
+}
+
+// invalidateIfToolchainConfigMap invalidates the cached toolchain configuration of the namespace of the given
+// object, if the latter is the toolchain ConfigMap
+func (l *Loader) invalidateIfToolchainConfigMap(obj interface{}) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	cm, err := meta.Accessor(obj)
+	if err != nil {
+		log.Error(err, "unable to get the metadata of the ConfigMap")
+		return
+	}
+	if cm.GetName() == ToolchainConfigMapName {
+		l.Invalidate(cm.GetNamespace())
+	}
+}
+
+// formatSettings returns the given settings as a list of `key=value` pairs, sorted by key
+func formatSettings(settings map[string]string) string {
+	pairs := make([]string, 0, len(settings))
+	for key, value := range settings {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
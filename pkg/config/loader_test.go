@@ -0,0 +1,222 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestLoader(t *testing.T) {
+
+	newConfigMap := func(policy string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ToolchainConfigMapName,
+				Namespace: test.HostOperatorNs,
+			},
+			Data: map[string]string{
+				ToolchainConfigMapUserApprovalPolicy: policy,
+			},
+		}
+	}
+
+	t.Run("load and cache config", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newConfigMap(UserApprovalPolicyAutomatic))
+		loader := NewLoader(cl, record.NewFakeRecorder(10))
+		cfg, err := loader.Load(test.HostOperatorNs)
+		require.NoError(t, err)
+		require.Equal(t, UserApprovalPolicyAutomatic, cfg.UserApprovalPolicy())
+		gets := 0
+		cl.MockGet = func(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+			gets++
+			return cl.Client.Get(ctx, key, obj)
+		}
+
+		// when
+		cfg, err = loader.Load(test.HostOperatorNs)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, UserApprovalPolicyAutomatic, cfg.UserApprovalPolicy())
+		assert.Equal(t, 0, gets)
+	})
+
+	t.Run("reload config after invalidation", func(t *testing.T) {
+		// given
+		cm := newConfigMap(UserApprovalPolicyAutomatic)
+		cl := test.NewFakeClient(t, cm)
+		loader := NewLoader(cl, record.NewFakeRecorder(10))
+		_, err := loader.Load(test.HostOperatorNs)
+		require.NoError(t, err)
+		cm.Data[ToolchainConfigMapUserApprovalPolicy] = UserApprovalPolicyManual
+		err = cl.Update(context.TODO(), cm)
+		require.NoError(t, err)
+
+		// when
+		loader.Invalidate(test.HostOperatorNs)
+		cfg, err := loader.Load(test.HostOperatorNs)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, UserApprovalPolicyManual, cfg.UserApprovalPolicy())
+	})
+
+	t.Run("reload config after the config map changed", func(t *testing.T) {
+		// given
+		cm := newConfigMap(UserApprovalPolicyAutomatic)
+		cl := test.NewFakeClient(t, cm)
+		loader := NewLoader(cl, record.NewFakeRecorder(10))
+		_, err := loader.Load(test.HostOperatorNs)
+		require.NoError(t, err)
+		cm.Data[ToolchainConfigMapUserApprovalPolicy] = UserApprovalPolicyManual
+		err = cl.Update(context.TODO(), cm)
+		require.NoError(t, err)
+
+		// when
+		loader.invalidateIfToolchainConfigMap(cm)
+		cfg, err := loader.Load(test.HostOperatorNs)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, UserApprovalPolicyManual, cfg.UserApprovalPolicy())
+	})
+
+	t.Run("keep config when another config map changed", func(t *testing.T) {
+		// given
+		cm := newConfigMap(UserApprovalPolicyAutomatic)
+		cl := test.NewFakeClient(t, cm)
+		loader := NewLoader(cl, record.NewFakeRecorder(10))
+		_, err := loader.Load(test.HostOperatorNs)
+		require.NoError(t, err)
+		cm.Data[ToolchainConfigMapUserApprovalPolicy] = UserApprovalPolicyManual
+		err = cl.Update(context.TODO(), cm)
+		require.NoError(t, err)
+		other := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "other",
+				Namespace: test.HostOperatorNs,
+			},
+		}
+
+		// when
+		loader.invalidateIfToolchainConfigMap(toolscache.DeletedFinalStateUnknown{Obj: other})
+		cfg, err := loader.Load(test.HostOperatorNs)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, UserApprovalPolicyAutomatic, cfg.UserApprovalPolicy())
+	})
+
+	t.Run("default config when config map does not exist", func(t *testing.T) {
+		// given
+		loader := NewLoader(test.NewFakeClient(t), record.NewFakeRecorder(10))
+
+		// when
+		cfg, err := loader.Load(test.HostOperatorNs)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, UserApprovalPolicyManual, cfg.UserApprovalPolicy())
+		assert.Equal(t, DefaultTierName, cfg.DefaultTier())
+	})
+
+	t.Run("report settings in effect", func(t *testing.T) {
+		// given
+		cm := newConfigMap(UserApprovalPolicyAutomatic)
+		cm.Data[ToolchainConfigMapMaxUsersPerCluster] = "100"
+		recorder := record.NewFakeRecorder(10)
+		loader := NewLoader(test.NewFakeClient(t, cm), recorder)
+
+		// when
+		_, err := loader.Load(test.HostOperatorNs)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, recorder.Events, 1)
+		e := <-recorder.Events
+		assert.True(t, strings.HasPrefix(e, "Normal ToolchainConfigLoaded Settings in effect: "))
+		assert.Contains(t, e, "max-users-per-cluster=100")
+		assert.Contains(t, e, "user-approval-policy=automatic")
+	})
+
+	t.Run("report invalid settings", func(t *testing.T) {
+		// given
+		cm := newConfigMap("sometimes")
+		cm.Data[ToolchainConfigMapMaxUsersPerCluster] = "many"
+		recorder := record.NewFakeRecorder(10)
+		loader := NewLoader(test.NewFakeClient(t, cm), recorder)
+
+		// when
+		cfg, err := loader.Load(test.HostOperatorNs)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, UserApprovalPolicyManual, cfg.Settings()[ToolchainConfigMapUserApprovalPolicy])
+		require.Len(t, recorder.Events, 2)
+		e := <-recorder.Events
+		assert.True(t, strings.HasPrefix(e, "Warning InvalidToolchainConfig Invalid settings are ignored: "))
+		assert.Contains(t, e, "invalid value for 'user-approval-policy': 'sometimes'")
+		assert.Contains(t, e, "invalid value for 'max-users-per-cluster': 'many'")
+		e = <-recorder.Events
+		assert.True(t, strings.HasPrefix(e, "Normal ToolchainConfigLoaded Settings in effect: "))
+		assert.Contains(t, e, "user-approval-policy=manual")
+		assert.NotContains(t, e, "max-users-per-cluster")
+	})
+
+	t.Run("no event when config map does not exist", func(t *testing.T) {
+		// given
+		recorder := record.NewFakeRecorder(10)
+		loader := NewLoader(test.NewFakeClient(t), recorder)
+
+		// when
+		_, err := loader.Load(test.HostOperatorNs)
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, recorder.Events)
+	})
+
+	t.Run("no event when config is cached", func(t *testing.T) {
+		// given
+		recorder := record.NewFakeRecorder(10)
+		loader := NewLoader(test.NewFakeClient(t, newConfigMap(UserApprovalPolicyAutomatic)), recorder)
+		_, err := loader.Load(test.HostOperatorNs)
+		require.NoError(t, err)
+		<-recorder.Events
+
+		// when
+		_, err = loader.Load(test.HostOperatorNs)
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, recorder.Events)
+	})
+
+	t.Run("error when config map cannot be read", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cl.MockGet = func(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+			return errors.New("unable to get")
+		}
+		loader := NewLoader(cl, record.NewFakeRecorder(10))
+
+		// when
+		_, err := loader.Load(test.HostOperatorNs)
+
+		// then
+		require.EqualError(t, err, "unable to get")
+	})
+}
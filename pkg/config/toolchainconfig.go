@@ -0,0 +1,283 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// ToolchainConfig the typed configuration of the toolchain, as defined in the `toolchain-saas-config` ConfigMap.
+// The getters return the default value of the settings which are not set, or an error if the value is invalid.
+type ToolchainConfig struct {
+	data map[string]string
+}
+
+// NewToolchainConfig returns a new ToolchainConfig with the given ConfigMap data
+func NewToolchainConfig(data map[string]string) ToolchainConfig {
+	return ToolchainConfig{data: data}
+}
+
+// UserApprovalPolicy returns the user approval policy, which is either "manual" (by default) or "automatic"
+func (c ToolchainConfig) UserApprovalPolicy() string {
+	if policy := c.data[ToolchainConfigMapUserApprovalPolicy]; policy != "" {
+		return policy
+	}
+	return UserApprovalPolicyManual
+}
+
+// AutoApprovalAllowedDomains returns the email domains of the users whose signup is approved automatically
+func (c ToolchainConfig) AutoApprovalAllowedDomains() []string {
+	return c.list(ToolchainConfigMapAutoApprovalAllowedDomains)
+}
+
+// AutoApprovalDeniedDomains returns the email domains of the users whose signup is rejected automatically
+func (c ToolchainConfig) AutoApprovalDeniedDomains() []string {
+	return c.list(ToolchainConfigMapAutoApprovalDeniedDomains)
+}
+
+// MaxActiveUsers returns the maximum number of active users on all the member clusters (0 if not set, meaning that there is no limit)
+func (c ToolchainConfig) MaxActiveUsers() (int, error) {
+	return c.nonNegativeInt(ToolchainConfigMapMaxActiveUsers)
+}
+
+// MaxUsersPerCluster returns the maximum number of users per member cluster (0 if not set, meaning that there is no limit)
+func (c ToolchainConfig) MaxUsersPerCluster() (int, error) {
+	return c.nonNegativeInt(ToolchainConfigMapMaxUsersPerCluster)
+}
+
+// PlacementRules returns the placement rules, in YAML (empty if not set)
+func (c ToolchainConfig) PlacementRules() string {
+	return c.data[ToolchainConfigMapPlacementRules]
+}
+
+// DefaultTier returns the name of the NSTemplateTier of the new users ("basic" if not set)
+func (c ToolchainConfig) DefaultTier() string {
+	if tier := c.data[ToolchainConfigMapDefaultTier]; tier != "" {
+		return tier
+	}
+	return DefaultTierName
+}
+
+// UserSelectableTiers returns the names of the NSTemplateTiers which the users can request without the approval of an admin
+func (c ToolchainConfig) UserSelectableTiers() []string {
+	return c.list(ToolchainConfigMapUserSelectableTiers)
+}
+
+// TierUpdateMaxUnavailable returns the maximum number of MasterUserRecords of a tier which can be updated at the same
+// time when the NSTemplateTier changes (5 if not set)
+func (c ToolchainConfig) TierUpdateMaxUnavailable() (int, error) {
+	val := c.data[ToolchainConfigMapTierUpdateMaxUnavailable]
+	if val == "" {
+		return DefaultTierUpdateMaxUnavailable, nil
+	}
+	maxUnavailable, err := strconv.Atoi(val)
+	if err != nil || maxUnavailable < 1 {
+		return 0, fmt.Errorf("invalid value for '%s': '%s'", ToolchainConfigMapTierUpdateMaxUnavailable, val)
+	}
+	return maxUnavailable, nil
+}
+
+// defaultForbiddenUsernames the usernames which are always forbidden. The names ending with a `*` are prefixes.
+var defaultForbiddenUsernames = []string{"openshift", "openshift-*", "kube", "kube-*", "default", "admin", "administrator", "root"}
+
+// ForbiddenUsernames returns the usernames which must not be used as compliant usernames: the default ones along with
+// the configured ones. The names ending with a `*` are prefixes.
+func (c ToolchainConfig) ForbiddenUsernames() []string {
+	return append(append([]string{}, defaultForbiddenUsernames...), c.list(ToolchainConfigMapForbiddenUsernames)...)
+}
+
+// UserLifetime returns the lifetime of the accounts on the given tier (0 if not set, meaning that the accounts never expire)
+func (c ToolchainConfig) UserLifetime(tierName string) (time.Duration, error) {
+	key := fmt.Sprintf("%s.%s", ToolchainConfigMapUserLifetimeDays, tierName)
+	if c.data[key] == "" {
+		key = ToolchainConfigMapUserLifetimeDays
+	}
+	return c.days(key)
+}
+
+// NotificationSMTPAddress returns the address (`host:port`) of the SMTP server used to send the notifications
+// (empty if not set, meaning that no notification is sent)
+func (c ToolchainConfig) NotificationSMTPAddress() string {
+	return c.data[ToolchainConfigMapNotificationSMTPAddress]
+}
+
+// NotificationSender returns the email address on behalf of which the notifications are sent
+func (c ToolchainConfig) NotificationSender() string {
+	return c.data[ToolchainConfigMapNotificationSender]
+}
+
+// NotificationExpiryWarning returns how long before the expiry of their account the users are notified
+// (0 if not set, meaning that the users are not notified)
+func (c ToolchainConfig) NotificationExpiryWarning() (time.Duration, error) {
+	return c.days(ToolchainConfigMapNotificationExpiryWarningDays)
+}
+
+// MigrationMaxInFlight returns the maximum number of MasterUserRecords which can be migrated at the same time
+// when all the users of a member cluster are moved to another one (5 if not set)
+func (c ToolchainConfig) MigrationMaxInFlight() (int, error) {
+	maxInFlight, err := c.nonNegativeInt(ToolchainConfigMapMigrationMaxInFlight)
+	if err != nil || maxInFlight > 0 {
+		return maxInFlight, err
+	}
+	return DefaultMigrationMaxInFlight, nil
+}
+
+// LostClusterTimeout returns how long the deletion of a MasterUserRecord waits for its unreachable member clusters
+// before abandoning the UserAccounts in these clusters (0 if not set, meaning that the deletion waits forever)
+func (c ToolchainConfig) LostClusterTimeout() (time.Duration, error) {
+	hours, err := c.nonNegativeInt(ToolchainConfigMapLostClusterTimeoutHours)
+	return time.Duration(hours) * time.Hour, err
+}
+
+// DriftAutoRepair returns true if the UserAccounts which were modified in the member clusters are reverted to the
+// spec of their MasterUserRecord (true if not set). When set to false, the drifts are only reported.
+func (c ToolchainConfig) DriftAutoRepair() (bool, error) {
+	val := c.data[ToolchainConfigMapDriftAutoRepair]
+	if val == "" {
+		return true, nil
+	}
+	repair, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for '%s': '%s'", ToolchainConfigMapDriftAutoRepair, val)
+	}
+	return repair, nil
+}
+
+// DriftDetectionPeriod returns the period of the reconciliation of all the MasterUserRecords, to detect the drifts of
+// their UserAccounts which were not notified by the watches of the member clusters (10 minutes if not set)
+func (c ToolchainConfig) DriftDetectionPeriod() (time.Duration, error) {
+	minutes, err := c.nonNegativeInt(ToolchainConfigMapDriftDetectionPeriodMinutes)
+	if err != nil || minutes > 0 {
+		return time.Duration(minutes) * time.Minute, err
+	}
+	return DefaultDriftDetectionPeriodMinutes * time.Minute, nil
+}
+
+// Validate returns an error listing all the invalid settings, or nil if all the settings are valid
+func (c ToolchainConfig) Validate() error {
+	errs := []error{}
+	if policy := c.UserApprovalPolicy(); policy != UserApprovalPolicyManual && policy != UserApprovalPolicyAutomatic {
+		errs = append(errs, fmt.Errorf("invalid value for '%s': '%s'", ToolchainConfigMapUserApprovalPolicy, policy))
+	}
+	if _, err := c.TierUpdateMaxUnavailable(); err != nil {
+		errs = append(errs, err)
+	}
+	if address := c.NotificationSMTPAddress(); address != "" {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			errs = append(errs, fmt.Errorf("invalid value for '%s': '%s'", ToolchainConfigMapNotificationSMTPAddress, address))
+		}
+	}
+	if _, err := c.DriftAutoRepair(); err != nil {
+		errs = append(errs, err)
+	}
+	for key := range c.data {
+		if key == ToolchainConfigMapMaxActiveUsers || key == ToolchainConfigMapMaxUsersPerCluster ||
+			key == ToolchainConfigMapNotificationExpiryWarningDays || key == ToolchainConfigMapMigrationMaxInFlight ||
+			key == ToolchainConfigMapLostClusterTimeoutHours || key == ToolchainConfigMapDriftDetectionPeriodMinutes ||
+			isUserLifetimeKey(key) {
+			if _, err := c.nonNegativeInt(key); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// Settings returns the settings in effect, ie, the values which are actually applied: the settings which are set
+// along with the default values of the settings which are not set. The invalid settings are left out, since they
+// are not applied (see Validate), except for the user approval policy which is manual unless it is set to automatic.
+func (c ToolchainConfig) Settings() map[string]string {
+	policy := c.UserApprovalPolicy()
+	if policy != UserApprovalPolicyAutomatic {
+		policy = UserApprovalPolicyManual
+	}
+	settings := map[string]string{
+		ToolchainConfigMapUserApprovalPolicy: policy,
+		ToolchainConfigMapDefaultTier:        c.DefaultTier(),
+		ToolchainConfigMapForbiddenUsernames: strings.Join(c.ForbiddenUsernames(), ","),
+	}
+	for key, values := range map[string][]string{
+		ToolchainConfigMapAutoApprovalAllowedDomains: c.AutoApprovalAllowedDomains(),
+		ToolchainConfigMapAutoApprovalDeniedDomains:  c.AutoApprovalDeniedDomains(),
+		ToolchainConfigMapUserSelectableTiers:        c.UserSelectableTiers(),
+	} {
+		if len(values) > 0 {
+			settings[key] = strings.Join(values, ",")
+		}
+	}
+	if rules := c.PlacementRules(); rules != "" {
+		settings[ToolchainConfigMapPlacementRules] = rules
+	}
+	if address := c.NotificationSMTPAddress(); address != "" {
+		if _, _, err := net.SplitHostPort(address); err == nil {
+			settings[ToolchainConfigMapNotificationSMTPAddress] = address
+		}
+	}
+	if sender := c.NotificationSender(); sender != "" {
+		settings[ToolchainConfigMapNotificationSender] = sender
+	}
+	intKeys := []string{ToolchainConfigMapMaxActiveUsers, ToolchainConfigMapMaxUsersPerCluster, ToolchainConfigMapUserLifetimeDays,
+		ToolchainConfigMapNotificationExpiryWarningDays, ToolchainConfigMapLostClusterTimeoutHours}
+	for key := range c.data {
+		if isUserLifetimeKey(key) && key != ToolchainConfigMapUserLifetimeDays {
+			intKeys = append(intKeys, key)
+		}
+	}
+	for _, key := range intKeys {
+		if value, err := c.nonNegativeInt(key); err == nil {
+			settings[key] = strconv.Itoa(value)
+		}
+	}
+	if maxUnavailable, err := c.TierUpdateMaxUnavailable(); err == nil {
+		settings[ToolchainConfigMapTierUpdateMaxUnavailable] = strconv.Itoa(maxUnavailable)
+	}
+	if maxInFlight, err := c.MigrationMaxInFlight(); err == nil {
+		settings[ToolchainConfigMapMigrationMaxInFlight] = strconv.Itoa(maxInFlight)
+	}
+	if repair, err := c.DriftAutoRepair(); err == nil {
+		settings[ToolchainConfigMapDriftAutoRepair] = strconv.FormatBool(repair)
+	}
+	if period, err := c.DriftDetectionPeriod(); err == nil {
+		settings[ToolchainConfigMapDriftDetectionPeriodMinutes] = strconv.Itoa(int(period / time.Minute))
+	}
+	return settings
+}
+
+// isUserLifetimeKey returns true if the key is the one of the user lifetime, or of its override for a tier
+func isUserLifetimeKey(key string) bool {
+	return key == ToolchainConfigMapUserLifetimeDays || strings.HasPrefix(key, ToolchainConfigMapUserLifetimeDays+".")
+}
+
+// list returns the values of the comma-separated list for the given key
+func (c ToolchainConfig) list(key string) []string {
+	values := []string{}
+	for _, value := range strings.Split(c.data[key], ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// nonNegativeInt returns the integer value for the given key (0 if not set)
+func (c ToolchainConfig) nonNegativeInt(key string) (int, error) {
+	val := c.data[key]
+	if val == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(val)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid value for '%s': '%s'", key, val)
+	}
+	return i, nil
+}
+
+// days returns the duration in days for the given key (0 if not set)
+func (c ToolchainConfig) days(key string) (time.Duration, error) {
+	days, err := c.nonNegativeInt(key)
+	return time.Duration(days) * 24 * time.Hour, err
+}
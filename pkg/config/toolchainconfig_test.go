@@ -0,0 +1,225 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolchainConfigDefaults(t *testing.T) {
+	// given
+	cfg := NewToolchainConfig(nil)
+
+	// then
+	assert.Equal(t, UserApprovalPolicyManual, cfg.UserApprovalPolicy())
+	assert.Empty(t, cfg.AutoApprovalAllowedDomains())
+	assert.Empty(t, cfg.AutoApprovalDeniedDomains())
+	assert.Empty(t, cfg.PlacementRules())
+	assert.Equal(t, DefaultTierName, cfg.DefaultTier())
+	assert.Empty(t, cfg.UserSelectableTiers())
+	assert.Empty(t, cfg.NotificationSMTPAddress())
+	assert.Empty(t, cfg.NotificationSender())
+	maxUnavailable, err := cfg.TierUpdateMaxUnavailable()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultTierUpdateMaxUnavailable, maxUnavailable)
+	maxActiveUsers, err := cfg.MaxActiveUsers()
+	require.NoError(t, err)
+	assert.Equal(t, 0, maxActiveUsers)
+	maxUsersPerCluster, err := cfg.MaxUsersPerCluster()
+	require.NoError(t, err)
+	assert.Equal(t, 0, maxUsersPerCluster)
+	lifetime, err := cfg.UserLifetime("basic")
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), lifetime)
+	warning, err := cfg.NotificationExpiryWarning()
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), warning)
+	maxInFlight, err := cfg.MigrationMaxInFlight()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultMigrationMaxInFlight, maxInFlight)
+	timeout, err := cfg.LostClusterTimeout()
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), timeout)
+	repair, err := cfg.DriftAutoRepair()
+	require.NoError(t, err)
+	assert.True(t, repair)
+	period, err := cfg.DriftDetectionPeriod()
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, period)
+}
+
+func TestToolchainConfig(t *testing.T) {
+	// given
+	cfg := NewToolchainConfig(map[string]string{
+		ToolchainConfigMapUserApprovalPolicy:            UserApprovalPolicyAutomatic,
+		ToolchainConfigMapAutoApprovalAllowedDomains:    "redhat.com, ibm.com,",
+		ToolchainConfigMapMaxActiveUsers:                "100",
+		ToolchainConfigMapMaxUsersPerCluster:            "many",
+		ToolchainConfigMapDefaultTier:                   "advanced",
+		ToolchainConfigMapUserSelectableTiers:           "basic,team",
+		ToolchainConfigMapTierUpdateMaxUnavailable:      "0",
+		ToolchainConfigMapNotificationSMTPAddress:       "smtp.redhat.com:25",
+		ToolchainConfigMapNotificationSender:            "noreply@redhat.com",
+		ToolchainConfigMapUserLifetimeDays:              "30",
+		ToolchainConfigMapUserLifetimeDays + ".team":    "90",
+		ToolchainConfigMapNotificationExpiryWarningDays: "-1",
+		ToolchainConfigMapMigrationMaxInFlight:          "10",
+		ToolchainConfigMapLostClusterTimeoutHours:       "48",
+		ToolchainConfigMapDriftAutoRepair:               "false",
+		ToolchainConfigMapDriftDetectionPeriodMinutes:   "30",
+	})
+
+	t.Run("valid values", func(t *testing.T) {
+		assert.Equal(t, UserApprovalPolicyAutomatic, cfg.UserApprovalPolicy())
+		assert.Equal(t, []string{"redhat.com", "ibm.com"}, cfg.AutoApprovalAllowedDomains())
+		assert.Equal(t, "advanced", cfg.DefaultTier())
+		assert.Equal(t, []string{"basic", "team"}, cfg.UserSelectableTiers())
+		assert.Equal(t, "smtp.redhat.com:25", cfg.NotificationSMTPAddress())
+		assert.Equal(t, "noreply@redhat.com", cfg.NotificationSender())
+		maxActiveUsers, err := cfg.MaxActiveUsers()
+		require.NoError(t, err)
+		assert.Equal(t, 100, maxActiveUsers)
+		maxInFlight, err := cfg.MigrationMaxInFlight()
+		require.NoError(t, err)
+		assert.Equal(t, 10, maxInFlight)
+		timeout, err := cfg.LostClusterTimeout()
+		require.NoError(t, err)
+		assert.Equal(t, 48*time.Hour, timeout)
+		repair, err := cfg.DriftAutoRepair()
+		require.NoError(t, err)
+		assert.False(t, repair)
+		period, err := cfg.DriftDetectionPeriod()
+		require.NoError(t, err)
+		assert.Equal(t, 30*time.Minute, period)
+	})
+
+	t.Run("lifetime overridden per tier", func(t *testing.T) {
+		lifetime, err := cfg.UserLifetime("basic")
+		require.NoError(t, err)
+		assert.Equal(t, 30*24*time.Hour, lifetime)
+		lifetime, err = cfg.UserLifetime("team")
+		require.NoError(t, err)
+		assert.Equal(t, 90*24*time.Hour, lifetime)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		_, err := cfg.MaxUsersPerCluster()
+		require.EqualError(t, err, "invalid value for 'max-users-per-cluster': 'many'")
+		_, err = cfg.NotificationExpiryWarning()
+		require.EqualError(t, err, "invalid value for 'notification-expiry-warning-days': '-1'")
+		_, err = cfg.TierUpdateMaxUnavailable()
+		require.EqualError(t, err, "invalid value for 'tier-update-max-unavailable': '0'")
+	})
+}
+
+func TestToolchainConfigValidate(t *testing.T) {
+
+	t.Run("valid config", func(t *testing.T) {
+		// given
+		cfg := NewToolchainConfig(map[string]string{
+			ToolchainConfigMapUserApprovalPolicy:         UserApprovalPolicyAutomatic,
+			ToolchainConfigMapMaxActiveUsers:             "100",
+			ToolchainConfigMapTierUpdateMaxUnavailable:   "10",
+			ToolchainConfigMapNotificationSMTPAddress:    "smtp.redhat.com:25",
+			ToolchainConfigMapUserLifetimeDays + ".team": "90",
+		})
+
+		// then
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		// given
+		cfg := NewToolchainConfig(map[string]string{
+			ToolchainConfigMapUserApprovalPolicy:          "sometimes",
+			ToolchainConfigMapMaxActiveUsers:              "100",
+			ToolchainConfigMapMaxUsersPerCluster:          "many",
+			ToolchainConfigMapTierUpdateMaxUnavailable:    "-5",
+			ToolchainConfigMapNotificationSMTPAddress:     "smtp.redhat.com",
+			ToolchainConfigMapUserLifetimeDays + ".team":  "-1",
+			ToolchainConfigMapLostClusterTimeoutHours:     "soon",
+			ToolchainConfigMapDriftAutoRepair:             "maybe",
+			ToolchainConfigMapDriftDetectionPeriodMinutes: "often",
+		})
+
+		// when
+		err := cfg.Validate()
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid value for 'user-approval-policy': 'sometimes'")
+		assert.Contains(t, err.Error(), "invalid value for 'max-users-per-cluster': 'many'")
+		assert.Contains(t, err.Error(), "invalid value for 'tier-update-max-unavailable': '-5'")
+		assert.Contains(t, err.Error(), "invalid value for 'notification-smtp-address': 'smtp.redhat.com'")
+		assert.Contains(t, err.Error(), "invalid value for 'user-lifetime-days.team': '-1'")
+		assert.Contains(t, err.Error(), "invalid value for 'lost-cluster-timeout-hours': 'soon'")
+		assert.Contains(t, err.Error(), "invalid value for 'drift-auto-repair': 'maybe'")
+		assert.Contains(t, err.Error(), "invalid value for 'drift-detection-period-minutes': 'often'")
+		assert.NotContains(t, err.Error(), "max-active-users")
+	})
+}
+
+func TestToolchainConfigSettings(t *testing.T) {
+
+	t.Run("default settings", func(t *testing.T) {
+		// given
+		cfg := NewToolchainConfig(map[string]string{
+			ToolchainConfigMapUserApprovalPolicy:  UserApprovalPolicyAutomatic,
+			ToolchainConfigMapPlacementRules:      "",
+			ToolchainConfigMapUserSelectableTiers: "team",
+		})
+
+		// then
+		assert.Equal(t, map[string]string{
+			ToolchainConfigMapUserApprovalPolicy:            UserApprovalPolicyAutomatic,
+			ToolchainConfigMapMaxActiveUsers:                "0",
+			ToolchainConfigMapMaxUsersPerCluster:            "0",
+			ToolchainConfigMapDefaultTier:                   DefaultTierName,
+			ToolchainConfigMapUserSelectableTiers:           "team",
+			ToolchainConfigMapTierUpdateMaxUnavailable:      "5",
+			ToolchainConfigMapUserLifetimeDays:              "0",
+			ToolchainConfigMapForbiddenUsernames:            "openshift,openshift-*,kube,kube-*,default,admin,administrator,root",
+			ToolchainConfigMapNotificationExpiryWarningDays: "0",
+			ToolchainConfigMapMigrationMaxInFlight:          "5",
+			ToolchainConfigMapLostClusterTimeoutHours:       "0",
+			ToolchainConfigMapDriftAutoRepair:               "true",
+			ToolchainConfigMapDriftDetectionPeriodMinutes:   "10",
+		}, cfg.Settings())
+	})
+
+	t.Run("applied settings", func(t *testing.T) {
+		// given
+		cfg := NewToolchainConfig(map[string]string{
+			ToolchainConfigMapUserApprovalPolicy:         "sometimes",
+			ToolchainConfigMapAutoApprovalDeniedDomains:  " spam.com, ",
+			ToolchainConfigMapMaxActiveUsers:             "many",
+			ToolchainConfigMapTierUpdateMaxUnavailable:   "0",
+			ToolchainConfigMapUserLifetimeDays + ".team": "90",
+			ToolchainConfigMapUserLifetimeDays + ".test": "-1",
+			ToolchainConfigMapForbiddenUsernames:         "sre",
+			ToolchainConfigMapNotificationSMTPAddress:    "smtp.redhat.com",
+			ToolchainConfigMapNotificationSender:         "noreply@redhat.com",
+			ToolchainConfigMapMigrationMaxInFlight:       "0",
+			ToolchainConfigMapDriftAutoRepair:            "maybe",
+			"unknown-setting":                            "foo",
+		})
+
+		// then
+		assert.Equal(t, map[string]string{
+			ToolchainConfigMapUserApprovalPolicy:            UserApprovalPolicyManual,
+			ToolchainConfigMapAutoApprovalDeniedDomains:     "spam.com",
+			ToolchainConfigMapMaxUsersPerCluster:            "0",
+			ToolchainConfigMapDefaultTier:                   DefaultTierName,
+			ToolchainConfigMapUserLifetimeDays:              "0",
+			ToolchainConfigMapUserLifetimeDays + ".team":    "90",
+			ToolchainConfigMapForbiddenUsernames:            "openshift,openshift-*,kube,kube-*,default,admin,administrator,root,sre",
+			ToolchainConfigMapNotificationSender:            "noreply@redhat.com",
+			ToolchainConfigMapNotificationExpiryWarningDays: "0",
+			ToolchainConfigMapMigrationMaxInFlight:          "5",
+			ToolchainConfigMapLostClusterTimeoutHours:       "0",
+			ToolchainConfigMapDriftDetectionPeriodMinutes:   "10",
+		}, cfg.Settings())
+	})
+}
@@ -0,0 +1,163 @@
+package console
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/pkg/errors"
+	kuberrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+)
+
+var log = logf.Log.WithName("console")
+
+const (
+	// URLAnnotationKey the annotation to set on a KubeFedCluster to override the URL of the web console of its member
+	// cluster, eg, when the member cluster has a custom console
+	URLAnnotationKey = "toolchain.dev.openshift.com/console-url"
+	// CABundleAnnotationKey the annotation to set on a KubeFedCluster with the PEM-encoded CA certificates to trust
+	// (in addition to the CA bundle of the KubeFedCluster) when probing the OpenShift 3.x web console of its member cluster
+	CABundleAnnotationKey = "toolchain.dev.openshift.com/console-ca-bundle"
+
+	// probeTimeout the timeout of the request to the OpenShift 3.x web console
+	probeTimeout = 5 * time.Second
+)
+
+// Discovery discovers the URLs of the web consoles of the member clusters, and keeps them in a cache until they
+// are refreshed (ie, when the KubeFedClusters changed)
+type Discovery struct {
+	client client.Client
+	mu     sync.RWMutex
+	urls   map[string]string
+}
+
+// NewDiscovery returns a new Discovery which uses the given client to read the KubeFedClusters
+func NewDiscovery(cl client.Client) *Discovery {
+	return &Discovery{
+		client: cl,
+		urls:   map[string]string{},
+	}
+}
+
+// URL returns the URL of the web console of the given member cluster, whose KubeFedCluster is in the given namespace.
+// The URL is discovered the first time, and then read from the cache.
+func (d *Discovery) URL(namespace string, memberCluster *cluster.FedCluster) (string, error) {
+	if url, found := d.Cached(memberCluster.Name); found {
+		return url, nil
+	}
+	return d.Refresh(namespace, memberCluster)
+}
+
+// Cached returns the URL of the web console of the given member cluster, if it is in the cache
+func (d *Discovery) Cached(clusterName string) (string, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	url, found := d.urls[clusterName]
+	return url, found
+}
+
+// Refresh discovers the URL of the web console of the given member cluster, and replaces the one in the cache.
+// The URL is either:
+// - the one set in the `toolchain.dev.openshift.com/console-url` annotation of the KubeFedCluster,
+// - the one of the `console` route in the `openshift-console` namespace of the member cluster,
+// - or the `<api-endpoint>/console` URL, if it is reachable (OpenShift 3.x)
+func (d *Discovery) Refresh(namespace string, memberCluster *cluster.FedCluster) (string, error) {
+	kubeFedCluster := &v1beta1.KubeFedCluster{}
+	if err := d.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: memberCluster.Name}, kubeFedCluster); err != nil {
+		if !kuberrors.IsNotFound(err) {
+			return "", err
+		}
+		kubeFedCluster = nil
+	}
+	url, err := discover(kubeFedCluster, memberCluster)
+	if err != nil {
+		return "", err
+	}
+	d.mu.Lock()
+	d.urls[memberCluster.Name] = url
+	d.mu.Unlock()
+	return url, nil
+}
+
+// Forget removes the URL of the web console of the given member cluster from the cache
+func (d *Discovery) Forget(clusterName string) {
+	d.mu.Lock()
+	delete(d.urls, clusterName)
+	d.mu.Unlock()
+}
+
+func discover(kubeFedCluster *v1beta1.KubeFedCluster, memberCluster *cluster.FedCluster) (string, error) {
+	if kubeFedCluster != nil && kubeFedCluster.Annotations[URLAnnotationKey] != "" {
+		return kubeFedCluster.Annotations[URLAnnotationKey], nil
+	}
+	route := &routev1.Route{}
+	err := memberCluster.Client.Get(context.TODO(), types.NamespacedName{Namespace: "openshift-console", Name: "console"}, route)
+	if err == nil {
+		return fmt.Sprintf("https://%s/%s", route.Spec.Host, route.Spec.Path), nil
+	}
+	if !kuberrors.IsNotFound(err) {
+		return "", err
+	}
+	// It can happen if running in old OpenShift version like 3.x (minishift) in dev environment
+	url := fmt.Sprintf("%s/console", memberCluster.APIEndpoint)
+	if probeErr := probe(url, caBundles(kubeFedCluster)); probeErr != nil {
+		// Log the probe error but return the original missing route error
+		log.Error(probeErr, "OpenShift 3.x web console unreachable", "url", url)
+		return "", errors.Wrapf(err, "unable to get web console route for cluster %s", memberCluster.Name)
+	}
+	log.Info("OpenShift 3.x web console URL used", "url", url)
+	return url, nil
+}
+
+// caBundles returns the CA bundles to trust when probing the web console of the member cluster of the given KubeFedCluster
+func caBundles(kubeFedCluster *v1beta1.KubeFedCluster) [][]byte {
+	if kubeFedCluster == nil {
+		return nil
+	}
+	bundles := [][]byte{kubeFedCluster.Spec.CABundle}
+	if bundle := kubeFedCluster.Annotations[CABundleAnnotationKey]; bundle != "" {
+		bundles = append(bundles, []byte(bundle))
+	}
+	return bundles
+}
+
+// probe checks that the given URL is reachable, trusting the system CA certificates along with the given CA bundles
+func probe(url string, bundles [][]byte) error {
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	for _, bundle := range bundles {
+		roots.AppendCertsFromPEM(bundle)
+	}
+	httpClient := &http.Client{
+		Timeout: probeTimeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots},
+		},
+	}
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("status is not 200 OK: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package console
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codeready-toolchain/host-operator/pkg/apis"
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+)
+
+func TestDiscovery(t *testing.T) {
+	// given
+	err := apis.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+	err = v1beta1.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/console" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	serverCA := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	t.Run("URL overridden on the KubeFedCluster", func(t *testing.T) {
+		// given
+		kubeFedCluster := newKubeFedCluster(server.URL)
+		kubeFedCluster.Annotations = map[string]string{URLAnnotationKey: "https://custom-console.member-cluster"}
+		discovery := NewDiscovery(test.NewFakeClient(t, kubeFedCluster))
+
+		// when
+		url, err := discovery.URL(test.HostOperatorNs, newMemberCluster(test.NewFakeClient(t, consoleRoute()), server.URL))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "https://custom-console.member-cluster", url)
+	})
+
+	t.Run("URL of the console route", func(t *testing.T) {
+		// given
+		discovery := NewDiscovery(test.NewFakeClient(t, newKubeFedCluster(server.URL)))
+
+		// when
+		url, err := discovery.URL(test.HostOperatorNs, newMemberCluster(test.NewFakeClient(t, consoleRoute()), server.URL))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "https://console.member-cluster/console", url)
+	})
+
+	t.Run("OpenShift 3.x console", func(t *testing.T) {
+
+		t.Run("trusted with the CA bundle of the KubeFedCluster", func(t *testing.T) {
+			// given
+			kubeFedCluster := newKubeFedCluster(server.URL)
+			kubeFedCluster.Spec.CABundle = serverCA
+			discovery := NewDiscovery(test.NewFakeClient(t, kubeFedCluster))
+
+			// when
+			url, err := discovery.URL(test.HostOperatorNs, newMemberCluster(test.NewFakeClient(t), server.URL))
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("%s/console", server.URL), url)
+		})
+
+		t.Run("trusted with the CA bundle annotation", func(t *testing.T) {
+			// given
+			kubeFedCluster := newKubeFedCluster(server.URL)
+			kubeFedCluster.Annotations = map[string]string{CABundleAnnotationKey: string(serverCA)}
+			discovery := NewDiscovery(test.NewFakeClient(t, kubeFedCluster))
+
+			// when
+			url, err := discovery.URL(test.HostOperatorNs, newMemberCluster(test.NewFakeClient(t), server.URL))
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("%s/console", server.URL), url)
+		})
+
+		t.Run("not trusted", func(t *testing.T) {
+			// given
+			discovery := NewDiscovery(test.NewFakeClient(t, newKubeFedCluster(server.URL)))
+
+			// when
+			_, err := discovery.URL(test.HostOperatorNs, newMemberCluster(test.NewFakeClient(t), server.URL))
+
+			// then
+			assert.EqualError(t, err, `unable to get web console route for cluster member-cluster: routes.route.openshift.io "console" not found`)
+			_, found := discovery.Cached(test.MemberClusterName)
+			assert.False(t, found)
+		})
+
+		t.Run("returns status other than 200", func(t *testing.T) {
+			// given
+			kubeFedCluster := newKubeFedCluster(server.URL)
+			kubeFedCluster.Spec.CABundle = serverCA
+			discovery := NewDiscovery(test.NewFakeClient(t, kubeFedCluster))
+
+			// when
+			_, err := discovery.URL(test.HostOperatorNs, newMemberCluster(test.NewFakeClient(t), server.URL+"/api"))
+
+			// then
+			assert.EqualError(t, err, `unable to get web console route for cluster member-cluster: routes.route.openshift.io "console" not found`)
+		})
+	})
+
+	t.Run("get route fails with error other than 404", func(t *testing.T) {
+		// given
+		discovery := NewDiscovery(test.NewFakeClient(t, newKubeFedCluster(server.URL)))
+		memberClient := test.NewFakeClient(t)
+		memberClient.MockGet = func(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+			return errors.New("something went wrong")
+		}
+
+		// when
+		_, err := discovery.URL(test.HostOperatorNs, newMemberCluster(memberClient, server.URL))
+
+		// then
+		assert.EqualError(t, err, "something went wrong")
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		// given
+		memberClient := test.NewFakeClient(t, consoleRoute())
+		memberCluster := newMemberCluster(memberClient, server.URL)
+		discovery := NewDiscovery(test.NewFakeClient(t, newKubeFedCluster(server.URL)))
+		_, err := discovery.URL(test.HostOperatorNs, memberCluster)
+		require.NoError(t, err)
+		route := consoleRoute()
+		route.Spec.Host = "new-console.member-cluster"
+		err = memberClient.Update(context.TODO(), route)
+		require.NoError(t, err)
+
+		t.Run("URL read from the cache", func(t *testing.T) {
+			// when
+			url, err := discovery.URL(test.HostOperatorNs, memberCluster)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, "https://console.member-cluster/console", url)
+		})
+
+		t.Run("URL refreshed", func(t *testing.T) {
+			// when
+			url, err := discovery.Refresh(test.HostOperatorNs, memberCluster)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, "https://new-console.member-cluster/console", url)
+			cached, found := discovery.Cached(test.MemberClusterName)
+			assert.True(t, found)
+			assert.Equal(t, url, cached)
+		})
+
+		t.Run("URL forgotten", func(t *testing.T) {
+			// when
+			discovery.Forget(test.MemberClusterName)
+
+			// then
+			_, found := discovery.Cached(test.MemberClusterName)
+			assert.False(t, found)
+		})
+	})
+}
+
+func newKubeFedCluster(apiEndpoint string) *v1beta1.KubeFedCluster {
+	return &v1beta1.KubeFedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      test.MemberClusterName,
+			Namespace: test.HostOperatorNs,
+		},
+		Spec: v1beta1.KubeFedClusterSpec{
+			APIEndpoint: apiEndpoint,
+		},
+	}
+}
+
+func newMemberCluster(cl client.Client, apiEndpoint string) *cluster.FedCluster {
+	return &cluster.FedCluster{
+		Name:              test.MemberClusterName,
+		APIEndpoint:       apiEndpoint,
+		Client:            cl,
+		Type:              cluster.Member,
+		OperatorNamespace: test.MemberOperatorNs,
+	}
+}
+
+func consoleRoute() *routev1.Route {
+	return &routev1.Route{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "console",
+			Namespace: "openshift-console",
+		},
+		Spec: routev1.RouteSpec{
+			Host: "console.member-cluster",
+			Path: "console",
+		},
+	}
+}
@@ -0,0 +1,182 @@
+package clustermigration
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/config"
+	"github.com/codeready-toolchain/host-operator/pkg/controller/masteruserrecord"
+	"github.com/codeready-toolchain/host-operator/pkg/placement"
+	"github.com/codeready-toolchain/host-operator/pkg/predicate"
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+)
+
+var log = logf.Log.WithName("controller_clustermigration")
+
+const (
+	// MigrateUsersToAnnotationKey the annotation to set on a KubeFedCluster to move all the users of this member cluster
+	// to the member cluster whose name is the value of the annotation
+	MigrateUsersToAnnotationKey = "toolchain.dev.openshift.com/migrate-users-to"
+	// MigrationProgressAnnotationKey the annotation in which the progress of the migration of the users of the member
+	// cluster is reported on its KubeFedCluster
+	MigrationProgressAnnotationKey = "toolchain.dev.openshift.com/migration-progress"
+
+	// migrationRequeueDelay the delay before checking the progress of the migration and migrating the next batch of users
+	migrationRequeueDelay = 10 * time.Second
+)
+
+// Add creates a new cluster migration Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager, configLoader *config.Loader) error {
+	return add(mgr, newReconciler(mgr, configLoader))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager, configLoader *config.Loader) reconcile.Reconciler {
+	return &ReconcileClusterMigration{
+		client:       mgr.GetClient(),
+		configLoader: configLoader,
+		placement:    placement.New(mgr.GetClient(), cluster.GetMemberClusters, placement.LeastUtilized),
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New("clustermigration-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the annotations of the KubeFedClusters, except for the progress reported by this controller
+	return c.Watch(&source.Kind{Type: &v1beta1.KubeFedCluster{}}, &handler.EnqueueRequestForObject{},
+		predicate.GenerationOrAnnotationsChangedPredicate{IgnoredAnnotations: []string{MigrationProgressAnnotationKey}})
+}
+
+var _ reconcile.Reconciler = &ReconcileClusterMigration{}
+
+// ReconcileClusterMigration moves all the users of a member cluster to other ones
+type ReconcileClusterMigration struct {
+	client client.Client
+	// configLoader loads the toolchain configuration, and caches it until the ConfigMap changes (shared by all the controllers)
+	configLoader *config.Loader
+	placement    *placement.Placement
+}
+
+// Reconcile migrates the MasterUserRecords which have a UserAccount in the member cluster of the KubeFedCluster
+// to the member cluster set in its `toolchain.dev.openshift.com/migrate-users-to` annotation (if any), or, if the
+// KubeFedCluster is draining, to the member clusters selected by the placement (as if the users signed up again).
+// The MasterUserRecords are migrated in batches: no more than `migration-max-in-flight` MasterUserRecords
+// can be migrating at the same time, and the request is requeued until all MasterUserRecords are migrated.
+// The progress of the migration is reported in the `toolchain.dev.openshift.com/migration-progress` annotation.
+func (r *ReconcileClusterMigration) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+
+	kubeFedCluster := &v1beta1.KubeFedCluster{}
+	if err := r.client.Get(context.TODO(), request.NamespacedName, kubeFedCluster); err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	cfg, err := r.configLoader.Load(request.Namespace)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	var selectTarget masteruserrecord.TargetClusterSelector
+	if to := kubeFedCluster.Annotations[MigrateUsersToAnnotationKey]; to != "" {
+		if err := r.validateTargetCluster(request.Namespace, kubeFedCluster.Name, to); err != nil {
+			return reconcile.Result{}, errs.Wrapf(err, "unable to migrate the users of the member cluster '%s'", kubeFedCluster.Name)
+		}
+		reqLogger.Info("Migrating the users of the member cluster", "To", to)
+		selectTarget = masteruserrecord.ToCluster(to)
+	} else if placement.IsDraining(*kubeFedCluster) {
+		reqLogger.Info("Draining the member cluster")
+		maxUsersPerCluster, err := cfg.MaxUsersPerCluster()
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		selectTarget = r.selectTargetCluster(request.Namespace, maxUsersPerCluster)
+	} else {
+		return reconcile.Result{}, nil
+	}
+
+	maxInFlight, err := cfg.MigrationMaxInFlight()
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	remaining, failed, err := masteruserrecord.MigrateUserAccounts(r.client, request.Namespace, kubeFedCluster.Name, selectTarget, maxInFlight)
+	if err != nil {
+		return reconcile.Result{}, errs.Wrapf(err, "unable to migrate the users of the member cluster '%s'", kubeFedCluster.Name)
+	}
+	if err := r.reportProgress(kubeFedCluster, remaining, failed); err != nil {
+		return reconcile.Result{}, errs.Wrapf(err, "unable to report the migration progress of the member cluster '%s'", kubeFedCluster.Name)
+	}
+	if remaining == 0 {
+		reqLogger.Info("All users of the member cluster were migrated", "Failed", failed)
+		return reconcile.Result{}, nil
+	}
+	reqLogger.Info("Users of the member cluster are being migrated", "Remaining", remaining, "Failed", failed)
+	return reconcile.Result{RequeueAfter: migrationRequeueDelay}, nil
+}
+
+// validateTargetCluster returns an error if the users of the `from` member cluster cannot be migrated to the `to`
+// member cluster, ie, if the latter does not exist, is not ready, or is cordoned or draining
+func (r *ReconcileClusterMigration) validateTargetCluster(namespace, from, to string) error {
+	if to == from {
+		return fmt.Errorf("the target member cluster '%s' is the migrated one", to)
+	}
+	candidates, err := r.placement.Candidates(namespace, 0)
+	if err != nil {
+		return err
+	}
+	for _, c := range candidates {
+		if c.Name != to {
+			continue
+		}
+		if !c.Ready {
+			return fmt.Errorf("the target member cluster '%s' is not ready", to)
+		}
+		if c.Unschedulable {
+			return fmt.Errorf("the target member cluster '%s' is cordoned or draining", to)
+		}
+		return nil
+	}
+	return fmt.Errorf("the target member cluster '%s' does not exist", to)
+}
+
+// selectTargetCluster returns a TargetClusterSelector which selects the member cluster with the placement, among the
+// member clusters in which the user has no UserAccount yet
+func (r *ReconcileClusterMigration) selectTargetCluster(namespace string, maxUsersPerCluster int) masteruserrecord.TargetClusterSelector {
+	return func(mur *toolchainv1alpha1.MasterUserRecord) (string, error) {
+		excluded := make([]string, 0, len(mur.Spec.UserAccounts))
+		for _, ua := range mur.Spec.UserAccounts {
+			excluded = append(excluded, ua.TargetCluster)
+		}
+		return r.placement.SelectTargetClusterExcept(namespace, maxUsersPerCluster, excluded)
+	}
+}
+
+// reportProgress sets the number of remaining and failed migrations in the annotation of the KubeFedCluster
+// (if they changed since the last report)
+func (r *ReconcileClusterMigration) reportProgress(kubeFedCluster *v1beta1.KubeFedCluster, remaining, failed int) error {
+	progress := fmt.Sprintf("remaining: %d, failed: %d", remaining, failed)
+	if kubeFedCluster.Annotations[MigrationProgressAnnotationKey] == progress {
+		return nil
+	}
+	kubeFedCluster.Annotations[MigrationProgressAnnotationKey] = progress
+	return r.client.Update(context.TODO(), kubeFedCluster)
+}
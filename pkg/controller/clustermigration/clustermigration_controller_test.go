@@ -0,0 +1,270 @@
+package clustermigration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/apis"
+	"github.com/codeready-toolchain/host-operator/pkg/config"
+	"github.com/codeready-toolchain/host-operator/pkg/controller/masteruserrecord"
+	"github.com/codeready-toolchain/host-operator/pkg/placement"
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	murtest "github.com/codeready-toolchain/toolchain-common/pkg/test/masteruserrecord"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/kubefed/pkg/apis/core/common"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+)
+
+func TestReconcile(t *testing.T) {
+	// given
+	err := apis.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+	err = v1beta1.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+
+	newMURs := func(count int) []runtime.Object {
+		murs := []runtime.Object{}
+		for i := 0; i < count; i++ {
+			murs = append(murs, murtest.NewMasterUserRecord(fmt.Sprintf("user-%d", i)))
+		}
+		return murs
+	}
+
+	t.Run("migrate the users in batches", func(t *testing.T) {
+		// given
+		kubeFedCluster := newKubeFedCluster(test.MemberClusterName, "member2-cluster")
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      config.ToolchainConfigMapName,
+				Namespace: test.HostOperatorNs,
+			},
+			Data: map[string]string{
+				config.ToolchainConfigMapMigrationMaxInFlight: "2",
+			},
+		}
+		r, cl := prepareReconcile(t, append(newMURs(3), kubeFedCluster, cm)...)
+
+		// when
+		result, err := r.Reconcile(newRequest(kubeFedCluster))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: migrationRequeueDelay}, result)
+		assert.Equal(t, 2, countMigrating(t, cl, "member2-cluster"))
+		assertProgress(t, cl, kubeFedCluster, "remaining: 3, failed: 0")
+	})
+
+	t.Run("all users migrated", func(t *testing.T) {
+		// given
+		kubeFedCluster := newKubeFedCluster("member3-cluster", "member2-cluster")
+		r, cl := prepareReconcile(t, append(newMURs(3), kubeFedCluster)...)
+
+		// when
+		result, err := r.Reconcile(newRequest(kubeFedCluster))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, result)
+		assert.Equal(t, 0, countMigrating(t, cl, "member2-cluster"))
+		assertProgress(t, cl, kubeFedCluster, "remaining: 0, failed: 0")
+	})
+
+	t.Run("invalid target cluster", func(t *testing.T) {
+
+		t.Run("unknown cluster", func(t *testing.T) {
+			// given
+			kubeFedCluster := newKubeFedCluster(test.MemberClusterName, "unknown-cluster")
+			r, cl := prepareReconcile(t, append(newMURs(3), kubeFedCluster)...)
+
+			// when
+			_, err := r.Reconcile(newRequest(kubeFedCluster))
+
+			// then
+			require.EqualError(t, err, fmt.Sprintf("unable to migrate the users of the member cluster '%s': the target member cluster 'unknown-cluster' does not exist", test.MemberClusterName))
+			assert.Equal(t, 0, countMigrating(t, cl, "unknown-cluster"))
+			assertProgress(t, cl, kubeFedCluster, "")
+		})
+
+		t.Run("same cluster", func(t *testing.T) {
+			// given
+			kubeFedCluster := newKubeFedCluster(test.MemberClusterName, test.MemberClusterName)
+			r, cl := prepareReconcile(t, append(newMURs(3), kubeFedCluster)...)
+
+			// when
+			_, err := r.Reconcile(newRequest(kubeFedCluster))
+
+			// then
+			require.EqualError(t, err, fmt.Sprintf("unable to migrate the users of the member cluster '%[1]s': the target member cluster '%[1]s' is the migrated one", test.MemberClusterName))
+			assert.Equal(t, 0, countMigrating(t, cl, test.MemberClusterName))
+		})
+
+		t.Run("cluster not ready", func(t *testing.T) {
+			// given
+			kubeFedCluster := newKubeFedCluster(test.MemberClusterName, "member2-cluster")
+			r, cl := prepareReconcile(t, append(newMURs(3), kubeFedCluster)...)
+			notReady := newFedCluster("member2-cluster")
+			notReady.ClusterStatus.Conditions[0].Status = corev1.ConditionFalse
+			r.placement = placement.New(cl, func() []*cluster.FedCluster {
+				return []*cluster.FedCluster{newFedCluster(test.MemberClusterName), notReady}
+			}, placement.LeastUtilized)
+
+			// when
+			_, err := r.Reconcile(newRequest(kubeFedCluster))
+
+			// then
+			require.EqualError(t, err, fmt.Sprintf("unable to migrate the users of the member cluster '%s': the target member cluster 'member2-cluster' is not ready", test.MemberClusterName))
+			assert.Equal(t, 0, countMigrating(t, cl, "member2-cluster"))
+		})
+
+		t.Run("cluster cordoned", func(t *testing.T) {
+			// given
+			kubeFedCluster := newKubeFedCluster(test.MemberClusterName, "member2-cluster")
+			cordoned := newKubeFedCluster("member2-cluster", "")
+			cordoned.Annotations = map[string]string{placement.UnschedulableAnnotationKey: "true"}
+			r, cl := prepareReconcile(t, append(newMURs(3), kubeFedCluster, cordoned)...)
+
+			// when
+			_, err := r.Reconcile(newRequest(kubeFedCluster))
+
+			// then
+			require.EqualError(t, err, fmt.Sprintf("unable to migrate the users of the member cluster '%s': the target member cluster 'member2-cluster' is cordoned or draining", test.MemberClusterName))
+			assert.Equal(t, 0, countMigrating(t, cl, "member2-cluster"))
+		})
+	})
+
+	t.Run("drain the member cluster", func(t *testing.T) {
+		// given
+		kubeFedCluster := newKubeFedCluster(test.MemberClusterName, "")
+		kubeFedCluster.Annotations = map[string]string{placement.DrainAnnotationKey: "true"}
+		cordoned := newKubeFedCluster("member3-cluster", "")
+		cordoned.Annotations = map[string]string{placement.UnschedulableAnnotationKey: "true"}
+		r, cl := prepareReconcile(t, append(newMURs(3), kubeFedCluster, cordoned)...)
+
+		// when
+		result, err := r.Reconcile(newRequest(kubeFedCluster))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: migrationRequeueDelay}, result)
+		// all users are moved to the only member cluster which is neither draining nor cordoned
+		assert.Equal(t, 3, countMigrating(t, cl, "member2-cluster"))
+		assertProgress(t, cl, kubeFedCluster, "remaining: 3, failed: 0")
+	})
+
+	t.Run("drain the member cluster without any other schedulable cluster", func(t *testing.T) {
+		// given
+		kubeFedCluster := newKubeFedCluster(test.MemberClusterName, "")
+		kubeFedCluster.Annotations = map[string]string{placement.DrainAnnotationKey: "true"}
+		other := newKubeFedCluster("member2-cluster", "")
+		other.Annotations = map[string]string{placement.DrainAnnotationKey: "true"}
+		cordoned := newKubeFedCluster("member3-cluster", "")
+		cordoned.Annotations = map[string]string{placement.UnschedulableAnnotationKey: "true"}
+		r, cl := prepareReconcile(t, append(newMURs(3), kubeFedCluster, other, cordoned)...)
+
+		// when
+		_, err := r.Reconcile(newRequest(kubeFedCluster))
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), placement.ErrNoCapacity.Error())
+		assert.Equal(t, 0, countMigrating(t, cl, "member2-cluster"))
+		assert.Equal(t, 0, countMigrating(t, cl, "member3-cluster"))
+	})
+
+	t.Run("no migration requested", func(t *testing.T) {
+		// given
+		kubeFedCluster := newKubeFedCluster(test.MemberClusterName, "")
+		r, cl := prepareReconcile(t, append(newMURs(3), kubeFedCluster)...)
+
+		// when
+		result, err := r.Reconcile(newRequest(kubeFedCluster))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, result)
+		assert.Equal(t, 0, countMigrating(t, cl, "member2-cluster"))
+	})
+}
+
+func prepareReconcile(t *testing.T, initObjs ...runtime.Object) (*ReconcileClusterMigration, *test.FakeClient) {
+	cl := test.NewFakeClient(t, initObjs...)
+	memberClusters := []*cluster.FedCluster{
+		newFedCluster(test.MemberClusterName),
+		newFedCluster("member2-cluster"),
+		newFedCluster("member3-cluster"),
+	}
+	return &ReconcileClusterMigration{
+		client:       cl,
+		configLoader: config.NewLoader(cl, record.NewFakeRecorder(10)),
+		placement: placement.New(cl, func() []*cluster.FedCluster {
+			return memberClusters
+		}, placement.LeastUtilized),
+	}, cl
+}
+
+func newFedCluster(name string) *cluster.FedCluster {
+	return &cluster.FedCluster{
+		Name:              name,
+		Type:              cluster.Member,
+		OperatorNamespace: test.MemberOperatorNs,
+		OwnerClusterName:  test.HostClusterName,
+		ClusterStatus: &v1beta1.KubeFedClusterStatus{
+			Conditions: []v1beta1.ClusterCondition{{
+				Type:   common.ClusterReady,
+				Status: corev1.ConditionTrue,
+			}},
+		},
+	}
+}
+
+func newKubeFedCluster(name, migrateTo string) *v1beta1.KubeFedCluster {
+	kubeFedCluster := &v1beta1.KubeFedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: test.HostOperatorNs,
+		},
+	}
+	if migrateTo != "" {
+		kubeFedCluster.Annotations = map[string]string{MigrateUsersToAnnotationKey: migrateTo}
+	}
+	return kubeFedCluster
+}
+
+func newRequest(kubeFedCluster *v1beta1.KubeFedCluster) reconcile.Request {
+	return reconcile.Request{
+		NamespacedName: types.NamespacedName{Namespace: kubeFedCluster.Namespace, Name: kubeFedCluster.Name},
+	}
+}
+
+func assertProgress(t *testing.T, cl client.Client, kubeFedCluster *v1beta1.KubeFedCluster, expected string) {
+	actual := &v1beta1.KubeFedCluster{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: kubeFedCluster.Namespace, Name: kubeFedCluster.Name}, actual)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual.Annotations[MigrationProgressAnnotationKey])
+}
+
+func countMigrating(t *testing.T, cl client.Client, to string) int {
+	murs := &toolchainv1alpha1.MasterUserRecordList{}
+	err := cl.List(context.TODO(), murs)
+	require.NoError(t, err)
+	count := 0
+	for _, mur := range murs.Items {
+		if mur.Annotations[masteruserrecord.MigrateToClusterAnnotationKey] == to {
+			count++
+		}
+	}
+	return count
+}
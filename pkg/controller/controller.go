@@ -1,25 +1,33 @@
 package controller
 
 import (
+	"github.com/codeready-toolchain/host-operator/pkg/config"
+	"github.com/codeready-toolchain/host-operator/pkg/controller/clustermigration"
 	"github.com/codeready-toolchain/host-operator/pkg/controller/masteruserrecord"
+	"github.com/codeready-toolchain/host-operator/pkg/controller/nstemplatetier"
 	"github.com/codeready-toolchain/host-operator/pkg/controller/registrationservice"
 	"github.com/codeready-toolchain/host-operator/pkg/controller/usersignup"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
-// addToManagerFuncs is a list of functions to add all Controllers to the Manager
-var addToManagerFuncs []func(manager.Manager) error
+// addToManagerFuncs is a list of functions to add all Controllers to the Manager, along with the toolchain
+// configuration loader which is shared by the Controllers
+var addToManagerFuncs []func(manager.Manager, *config.Loader) error
 
 func init() {
+	addToManagerFuncs = append(addToManagerFuncs, clustermigration.Add)
 	addToManagerFuncs = append(addToManagerFuncs, masteruserrecord.Add)
-	addToManagerFuncs = append(addToManagerFuncs, registrationservice.Add)
+	addToManagerFuncs = append(addToManagerFuncs, nstemplatetier.Add)
+	addToManagerFuncs = append(addToManagerFuncs, func(mgr manager.Manager, _ *config.Loader) error {
+		return registrationservice.Add(mgr)
+	})
 	addToManagerFuncs = append(addToManagerFuncs, usersignup.Add)
 }
 
 // AddToManager adds all Controllers to the Manager
-func AddToManager(m manager.Manager) error {
+func AddToManager(m manager.Manager, configLoader *config.Loader) error {
 	for _, f := range addToManagerFuncs {
-		if err := f(m); err != nil {
+		if err := f(m, configLoader); err != nil {
 			return err
 		}
 	}
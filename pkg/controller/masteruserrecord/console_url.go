@@ -0,0 +1,20 @@
+package masteruserrecord
+
+import (
+	"github.com/codeready-toolchain/host-operator/pkg/console"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// forgetConsoleURL returns a mapper which removes the cached URL of the web console of the member cluster of the
+// KubeFedCluster, and returns the requests to reconcile the MasterUserRecords which have a UserAccount in this
+// member cluster. The URL is discovered again when these MasterUserRecords are reconciled, so that their status
+// gets the new URL.
+func forgetConsoleURL(consoleURLs *console.Discovery, cl client.Client) handler.ToRequestsFunc {
+	return func(obj handler.MapObject) []reconcile.Request {
+		consoleURLs.Forget(obj.Meta.GetName())
+		return masterUserRecordsInCluster(cl)(obj)
+	}
+}
@@ -0,0 +1,48 @@
+package masteruserrecord
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/codeready-toolchain/host-operator/pkg/console"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	murtest "github.com/codeready-toolchain/toolchain-common/pkg/test/masteruserrecord"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+)
+
+func TestForgetConsoleURL(t *testing.T) {
+	// given
+	apiScheme(t)
+	john := murtest.NewMasterUserRecord("john")
+	hostClient := test.NewFakeClient(t, john)
+	memberClient := test.NewFakeClient(t, consoleRoute())
+	consoleURLs := console.NewDiscovery(hostClient)
+	_, err := consoleURLs.URL(test.HostOperatorNs, newMemberCluster(memberClient))
+	require.NoError(t, err)
+	memberClient.MockGet = func(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+		return errors.New("the member cluster must not be called")
+	}
+	kubeFedCluster := &v1beta1.KubeFedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      test.MemberClusterName,
+			Namespace: test.HostOperatorNs,
+		},
+	}
+
+	// when
+	requests := forgetConsoleURL(consoleURLs, hostClient)(handler.MapObject{Meta: kubeFedCluster, Object: kubeFedCluster})
+
+	// then
+	assert.Equal(t, []reconcile.Request{newMurRequest(john)}, requests)
+	_, found := consoleURLs.Cached(test.MemberClusterName)
+	assert.False(t, found)
+}
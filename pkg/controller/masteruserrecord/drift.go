@@ -0,0 +1,261 @@
+package masteruserrecord
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/config"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// driftedCondition the type of the condition of a MasterUserRecord whose UserAccounts were modified in the
+	// member clusters
+	driftedCondition toolchainv1alpha1.ConditionType = "Drifted"
+
+	// Drifted condition reasons
+	driftDetectedReason = "DriftDetected"
+	noDriftReason       = "NoDrift"
+
+	// syncedSpecAnnotationKey the annotation of a UserAccount which contains the hash of the spec of its
+	// MasterUserRecord when the UserAccount was last synchronized with it
+	syncedSpecAnnotationKey = "toolchain.dev.openshift.com/synced-spec"
+)
+
+var (
+	driftedUserAccounts = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "host_operator_drifted_useraccounts",
+		Help: "Number of UserAccounts whose spec differs from the one of their MasterUserRecord, per member cluster",
+	}, []string{"cluster"})
+
+	repairedDrifts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "host_operator_repaired_useraccount_drifts_total",
+		Help: "Number of UserAccounts reverted to the spec of their MasterUserRecord, per member cluster",
+	}, []string{"cluster"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(driftedUserAccounts, repairedDrifts)
+}
+
+// driftTracker keeps track of the drifted UserAccounts, to expose their number per member cluster
+type driftTracker struct {
+	mu sync.Mutex
+	// drifted the names of the MasterUserRecords whose UserAccounts drifted, per member cluster
+	drifted map[string]map[string]bool
+}
+
+// newDriftTracker returns a new driftTracker with no drifted UserAccounts
+func newDriftTracker() *driftTracker {
+	return &driftTracker{drifted: map[string]map[string]bool{}}
+}
+
+// set records whether the UserAccount of the given MasterUserRecord in the given member cluster drifted
+func (t *driftTracker) set(clusterName, murName string, drifted bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.drifted[clusterName] == nil {
+		t.drifted[clusterName] = map[string]bool{}
+	}
+	if drifted {
+		t.drifted[clusterName][murName] = true
+	} else {
+		delete(t.drifted[clusterName], murName)
+	}
+	driftedUserAccounts.WithLabelValues(clusterName).Set(float64(len(t.drifted[clusterName])))
+}
+
+// forget removes the UserAccounts of the given MasterUserRecord from the drifted ones
+func (t *driftTracker) forget(murName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for clusterName, murs := range t.drifted {
+		if murs[murName] {
+			delete(murs, murName)
+			driftedUserAccounts.WithLabelValues(clusterName).Set(float64(len(murs)))
+		}
+	}
+}
+
+// specHash returns the hash of the given UserAccount spec
+func specHash(spec toolchainv1alpha1.UserAccountSpec) string {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// markSynchronized records in the annotations of the UserAccount that it was synchronized with the given spec
+func markSynchronized(userAccount *toolchainv1alpha1.UserAccount, spec toolchainv1alpha1.UserAccountSpec) {
+	if userAccount.Annotations == nil {
+		userAccount.Annotations = map[string]string{}
+	}
+	userAccount.Annotations[syncedSpecAnnotationKey] = specHash(spec)
+}
+
+// hasDrifted returns true if the spec of the UserAccount differs from the given spec of its MasterUserRecord, while
+// the UserAccount was already synchronized with this spec, ie, if the UserAccount was modified in the member cluster.
+// A UserAccount which was never synchronized with the current spec of its MasterUserRecord did not drift: it is
+// outdated.
+func hasDrifted(userAccount *toolchainv1alpha1.UserAccount, spec toolchainv1alpha1.UserAccountSpec) bool {
+	return !reflect.DeepEqual(userAccount.Spec, spec) && userAccount.Annotations[syncedSpecAnnotationKey] == specHash(spec)
+}
+
+// specDiff returns the differences between the actual spec of a UserAccount and the expected one, field by field
+func specDiff(actual, expected toolchainv1alpha1.UserAccountSpec) []string {
+	diffs := []string{}
+	diffValues("spec", reflect.ValueOf(actual), reflect.ValueOf(expected), &diffs)
+	return diffs
+}
+
+// diffValues appends the differences between the given values to the diffs. The fields of the structs are named
+// after their JSON key.
+func diffValues(path string, actual, expected reflect.Value, diffs *[]string) {
+	if reflect.DeepEqual(actual.Interface(), expected.Interface()) {
+		return
+	}
+	switch actual.Kind() {
+	case reflect.Struct:
+		for i := 0; i < actual.NumField(); i++ {
+			field := actual.Type().Field(i)
+			if field.PkgPath != "" {
+				continue // unexported
+			}
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "" {
+				name = field.Name
+			}
+			if name == "-" {
+				continue
+			}
+			diffValues(path+"."+name, actual.Field(i), expected.Field(i), diffs)
+		}
+		return
+	case reflect.Ptr:
+		if !actual.IsNil() && !expected.IsNil() {
+			diffValues(path, actual.Elem(), expected.Elem(), diffs)
+			return
+		}
+	case reflect.Slice:
+		if actual.Len() == expected.Len() {
+			for i := 0; i < actual.Len(); i++ {
+				diffValues(fmt.Sprintf("%s[%d]", path, i), actual.Index(i), expected.Index(i), diffs)
+			}
+			return
+		}
+	}
+	*diffs = append(*diffs, fmt.Sprintf("%s: '%v' instead of '%v'", path, printable(actual), printable(expected)))
+}
+
+func printable(value reflect.Value) interface{} {
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		return value.Elem().Interface()
+	}
+	return value.Interface()
+}
+
+// updateDriftedCondition sets the Drifted condition of the MasterUserRecord according to the given drifts, per member
+// cluster. The condition is only set if some UserAccounts drifted, or if they drifted before.
+func updateDriftedCondition(cl client.Client, mur *toolchainv1alpha1.MasterUserRecord, driftsPerCluster map[string][]string) error {
+	if len(driftsPerCluster) == 0 {
+		if !hasCondition(mur.Status.Conditions, driftedCondition) {
+			return nil
+		}
+		return updateStatusConditions(cl, mur, toolchainv1alpha1.Condition{
+			Type:   driftedCondition,
+			Status: corev1.ConditionFalse,
+			Reason: noDriftReason,
+		})
+	}
+	clusters := make([]string, 0, len(driftsPerCluster))
+	for clusterName := range driftsPerCluster {
+		clusters = append(clusters, clusterName)
+	}
+	sort.Strings(clusters)
+	messages := make([]string, len(clusters))
+	for i, clusterName := range clusters {
+		messages[i] = fmt.Sprintf("'%s' (%s)", clusterName, strings.Join(driftsPerCluster[clusterName], ", "))
+	}
+	return updateStatusConditions(cl, mur, toolchainv1alpha1.Condition{
+		Type:    driftedCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  driftDetectedReason,
+		Message: fmt.Sprintf("UserAccounts modified in %s", strings.Join(messages, ", ")),
+	})
+}
+
+func hasCondition(conditions []toolchainv1alpha1.Condition, conditionType toolchainv1alpha1.ConditionType) bool {
+	for _, cond := range conditions {
+		if cond.Type == conditionType {
+			return true
+		}
+	}
+	return false
+}
+
+// detectDrifts returns a Runnable which periodically sends an event for each MasterUserRecord of the given namespace
+// to the given channel, so that the specs of their UserAccounts are compared with the MasterUserRecords even if the
+// watch of a member cluster missed a change. The period is read before each detection, so that a change of the
+// configuration is taken into account without restarting the operator.
+func detectDrifts(cl client.Client, namespace string, events chan<- event.GenericEvent, period func() time.Duration) manager.RunnableFunc {
+	return func(stop <-chan struct{}) error {
+		for {
+			select {
+			case <-stop:
+				return nil
+			case <-time.After(period()):
+				murs := &toolchainv1alpha1.MasterUserRecordList{}
+				if err := cl.List(context.TODO(), murs, client.InNamespace(namespace)); err != nil {
+					log.Error(err, "unable to list the MasterUserRecords to detect the drifts of their UserAccounts")
+					continue
+				}
+				for i := range murs.Items {
+					mur := &murs.Items[i]
+					select {
+					case events <- event.GenericEvent{Meta: mur, Object: mur}:
+					case <-stop:
+						return nil
+					}
+				}
+			}
+		}
+	}
+}
+
+// driftDetectionPeriod returns a function which returns the drift detection period set in the toolchain
+// configuration of the given namespace, or the default period if the configuration cannot be loaded or is invalid
+func driftDetectionPeriod(configLoader *config.Loader, namespace string) func() time.Duration {
+	return func() time.Duration {
+		cfg, err := configLoader.Load(namespace)
+		if err != nil {
+			log.Error(err, "unable to load the toolchain configuration, using the default drift detection period")
+			return config.DefaultDriftDetectionPeriodMinutes * time.Minute
+		}
+		period, err := cfg.DriftDetectionPeriod()
+		if err != nil {
+			log.Error(err, "invalid drift detection period, using the default one")
+			return config.DefaultDriftDetectionPeriodMinutes * time.Minute
+		}
+		return period
+	}
+}
@@ -0,0 +1,244 @@
+package masteruserrecord
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	murtest "github.com/codeready-toolchain/toolchain-common/pkg/test/masteruserrecord"
+	uatest "github.com/codeready-toolchain/toolchain-common/pkg/test/useraccount"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestSpecDiff(t *testing.T) {
+	// given
+	mur := murtest.NewMasterUserRecord("john")
+	modified := murtest.NewMasterUserRecord("john")
+	murtest.ModifyUaInMur(modified, test.MemberClusterName, murtest.NsLimit("advanced"), murtest.TierName("admin"))
+
+	t.Run("no difference", func(t *testing.T) {
+		// when
+		diffs := specDiff(mur.Spec.UserAccounts[0].Spec, mur.Spec.UserAccounts[0].Spec)
+
+		// then
+		assert.Empty(t, diffs)
+	})
+
+	t.Run("differences field by field", func(t *testing.T) {
+		// when
+		diffs := specDiff(modified.Spec.UserAccounts[0].Spec, mur.Spec.UserAccounts[0].Spec)
+
+		// then
+		require.Len(t, diffs, 2)
+		assert.Contains(t, diffs[0], "spec.nsLimit: 'advanced' instead of ")
+		assert.Contains(t, diffs[1], "spec.nsTemplateSet.tierName: 'admin' instead of ")
+	})
+}
+
+func TestDriftDetection(t *testing.T) {
+	// given
+	logf.SetLogger(logf.ZapLogger(true))
+	s := apiScheme(t)
+
+	t.Run("report the drift of the UserAccount", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john", murtest.StatusCondition(toBeProvisioned()))
+		userAccount := driftedUserAccount(mur)
+		memberClient := test.NewFakeClient(t, userAccount, consoleRoute())
+		hostClient := test.NewFakeClient(t, mur, toolchainConfigMap(map[string]string{
+			config.ToolchainConfigMapDriftAutoRepair: "false",
+		}))
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, corev1.ConditionTrue),
+			clusterClient(test.MemberClusterName, memberClient))
+
+		// when
+		_, err := cntrl.Reconcile(newMurRequest(mur))
+
+		// then
+		require.NoError(t, err)
+		uatest.AssertThatUserAccount(t, "john", memberClient).HasSpec(userAccount.Spec)
+		drifted := assertDriftedCondition(t, hostClient, mur, corev1.ConditionTrue, driftDetectedReason)
+		assert.Contains(t, drifted.Message, "UserAccounts modified in 'member-cluster' (spec.nsTemplateSet.tierName: 'admin' instead of ")
+		assert.Equal(t, float64(1), testutil.ToFloat64(driftedUserAccounts.WithLabelValues(test.MemberClusterName)))
+
+		t.Run("forget the drift when the MasterUserRecord is deleted", func(t *testing.T) {
+			// given
+			err := hostClient.Delete(context.TODO(), mur)
+			require.NoError(t, err)
+
+			// when
+			_, err = cntrl.Reconcile(newMurRequest(mur))
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, float64(0), testutil.ToFloat64(driftedUserAccounts.WithLabelValues(test.MemberClusterName)))
+		})
+	})
+
+	t.Run("repair the drift of the UserAccount", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john", murtest.StatusCondition(toBeProvisioned()))
+		memberClient := test.NewFakeClient(t, driftedUserAccount(mur), consoleRoute())
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, corev1.ConditionTrue),
+			clusterClient(test.MemberClusterName, memberClient))
+		repaired := testutil.ToFloat64(repairedDrifts.WithLabelValues(test.MemberClusterName))
+
+		// when
+		_, err := cntrl.Reconcile(newMurRequest(mur))
+
+		// then
+		require.NoError(t, err)
+		uatest.AssertThatUserAccount(t, "john", memberClient).HasSpec(mur.Spec.UserAccounts[0].Spec)
+		actual := getMasterUserRecord(t, hostClient, mur)
+		assert.False(t, hasCondition(actual.Status.Conditions, driftedCondition))
+		assert.Equal(t, repaired+1, testutil.ToFloat64(repairedDrifts.WithLabelValues(test.MemberClusterName)))
+		assert.Equal(t, float64(0), testutil.ToFloat64(driftedUserAccounts.WithLabelValues(test.MemberClusterName)))
+	})
+
+	t.Run("update the outdated UserAccount", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john", murtest.StatusCondition(toBeProvisioned()))
+		previous := murtest.NewMasterUserRecord("john")
+		murtest.ModifyUaInMur(previous, test.MemberClusterName, murtest.TierName("admin"))
+		userAccount := uatest.NewUserAccountFromMur(previous, uatest.StatusCondition(toBeProvisioned()))
+		markSynchronized(userAccount, previous.Spec.UserAccounts[0].Spec)
+		memberClient := test.NewFakeClient(t, userAccount, consoleRoute())
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, corev1.ConditionTrue),
+			clusterClient(test.MemberClusterName, memberClient))
+
+		// when
+		_, err := cntrl.Reconcile(newMurRequest(mur))
+
+		// then
+		require.NoError(t, err)
+		uatest.AssertThatUserAccount(t, "john", memberClient).HasSpec(mur.Spec.UserAccounts[0].Spec)
+		actual := getMasterUserRecord(t, hostClient, mur)
+		assert.False(t, hasCondition(actual.Status.Conditions, driftedCondition))
+	})
+
+	t.Run("clear the Drifted condition once the UserAccount is back to the spec", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john")
+		mur.Status.Conditions = []toolchainv1alpha1.Condition{toBeProvisioned(), {
+			Type:   driftedCondition,
+			Status: corev1.ConditionTrue,
+			Reason: driftDetectedReason,
+		}}
+		userAccount := uatest.NewUserAccountFromMur(mur, uatest.StatusCondition(toBeProvisioned()))
+		markSynchronized(userAccount, mur.Spec.UserAccounts[0].Spec)
+		memberClient := test.NewFakeClient(t, userAccount, consoleRoute())
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, corev1.ConditionTrue),
+			clusterClient(test.MemberClusterName, memberClient))
+
+		// when
+		_, err := cntrl.Reconcile(newMurRequest(mur))
+
+		// then
+		require.NoError(t, err)
+		assertDriftedCondition(t, hostClient, mur, corev1.ConditionFalse, noDriftReason)
+	})
+}
+
+func TestDetectDrifts(t *testing.T) {
+	// given
+	apiScheme(t)
+	hostClient := test.NewFakeClient(t, murtest.NewMasterUserRecord("john"), murtest.NewMasterUserRecord("jane"))
+	events := make(chan event.GenericEvent)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	// when
+	go func() {
+		_ = detectDrifts(hostClient, test.HostOperatorNs, events, func() time.Duration {
+			return 10 * time.Millisecond
+		}).Start(stop)
+	}()
+
+	// then
+	names := []string{}
+	for len(names) < 2 {
+		select {
+		case evt := <-events:
+			names = append(names, evt.Meta.GetName())
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "no event for the MasterUserRecords")
+		}
+	}
+	assert.ElementsMatch(t, []string{"john", "jane"}, names)
+}
+
+func TestDriftDetectionPeriod(t *testing.T) {
+
+	t.Run("period set in the config", func(t *testing.T) {
+		// given
+		hostClient := test.NewFakeClient(t, toolchainConfigMap(map[string]string{
+			config.ToolchainConfigMapDriftDetectionPeriodMinutes: "30",
+		}))
+		period := driftDetectionPeriod(config.NewLoader(hostClient, record.NewFakeRecorder(10)), test.HostOperatorNs)
+
+		// then
+		assert.Equal(t, 30*time.Minute, period())
+	})
+
+	t.Run("default period when the config is invalid", func(t *testing.T) {
+		// given
+		hostClient := test.NewFakeClient(t, toolchainConfigMap(map[string]string{
+			config.ToolchainConfigMapDriftDetectionPeriodMinutes: "often",
+		}))
+		period := driftDetectionPeriod(config.NewLoader(hostClient, record.NewFakeRecorder(10)), test.HostOperatorNs)
+
+		// then
+		assert.Equal(t, 10*time.Minute, period())
+	})
+
+	t.Run("default period when the config cannot be loaded", func(t *testing.T) {
+		// given
+		hostClient := test.NewFakeClient(t)
+		hostClient.MockGet = func(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+			return errors.New("unable to get")
+		}
+		period := driftDetectionPeriod(config.NewLoader(hostClient, record.NewFakeRecorder(10)), test.HostOperatorNs)
+
+		// then
+		assert.Equal(t, 10*time.Minute, period())
+	})
+}
+
+// driftedUserAccount returns the UserAccount of the given MasterUserRecord, which was synchronized with the spec of
+// the MasterUserRecord and then moved to another tier in the member cluster
+func driftedUserAccount(mur *toolchainv1alpha1.MasterUserRecord) *toolchainv1alpha1.UserAccount {
+	modified := murtest.NewMasterUserRecord(mur.Name)
+	murtest.ModifyUaInMur(modified, test.MemberClusterName, murtest.TierName("admin"))
+	userAccount := uatest.NewUserAccountFromMur(modified, uatest.StatusCondition(toBeProvisioned()))
+	markSynchronized(userAccount, mur.Spec.UserAccounts[0].Spec)
+	return userAccount
+}
+
+func assertDriftedCondition(t *testing.T, cl client.Client, mur *toolchainv1alpha1.MasterUserRecord, status corev1.ConditionStatus, reason string) toolchainv1alpha1.Condition {
+	actual := getMasterUserRecord(t, cl, mur)
+	for _, cond := range actual.Status.Conditions {
+		if cond.Type == driftedCondition {
+			assert.Equal(t, status, cond.Status)
+			assert.Equal(t, reason, cond.Reason)
+			return cond
+		}
+	}
+	require.Fail(t, "no Drifted condition")
+	return toolchainv1alpha1.Condition{}
+}
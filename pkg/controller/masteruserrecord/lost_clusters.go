@@ -0,0 +1,105 @@
+package masteruserrecord
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ForceDeleteAnnotationKey the annotation to set to "true" on a MasterUserRecord being deleted, so that its
+	// UserAccounts in the unreachable member clusters are abandoned without waiting for the lost cluster timeout
+	ForceDeleteAnnotationKey = "toolchain.dev.openshift.com/force-delete"
+
+	// OrphanUserAccountsConfigMapName the name of the ConfigMap which lists the UserAccounts abandoned when their
+	// MasterUserRecord was deleted: the keys are the names of the member clusters, and the values are the
+	// comma-separated names of the UserAccounts in these clusters
+	OrphanUserAccountsConfigMapName = "orphan-useraccounts"
+
+	// abandonedUserAccountsReason the reason of the event recorded when the UserAccounts of a MasterUserRecord are abandoned
+	abandonedUserAccountsReason = "AbandonedUserAccounts"
+
+	// unableToReportOrphanUserAccountsReason the reason of the Ready condition when the abandoned UserAccounts could
+	// not be added to the orphan report
+	unableToReportOrphanUserAccountsReason = "UnableToReportOrphanUserAccounts"
+)
+
+// canAbandonUserAccounts returns true if the UserAccounts of the given MasterUserRecord (being deleted) in the
+// unreachable member clusters can be abandoned, ie, if the MasterUserRecord has the force-delete annotation, or if
+// its deletion was requested for longer than the `lost-cluster-timeout-hours`
+func (r *ReconcileMasterUserRecord) canAbandonUserAccounts(mur *toolchainv1alpha1.MasterUserRecord) (bool, error) {
+	if mur.Annotations[ForceDeleteAnnotationKey] == "true" {
+		return true, nil
+	}
+	cfg, err := r.configLoader.Load(mur.Namespace)
+	if err != nil {
+		return false, err
+	}
+	timeout, err := cfg.LostClusterTimeout()
+	if err != nil || timeout == 0 || mur.DeletionTimestamp == nil {
+		return false, err
+	}
+	return time.Since(mur.DeletionTimestamp.Time) >= timeout, nil
+}
+
+// reportOrphanUserAccounts adds the UserAccount with the given name in each of the given member clusters to the
+// orphan report of the given namespace. The report is created if it does not exist yet.
+func reportOrphanUserAccounts(cl client.Client, namespace, name string, clusters []string) error {
+	report := &corev1.ConfigMap{}
+	err := cl.Get(context.TODO(), namespacedName(namespace, OrphanUserAccountsConfigMapName), report)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+	if !exists {
+		report = &corev1.ConfigMap{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: namespace,
+				Name:      OrphanUserAccountsConfigMapName,
+			},
+		}
+	}
+	if report.Data == nil {
+		report.Data = map[string]string{}
+	}
+	for _, clusterName := range clusters {
+		names := orphanUserAccounts(report, clusterName)
+		if !contains(names, name) {
+			names = append(names, name)
+			sort.Strings(names)
+		}
+		report.Data[clusterName] = strings.Join(names, ",")
+	}
+	if exists {
+		return cl.Update(context.TODO(), report)
+	}
+	return cl.Create(context.TODO(), report)
+}
+
+// orphanUserAccounts returns the names of the orphan UserAccounts in the given member cluster, as listed in the report
+func orphanUserAccounts(report *corev1.ConfigMap, clusterName string) []string {
+	names := []string{}
+	for _, name := range strings.Split(report.Data[clusterName], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
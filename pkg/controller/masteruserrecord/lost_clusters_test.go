@@ -0,0 +1,194 @@
+package masteruserrecord
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/host-operator/pkg/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	murtest "github.com/codeready-toolchain/toolchain-common/pkg/test/masteruserrecord"
+	uatest "github.com/codeready-toolchain/toolchain-common/pkg/test/useraccount"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestDeleteMasterUserRecordWithLostCluster(t *testing.T) {
+	// given
+	logf.SetLogger(logf.ZapLogger(true))
+	s := apiScheme(t)
+
+	t.Run("wait for the unreachable member cluster by default", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john", murtest.ToBeDeleted(), murtest.AdditionalAccounts("member2-cluster"))
+		memberClient := test.NewFakeClient(t, uatest.NewUserAccountFromMur(mur))
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, corev1.ConditionTrue),
+			clusterClient(test.MemberClusterName, memberClient))
+
+		// when
+		_, err := cntrl.Reconcile(newMurRequest(mur))
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to delete UserAccount in the member cluster 'member2-cluster'")
+		uatest.AssertThatUserAccount(t, "john", memberClient).DoesNotExist()
+		murtest.AssertThatMasterUserRecord(t, "john", hostClient).
+			HasFinalizer().
+			HasConditions(toBeNotReady(unableToDeleteUserAccountsReason, "the member cluster member2-cluster not found in the registry"))
+		assertNoOrphanReport(t, hostClient)
+	})
+
+	t.Run("abandon the UserAccount when the MasterUserRecord is force-deleted", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john", murtest.ToBeDeleted(), murtest.AdditionalAccounts("member2-cluster"))
+		mur.Annotations = map[string]string{ForceDeleteAnnotationKey: "true"}
+		memberClient := test.NewFakeClient(t, uatest.NewUserAccountFromMur(mur))
+		hostClient := test.NewFakeClient(t, mur, orphanReport(map[string]string{"member2-cluster": "jack"}))
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, corev1.ConditionTrue),
+			clusterClient(test.MemberClusterName, memberClient))
+
+		// when
+		_, err := cntrl.Reconcile(newMurRequest(mur))
+
+		// then
+		require.NoError(t, err)
+		uatest.AssertThatUserAccount(t, "john", memberClient).DoesNotExist()
+		murtest.AssertThatMasterUserRecord(t, "john", hostClient).DoesNotHaveFinalizer()
+		assertOrphanReport(t, hostClient, map[string]string{"member2-cluster": "jack,john"})
+		assertEvent(t, cntrl.recorder, "Warning AbandonedUserAccounts Abandoned the UserAccounts in the unreachable member clusters: member2-cluster")
+	})
+
+	t.Run("abandon the UserAccount when the lost cluster timeout elapsed", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john", murtest.ToBeDeleted())
+		mur.DeletionTimestamp = &metav1.Time{Time: time.Now().Add(-3 * time.Hour)}
+		hostClient := test.NewFakeClient(t, mur, toolchainConfigMap(map[string]string{
+			config.ToolchainConfigMapLostClusterTimeoutHours: "2",
+		}))
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, corev1.ConditionFalse),
+			clusterClient(test.MemberClusterName, test.NewFakeClient(t)))
+
+		// when
+		_, err := cntrl.Reconcile(newMurRequest(mur))
+
+		// then
+		require.NoError(t, err)
+		murtest.AssertThatMasterUserRecord(t, "john", hostClient).DoesNotHaveFinalizer()
+		assertOrphanReport(t, hostClient, map[string]string{test.MemberClusterName: "john"})
+		assertEvent(t, cntrl.recorder, "Warning AbandonedUserAccounts Abandoned the UserAccounts in the unreachable member clusters: member-cluster")
+	})
+
+	t.Run("wait for the unreachable member cluster until the lost cluster timeout elapsed", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john", murtest.ToBeDeleted())
+		mur.DeletionTimestamp = &metav1.Time{Time: time.Now().Add(-1 * time.Hour)}
+		hostClient := test.NewFakeClient(t, mur, toolchainConfigMap(map[string]string{
+			config.ToolchainConfigMapLostClusterTimeoutHours: "2",
+		}))
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, corev1.ConditionFalse),
+			clusterClient(test.MemberClusterName, test.NewFakeClient(t)))
+
+		// when
+		_, err := cntrl.Reconcile(newMurRequest(mur))
+
+		// then
+		require.Error(t, err)
+		murtest.AssertThatMasterUserRecord(t, "john", hostClient).HasFinalizer()
+		assertNoOrphanReport(t, hostClient)
+	})
+
+	t.Run("do not abandon the UserAccount when its deletion failed in a ready member cluster", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john", murtest.ToBeDeleted())
+		mur.Annotations = map[string]string{ForceDeleteAnnotationKey: "true"}
+		memberClient := test.NewFakeClient(t, uatest.NewUserAccountFromMur(mur))
+		memberClient.MockDelete = func(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+			return fmt.Errorf("unable to delete user account")
+		}
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, corev1.ConditionTrue),
+			clusterClient(test.MemberClusterName, memberClient))
+
+		// when
+		_, err := cntrl.Reconcile(newMurRequest(mur))
+
+		// then
+		require.Error(t, err)
+		murtest.AssertThatMasterUserRecord(t, "john", hostClient).HasFinalizer()
+		assertNoOrphanReport(t, hostClient)
+	})
+
+	t.Run("failed to report the abandoned UserAccount", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john", murtest.ToBeDeleted())
+		mur.Annotations = map[string]string{ForceDeleteAnnotationKey: "true"}
+		hostClient := test.NewFakeClient(t, mur)
+		hostClient.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			return fmt.Errorf("unable to create the report")
+		}
+		cntrl := newController(hostClient, s, newGetMemberCluster(false, corev1.ConditionTrue))
+
+		// when
+		_, err := cntrl.Reconcile(newMurRequest(mur))
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to report the UserAccounts abandoned in the member clusters [member-cluster]")
+		murtest.AssertThatMasterUserRecord(t, "john", hostClient).
+			HasFinalizer().
+			HasConditions(toBeNotReady(unableToReportOrphanUserAccountsReason, "unable to create the report"))
+	})
+}
+
+func orphanReport(data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: test.HostOperatorNs,
+			Name:      OrphanUserAccountsConfigMapName,
+		},
+		Data: data,
+	}
+}
+
+func toolchainConfigMap(data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: test.HostOperatorNs,
+			Name:      config.ToolchainConfigMapName,
+		},
+		Data: data,
+	}
+}
+
+func assertOrphanReport(t *testing.T, cl client.Client, expected map[string]string) {
+	report := &corev1.ConfigMap{}
+	err := cl.Get(context.TODO(), namespacedName(test.HostOperatorNs, OrphanUserAccountsConfigMapName), report)
+	require.NoError(t, err)
+	assert.Equal(t, expected, report.Data)
+}
+
+func assertNoOrphanReport(t *testing.T, cl client.Client) {
+	report := &corev1.ConfigMap{}
+	err := cl.Get(context.TODO(), namespacedName(test.HostOperatorNs, OrphanUserAccountsConfigMapName), report)
+	require.Error(t, err)
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func assertEvent(t *testing.T, recorder record.EventRecorder, expected string) {
+	select {
+	case evt := <-recorder.(*record.FakeRecorder).Events:
+		assert.Equal(t, expected, evt)
+	default:
+		assert.Fail(t, "no event was recorded")
+	}
+}
@@ -2,13 +2,16 @@ package masteruserrecord
 
 import (
 	"context"
-	"fmt"
+	"strings"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/config"
+	"github.com/codeready-toolchain/host-operator/pkg/console"
+	"github.com/codeready-toolchain/host-operator/pkg/predicate"
 	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
 	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
 	"github.com/go-logr/logr"
-	"github.com/operator-framework/operator-sdk/pkg/predicate"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	errs "github.com/pkg/errors"
 	coputil "github.com/redhat-cop/operator-utils/pkg/util"
 	corev1 "k8s.io/api/core/v1"
@@ -16,13 +19,16 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
 	"sigs.k8s.io/kubefed/pkg/controller/util"
 )
 
@@ -46,21 +52,25 @@ const (
 
 // Add creates a new MasterUserRecord Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
-func Add(mgr manager.Manager) error {
-	return add(mgr, newReconciler(mgr))
+func Add(mgr manager.Manager, configLoader *config.Loader) error {
+	return add(mgr, newReconciler(mgr, configLoader))
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+func newReconciler(mgr manager.Manager, configLoader *config.Loader) *ReconcileMasterUserRecord {
 	return &ReconcileMasterUserRecord{
 		client:                mgr.GetClient(),
 		scheme:                mgr.GetScheme(),
 		retrieveMemberCluster: cluster.GetFedCluster,
+		configLoader:          configLoader,
+		recorder:              mgr.GetEventRecorderFor("masteruserrecord-controller"),
+		consoleURLs:           console.NewDiscovery(mgr.GetClient()),
+		drifts:                newDriftTracker(),
 	}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
-func add(mgr manager.Manager, r reconcile.Reconciler) error {
+func add(mgr manager.Manager, r *ReconcileMasterUserRecord) error {
 	// Create a new controller
 	c, err := controller.New("masteruserrecord-controller", mgr, controller.Options{Reconciler: r})
 	if err != nil {
@@ -71,12 +81,49 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	err = c.Watch(&source.Kind{
 		Type: &toolchainv1alpha1.MasterUserRecord{}},
 		&handler.EnqueueRequestForObject{},
-		predicate.GenerationChangedPredicate{})
+		predicate.GenerationOrAnnotationsChangedPredicate{})
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// Watch for the member clusters which became ready, to reconcile the MasterUserRecords which have a UserAccount in them
+	err = c.Watch(&source.Kind{
+		Type: &v1beta1.KubeFedCluster{}},
+		&handler.EnqueueRequestsFromMapFunc{ToRequests: masterUserRecordsInCluster(mgr.GetClient())},
+		predicate.ClusterBecameReadyPredicate{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the spec and the console annotations of the KubeFedClusters, to refresh the URLs of the
+	// web consoles of their member clusters
+	err = c.Watch(&source.Kind{
+		Type: &v1beta1.KubeFedCluster{}},
+		&handler.EnqueueRequestsFromMapFunc{ToRequests: forgetConsoleURL(r.consoleURLs, mgr.GetClient())},
+		predicate.GenerationOrAnnotationsChangedPredicate{
+			WatchedAnnotations: []string{console.URLAnnotationKey, console.CABundleAnnotationKey},
+		})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the UserAccounts in the member clusters
+	events := make(chan event.GenericEvent)
+	if err := c.Watch(&source.Channel{Source: events}, &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+	if err := addUserAccountWatches(mgr, events); err != nil {
+		return err
+	}
+	// Reconcile all the MasterUserRecords periodically, to detect the UserAccounts which drifted
+	namespace, err := k8sutil.GetWatchNamespace()
+	if err != nil {
+		return err
+	}
+	if err := mgr.Add(detectDrifts(mgr.GetClient(), namespace, events, driftDetectionPeriod(r.configLoader, namespace))); err != nil {
+		return err
+	}
+	return addOrphanUserAccountsCleanup(mgr)
 }
 
 var _ reconcile.Reconciler = &ReconcileMasterUserRecord{}
@@ -88,6 +135,14 @@ type ReconcileMasterUserRecord struct {
 	client                client.Client
 	scheme                *runtime.Scheme
 	retrieveMemberCluster func(name string) (*cluster.FedCluster, bool)
+	// configLoader loads the toolchain configuration, and caches it until the ConfigMap changes (shared by all the controllers)
+	configLoader *config.Loader
+	recorder     record.EventRecorder
+	// consoleURLs discovers the URLs of the web consoles of the member clusters, and caches them until the
+	// KubeFedClusters change
+	consoleURLs *console.Discovery
+	// drifts keeps track of the UserAccounts which were modified in the member clusters
+	drifts *driftTracker
 }
 
 // Reconcile reads that state of the cluster for a MasterUserRecord object and makes changes based on the state read
@@ -107,6 +162,7 @@ func (r *ReconcileMasterUserRecord) Reconcile(request reconcile.Request) (reconc
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
+			r.drifts.forget(request.Name)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
@@ -114,6 +170,7 @@ func (r *ReconcileMasterUserRecord) Reconcile(request reconcile.Request) (reconc
 		return reconcile.Result{}, err
 	}
 
+	result := reconcile.Result{}
 	// If the UserAccount is not being deleted, create or synchronize UserAccounts.
 	if !coputil.IsBeingDeleted(mur) {
 		// Add the finalizer if it is not present
@@ -121,12 +178,34 @@ func (r *ReconcileMasterUserRecord) Reconcile(request reconcile.Request) (reconc
 			reqLogger.Error(err, "unable to add finalizer to MasterUserRecord")
 			return reconcile.Result{}, err
 		}
-		for _, account := range mur.Spec.UserAccounts {
-			err = r.ensureUserAccount(reqLogger, account, mur)
-			if err != nil {
-				reqLogger.Error(err, "unable to synchronize with member UserAccount")
-				return reconcile.Result{}, err
-			}
+		// Move the UserAccounts to another tier if requested
+		if err := r.promoteTier(reqLogger, mur); err != nil {
+			reqLogger.Error(err, "unable to promote MasterUserRecord to another tier")
+			return reconcile.Result{}, err
+		}
+		// Move the UserAccount to another member cluster if requested
+		if result, err = r.migrate(reqLogger, mur); err != nil {
+			reqLogger.Error(err, "unable to migrate MasterUserRecord to another member cluster")
+			return reconcile.Result{}, err
+		}
+		// Delete the UserAccounts which were removed from the spec
+		pending, err := r.removeOrphanUserAccounts(reqLogger, mur)
+		if err != nil {
+			reqLogger.Error(err, "unable to remove the orphan UserAccounts of the MasterUserRecord")
+			return reconcile.Result{}, err
+		}
+		if pending {
+			result = requeueAfter(result, orphanRetryPeriod)
+		}
+		ensureResult, err := r.ensureUserAccounts(reqLogger, mur)
+		if err != nil {
+			reqLogger.Error(err, "unable to synchronize with member UserAccounts")
+			return reconcile.Result{}, err
+		}
+		result = requeueAfter(result, ensureResult.RequeueAfter)
+		if err := r.completeTierPromotion(mur); err != nil {
+			reqLogger.Error(err, "unable to complete the tier promotion of the MasterUserRecord")
+			return reconcile.Result{}, err
 		}
 
 		// If the UserAccount is being deleted, delete the UserAccounts in members.
@@ -136,7 +215,7 @@ func (r *ReconcileMasterUserRecord) Reconcile(request reconcile.Request) (reconc
 			return reconcile.Result{}, err
 		}
 	}
-	return reconcile.Result{}, nil
+	return result, nil
 }
 
 func (r *ReconcileMasterUserRecord) addFinalizer(mur *toolchainv1alpha1.MasterUserRecord, finalizer string) error {
@@ -151,62 +230,14 @@ func (r *ReconcileMasterUserRecord) addFinalizer(mur *toolchainv1alpha1.MasterUs
 	return nil
 }
 
-func (r *ReconcileMasterUserRecord) ensureUserAccount(log logr.Logger, recAccount toolchainv1alpha1.UserAccountEmbedded, record *toolchainv1alpha1.MasterUserRecord) error {
-	// get & check member cluster
-	memberCluster, err := r.getMemberCluster(recAccount.TargetCluster)
-	if err != nil {
-		return r.wrapErrorWithStatusUpdate(log, record, r.setStatusFailed(targetClusterNotReadyReason), err,
-			"failed to get the member cluster '%s'", recAccount.TargetCluster)
-	}
-
-	// get UserAccount from member
-	nsdName := namespacedName(memberCluster.OperatorNamespace, record.Name)
-	userAccount := &toolchainv1alpha1.UserAccount{}
-	err = memberCluster.Client.Get(context.TODO(), nsdName, userAccount)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// does not exist - should create
-			userAccount = newUserAccount(nsdName, recAccount.Spec)
-			if err := updateStatusConditions(r.client, record, toBeNotReady(provisioningReason, "")); err != nil {
-				return err
-			}
-			if err := memberCluster.Client.Create(context.TODO(), userAccount); err != nil {
-				return r.wrapErrorWithStatusUpdate(log, record, r.setStatusFailed(unableToCreateUserAccountReason), err,
-					"failed to create UserAccount in the member cluster '%s'", recAccount.TargetCluster)
-			}
-			return nil
-		}
-		return r.wrapErrorWithStatusUpdate(log, record, r.setStatusFailed(unableToGetUserAccountReason), err,
-			"failed to get userAccount '%s' from cluster '%s'", record.Name, recAccount.TargetCluster)
-	}
-
-	sync := Synchronizer{
-		record:            record,
-		hostClient:        r.client,
-		memberCluster:     memberCluster,
-		memberUserAcc:     userAccount,
-		recordSpecUserAcc: recAccount,
-		log:               log,
-	}
-	if err := sync.synchronizeSpec(); err != nil {
-		return r.wrapErrorWithStatusUpdate(log, record, r.setStatusFailed(unableToSynchronizeUserAccountSpecReason), err,
-			"update of the UserAccount.spec in the cluster '%s' failed", recAccount.TargetCluster)
-	}
-	if err := sync.synchronizeStatus(); err != nil {
-		err = errs.Wrapf(err, "update of the MasterUserRecord failed while synchronizing with UserAccount status from the cluster '%s'", recAccount.TargetCluster)
-		return r.wrapErrorWithStatusUpdate(log, record, r.useExistingConditionOfType(toolchainv1alpha1.ConditionReady), err, "")
-	}
-	return nil
-}
-
 func (r *ReconcileMasterUserRecord) getMemberCluster(targetCluster string) (*cluster.FedCluster, error) {
 	// get & check fed cluster
 	fedCluster, ok := r.retrieveMemberCluster(targetCluster)
 	if !ok {
-		return nil, fmt.Errorf("the member cluster %s not found in the registry", targetCluster)
+		return nil, clusterNotFoundError{name: targetCluster}
 	}
 	if !util.IsClusterReady(fedCluster.ClusterStatus) {
-		return nil, fmt.Errorf("the member cluster %s is not ready", targetCluster)
+		return nil, clusterNotReadyError{name: targetCluster, unavailableFor: unavailableFor(fedCluster.ClusterStatus)}
 	}
 	return fedCluster, nil
 }
@@ -234,26 +265,38 @@ func (r *ReconcileMasterUserRecord) setStatusFailed(reason string) func(record *
 	}
 }
 
-func (r *ReconcileMasterUserRecord) useExistingConditionOfType(condType toolchainv1alpha1.ConditionType) func(record *toolchainv1alpha1.MasterUserRecord, message string) error {
-	return func(mur *toolchainv1alpha1.MasterUserRecord, message string) error {
-		cond := toolchainv1alpha1.Condition{Type: condType}
-		for _, con := range mur.Status.Conditions {
-			if con.Type == condType {
-				cond = con
-				break
+// manageCleanUp deletes the UserAccounts in the member clusters and removes the finalizer of the MasterUserRecord.
+// The UserAccounts in the member clusters which are unreachable are abandoned if the MasterUserRecord can be
+// force-deleted (see `canAbandonUserAccounts`): they are then listed in the orphan report, to be deleted if
+// their member cluster comes back.
+func (r *ReconcileMasterUserRecord) manageCleanUp(mur *toolchainv1alpha1.MasterUserRecord) error {
+	abandoned := []string{}
+	for _, ua := range mur.Spec.UserAccounts {
+		err := r.deleteUserAccount(ua.TargetCluster, mur.Name)
+		if err == nil {
+			continue
+		}
+		if isUnreachable(err) {
+			abandon, cfgErr := r.canAbandonUserAccounts(mur)
+			if cfgErr != nil {
+				return errs.Wrap(cfgErr, "unable to load the toolchain configuration")
+			}
+			if abandon {
+				abandoned = append(abandoned, ua.TargetCluster)
+				continue
 			}
 		}
-		cond.Message = message
-		return updateStatusConditions(r.client, mur, cond)
+		return r.wrapErrorWithStatusUpdate(log, mur, r.setStatusFailed(unableToDeleteUserAccountsReason), err,
+			"failed to delete UserAccount in the member cluster '%s'", ua.TargetCluster)
 	}
-}
-
-func (r *ReconcileMasterUserRecord) manageCleanUp(mur *toolchainv1alpha1.MasterUserRecord) error {
-	for _, ua := range mur.Spec.UserAccounts {
-		if err := r.deleteUserAccount(ua.TargetCluster, mur.Name); err != nil {
-			return r.wrapErrorWithStatusUpdate(log, mur, r.setStatusFailed(unableToDeleteUserAccountsReason), err,
-				"failed to delete UserAccount in the member cluster '%s'", ua.TargetCluster)
+	if len(abandoned) > 0 {
+		if err := reportOrphanUserAccounts(r.client, mur.Namespace, mur.Name, abandoned); err != nil {
+			return r.wrapErrorWithStatusUpdate(log, mur, r.setStatusFailed(unableToReportOrphanUserAccountsReason), err,
+				"failed to report the UserAccounts abandoned in the member clusters %v", abandoned)
 		}
+		log.Info("abandoned the UserAccounts in the unreachable member clusters", "Name", mur.Name, "TargetClusters", abandoned)
+		r.recorder.Eventf(mur, corev1.EventTypeWarning, abandonedUserAccountsReason,
+			"Abandoned the UserAccounts in the unreachable member clusters: %s", strings.Join(abandoned, ", "))
 	}
 	// Remove finalizer from MasterUserRecord
 	coputil.RemoveFinalizer(mur, murFinalizerName)
@@ -270,10 +313,15 @@ func (r *ReconcileMasterUserRecord) deleteUserAccount(targetCluster, name string
 	if err != nil {
 		return err
 	}
+	return deleteUserAccountIn(memberCluster, name)
+}
+
+// deleteUserAccountIn deletes the UserAccount with the given name in the given member cluster, if it exists
+func deleteUserAccountIn(memberCluster *cluster.FedCluster, name string) error {
 	// Get the User associated with the UserAccount
 	userAcc := &toolchainv1alpha1.UserAccount{}
 	namespacedName := types.NamespacedName{Namespace: memberCluster.OperatorNamespace, Name: name}
-	err = memberCluster.Client.Get(context.TODO(), namespacedName, userAcc)
+	err := memberCluster.Client.Get(context.TODO(), namespacedName, userAcc)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			return nil
@@ -315,13 +363,15 @@ func updateStatusConditions(cl client.Client, record *toolchainv1alpha1.MasterUs
 }
 
 func newUserAccount(nsdName types.NamespacedName, spec toolchainv1alpha1.UserAccountSpec) *toolchainv1alpha1.UserAccount {
-	return &toolchainv1alpha1.UserAccount{
+	userAccount := &toolchainv1alpha1.UserAccount{
 		ObjectMeta: v1.ObjectMeta{
 			Name:      nsdName.Name,
 			Namespace: nsdName.Namespace,
 		},
 		Spec: spec,
 	}
+	markSynchronized(userAccount, spec)
+	return userAccount
 }
 
 func namespacedName(namespace, name string) types.NamespacedName {
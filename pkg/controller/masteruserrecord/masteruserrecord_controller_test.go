@@ -7,6 +7,8 @@ import (
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
 	"github.com/codeready-toolchain/host-operator/pkg/apis"
+	"github.com/codeready-toolchain/host-operator/pkg/config"
+	"github.com/codeready-toolchain/host-operator/pkg/console"
 	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
 	"github.com/codeready-toolchain/toolchain-common/pkg/test"
 	murtest "github.com/codeready-toolchain/toolchain-common/pkg/test/masteruserrecord"
@@ -17,11 +19,13 @@ import (
 	apierros "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 	"sigs.k8s.io/kubefed/pkg/apis/core/common"
 	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1/defaults"
 )
 
 type getMemberCluster func(clusters ...clientForCluster) func(name string) (*cluster.FedCluster, bool)
@@ -130,12 +134,12 @@ func TestCreateSynchronizeOrDeleteUserAccountFailed(t *testing.T) {
 			clusterClient(test.MemberClusterName, memberClient))
 
 		// when
-		_, err := cntrl.Reconcile(newMurRequest(mur))
+		result, err := cntrl.Reconcile(newMurRequest(mur))
 
 		// then
-		require.Error(t, err)
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: defaults.DefaultClusterHealthCheckPeriod}, result)
 		msg := "the member cluster member-cluster is not ready"
-		assert.Contains(t, err.Error(), msg)
 
 		uatest.AssertThatUserAccount(t, "john", memberClient).DoesNotExist()
 		murtest.AssertThatMasterUserRecord(t, "john", hostClient).
@@ -150,12 +154,12 @@ func TestCreateSynchronizeOrDeleteUserAccountFailed(t *testing.T) {
 			clusterClient(test.MemberClusterName, memberClient))
 
 		// when
-		_, err := cntrl.Reconcile(newMurRequest(mur))
+		result, err := cntrl.Reconcile(newMurRequest(mur))
 
 		// then
-		require.Error(t, err)
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: defaults.DefaultClusterHealthCheckPeriod}, result)
 		msg := "the member cluster member-cluster is not ready"
-		assert.Contains(t, err.Error(), msg)
 
 		murtest.AssertThatMasterUserRecord(t, "john", hostClient).
 			HasConditions(toBeNotReady(targetClusterNotReadyReason, msg)).
@@ -509,6 +513,8 @@ func apiScheme(t *testing.T) *runtime.Scheme {
 	s := scheme.Scheme
 	err := apis.AddToScheme(s)
 	require.NoError(t, err)
+	err = v1beta1.AddToScheme(s)
+	require.NoError(t, err)
 	return s
 }
 
@@ -517,6 +523,10 @@ func newController(hostCl client.Client, s *runtime.Scheme, getMemberCluster get
 		client:                hostCl,
 		scheme:                s,
 		retrieveMemberCluster: getMemberCluster(memberCl...),
+		configLoader:          config.NewLoader(hostCl, record.NewFakeRecorder(10)),
+		recorder:              record.NewFakeRecorder(10),
+		consoleURLs:           console.NewDiscovery(hostCl),
+		drifts:                newDriftTracker(),
 	}
 }
 
@@ -540,6 +550,7 @@ func newGetMemberCluster(ok bool, status v1.ConditionStatus) getMemberCluster {
 				return nil, false
 			}
 			return &cluster.FedCluster{
+				Name:              name,
 				Client:            cl,
 				Type:              cluster.Host,
 				OperatorNamespace: test.MemberOperatorNs,
@@ -0,0 +1,330 @@
+package masteruserrecord
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// MigrateFromClusterAnnotationKey the annotation to set on a MasterUserRecord, along with the `migrate-to-cluster` one,
+	// to move the UserAccount from a member cluster to another one
+	MigrateFromClusterAnnotationKey = "toolchain.dev.openshift.com/migrate-from-cluster"
+	// MigrateToClusterAnnotationKey the annotation with the name of the member cluster in which the UserAccount should be moved
+	MigrateToClusterAnnotationKey = "toolchain.dev.openshift.com/migrate-to-cluster"
+	// migrationStartedAnnotationKey the annotation in which the start time of the ongoing migration attempt is recorded
+	migrationStartedAnnotationKey = "toolchain.dev.openshift.com/migration-started-time"
+	// migrationAttemptsAnnotationKey the annotation in which the number of failed migration attempts is recorded
+	migrationAttemptsAnnotationKey = "toolchain.dev.openshift.com/migration-attempts"
+
+	// migrated the type of the condition which tracks the progress of the migration between member clusters
+	migrated toolchainv1alpha1.ConditionType = "Migrated"
+
+	// Migration condition reasons
+	provisioningTargetAccountReason = "ProvisioningTargetAccount"
+	waitingForTargetAccountReason   = "WaitingForTargetAccount"
+	removingSourceAccountReason     = "RemovingSourceAccount"
+	migratedReason                  = "Migrated"
+	migrationRolledBackReason       = "MigrationRolledBack"
+	migrationFailedReason           = "MigrationFailed"
+
+	// migrationTimeout the time after which the migration is rolled back if the UserAccount in the target cluster is not ready
+	migrationTimeout = 10 * time.Minute
+	// migrationCheckPeriod the period at which the readiness of the UserAccount in the target cluster is checked
+	migrationCheckPeriod = 10 * time.Second
+	// maxMigrationAttempts the number of attempts after which a failing migration is abandoned
+	maxMigrationAttempts = 3
+)
+
+// migrate moves the UserAccount of the given MasterUserRecord from the member cluster set in the
+// `toolchain.dev.openshift.com/migrate-from-cluster` annotation to the one set in the
+// `toolchain.dev.openshift.com/migrate-to-cluster` annotation (if any). The migration is done in phases: first a
+// UserAccount is added for the target cluster, with the same spec as the one in the source cluster (it is then created
+// by the regular UserAccount provisioning), then it is awaited until it is ready, and finally the UserAccount in
+// the source cluster is removed.
+// If the UserAccount in the target cluster is not ready after the migration timeout, then the migration is rolled back
+// (ie, the UserAccount in the target cluster is removed) and retried, until the maximum number of attempts is reached.
+// Returns a non-empty result if the MasterUserRecord should be reconciled again to check the progress of the migration.
+func (r *ReconcileMasterUserRecord) migrate(logger logr.Logger, mur *toolchainv1alpha1.MasterUserRecord) (reconcile.Result, error) {
+	from := mur.Annotations[MigrateFromClusterAnnotationKey]
+	to := mur.Annotations[MigrateToClusterAnnotationKey]
+	if from == "" || to == "" {
+		return reconcile.Result{}, nil
+	}
+	_, source := findUserAccount(mur, from)
+	_, target := findUserAccount(mur, to)
+	switch {
+	case from == to:
+		return reconcile.Result{}, r.abandonMigration(logger, mur, fmt.Sprintf("cannot migrate from the '%s' cluster to itself", from))
+
+	case source < 0 && target < 0:
+		return reconcile.Result{}, r.abandonMigration(logger, mur, fmt.Sprintf("no UserAccount in the '%s' cluster", from))
+
+	case source < 0:
+		// the UserAccount in the source cluster was already removed: the migration is complete
+		return reconcile.Result{}, r.completeMigration(logger, mur, from, to)
+
+	case target < 0:
+		if _, started := mur.Annotations[migrationStartedAnnotationKey]; started {
+			// the UserAccount in the target cluster was removed by a rollback
+			return reconcile.Result{}, r.abandonMigration(logger, mur, fmt.Sprintf("the UserAccount in the '%s' cluster was removed", to))
+		}
+		return reconcile.Result{RequeueAfter: migrationCheckPeriod}, r.provisionTargetAccount(logger, mur, source, from, to)
+
+	default:
+		started, err := time.Parse(time.RFC3339, mur.Annotations[migrationStartedAnnotationKey])
+		if err != nil {
+			// the UserAccount was already in the target cluster before the migration started
+			return reconcile.Result{}, r.abandonMigration(logger, mur, fmt.Sprintf("a UserAccount already exists in the '%s' cluster", to))
+		}
+		if status, _ := getUserAccountStatus(to, mur); IsReady(status.Conditions) {
+			return reconcile.Result{}, r.removeSourceAccount(logger, mur, from, to)
+		}
+		if time.Since(started) > migrationTimeout {
+			return reconcile.Result{}, r.rollbackMigration(logger, mur, target, to)
+		}
+		return reconcile.Result{RequeueAfter: migrationCheckPeriod}, updateStatusConditions(r.client, mur, toolchainv1alpha1.Condition{
+			Type:    migrated,
+			Status:  corev1.ConditionFalse,
+			Reason:  waitingForTargetAccountReason,
+			Message: fmt.Sprintf("waiting for the UserAccount in the '%s' cluster to be ready", to),
+		})
+	}
+}
+
+// provisionTargetAccount adds a UserAccount for the target cluster, with the same spec as the one in the source cluster
+func (r *ReconcileMasterUserRecord) provisionTargetAccount(logger logr.Logger, mur *toolchainv1alpha1.MasterUserRecord, source int, from, to string) error {
+	account := *mur.Spec.UserAccounts[source].DeepCopy()
+	account.TargetCluster = to
+	account.SyncIndex = ""
+	mur.Spec.UserAccounts = append(mur.Spec.UserAccounts, account)
+	mur.Annotations[migrationStartedAnnotationKey] = time.Now().UTC().Format(time.RFC3339)
+	if err := r.client.Update(context.TODO(), mur); err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, mur, r.setMigrationFailed(provisioningTargetAccountReason), err,
+			"unable to add the UserAccount in the '%s' cluster", to)
+	}
+	logger.Info("migrating MasterUserRecord", "From", from, "To", to)
+	return updateStatusConditions(r.client, mur, toolchainv1alpha1.Condition{
+		Type:    migrated,
+		Status:  corev1.ConditionFalse,
+		Reason:  provisioningTargetAccountReason,
+		Message: fmt.Sprintf("provisioning the UserAccount in the '%s' cluster", to),
+	})
+}
+
+// removeSourceAccount deletes the UserAccount in the source cluster and removes it from the MasterUserRecord
+func (r *ReconcileMasterUserRecord) removeSourceAccount(logger logr.Logger, mur *toolchainv1alpha1.MasterUserRecord, from, to string) error {
+	if err := updateStatusConditions(r.client, mur, toolchainv1alpha1.Condition{
+		Type:    migrated,
+		Status:  corev1.ConditionFalse,
+		Reason:  removingSourceAccountReason,
+		Message: fmt.Sprintf("removing the UserAccount in the '%s' cluster", from),
+	}); err != nil {
+		return err
+	}
+	if err := r.removeUserAccount(mur, from); err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, mur, r.setMigrationFailed(removingSourceAccountReason), err,
+			"unable to remove the UserAccount in the '%s' cluster", from)
+	}
+	return r.completeMigration(logger, mur, from, to)
+}
+
+// rollbackMigration removes the UserAccount in the target cluster, and schedules a new attempt if the maximum
+// number of attempts is not reached yet
+func (r *ReconcileMasterUserRecord) rollbackMigration(logger logr.Logger, mur *toolchainv1alpha1.MasterUserRecord, target int, to string) error {
+	attempts, _ := strconv.Atoi(mur.Annotations[migrationAttemptsAnnotationKey])
+	attempts++
+	message := fmt.Sprintf("the UserAccount in the '%s' cluster was not ready after %s (attempt %d of %d)", to, migrationTimeout, attempts, maxMigrationAttempts)
+	logger.Info("rolling back the migration of the MasterUserRecord", "To", to, "Attempts", attempts)
+	if err := r.deleteUserAccount(to, mur.Name); err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, mur, r.setMigrationFailed(migrationRolledBackReason), err,
+			"unable to delete the UserAccount in the '%s' cluster", to)
+	}
+	mur.Spec.UserAccounts = append(mur.Spec.UserAccounts[:target], mur.Spec.UserAccounts[target+1:]...)
+	delete(mur.Annotations, migrationStartedAnnotationKey)
+	if attempts >= maxMigrationAttempts {
+		return r.abandonMigration(logger, mur, message)
+	}
+	mur.Annotations[migrationAttemptsAnnotationKey] = strconv.Itoa(attempts)
+	if err := r.client.Update(context.TODO(), mur); err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, mur, r.setMigrationFailed(migrationRolledBackReason), err,
+			"unable to remove the UserAccount in the '%s' cluster", to)
+	}
+	return r.updateMigrationStatus(mur, toolchainv1alpha1.Condition{
+		Type:    migrated,
+		Status:  corev1.ConditionFalse,
+		Reason:  migrationRolledBackReason,
+		Message: message,
+	})
+}
+
+// completeMigration removes the migration annotations and marks the migration as completed
+func (r *ReconcileMasterUserRecord) completeMigration(logger logr.Logger, mur *toolchainv1alpha1.MasterUserRecord, from, to string) error {
+	removeMigrationAnnotations(mur)
+	if err := r.client.Update(context.TODO(), mur); err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, mur, r.setMigrationFailed(removingSourceAccountReason), err,
+			"unable to complete the migration to the '%s' cluster", to)
+	}
+	logger.Info("migrated MasterUserRecord", "From", from, "To", to)
+	return r.updateMigrationStatus(mur, toolchainv1alpha1.Condition{
+		Type:    migrated,
+		Status:  corev1.ConditionTrue,
+		Reason:  migratedReason,
+		Message: fmt.Sprintf("migrated from the '%s' cluster to the '%s' cluster", from, to),
+	})
+}
+
+// abandonMigration removes the migration annotations and marks the migration as failed
+func (r *ReconcileMasterUserRecord) abandonMigration(logger logr.Logger, mur *toolchainv1alpha1.MasterUserRecord, message string) error {
+	logger.Info("abandoning the migration of the MasterUserRecord", "Reason", message)
+	removeMigrationAnnotations(mur)
+	if err := r.client.Update(context.TODO(), mur); err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, mur, r.setMigrationFailed(migrationFailedReason), err,
+			"unable to abandon the migration")
+	}
+	return r.updateMigrationStatus(mur, toolchainv1alpha1.Condition{
+		Type:    migrated,
+		Status:  corev1.ConditionFalse,
+		Reason:  migrationFailedReason,
+		Message: message,
+	})
+}
+
+// updateMigrationStatus sets the migration condition and removes the statuses of the UserAccounts which are
+// no longer in the spec of the MasterUserRecord
+func (r *ReconcileMasterUserRecord) updateMigrationStatus(mur *toolchainv1alpha1.MasterUserRecord, migration toolchainv1alpha1.Condition) error {
+	mur.Status.UserAccounts = withoutRemovedUserAccountStatuses(mur)
+	mur.Status.Conditions, _ = condition.AddOrUpdateStatusConditions(mur.Status.Conditions, migration)
+	return r.client.Status().Update(context.TODO(), mur)
+}
+
+// removeUserAccount deletes the UserAccount in the given member cluster and removes it from the spec of the MasterUserRecord
+func (r *ReconcileMasterUserRecord) removeUserAccount(mur *toolchainv1alpha1.MasterUserRecord, targetCluster string) error {
+	if err := r.deleteUserAccount(targetCluster, mur.Name); err != nil {
+		return err
+	}
+	if _, index := findUserAccount(mur, targetCluster); index >= 0 {
+		mur.Spec.UserAccounts = append(mur.Spec.UserAccounts[:index], mur.Spec.UserAccounts[index+1:]...)
+	}
+	return nil
+}
+
+func (r *ReconcileMasterUserRecord) setMigrationFailed(reason string) func(mur *toolchainv1alpha1.MasterUserRecord, message string) error {
+	return func(mur *toolchainv1alpha1.MasterUserRecord, message string) error {
+		return updateStatusConditions(r.client, mur, toolchainv1alpha1.Condition{
+			Type:    migrated,
+			Status:  corev1.ConditionFalse,
+			Reason:  reason,
+			Message: message,
+		})
+	}
+}
+
+// findUserAccount returns the UserAccount of the MasterUserRecord in the given cluster along with its index,
+// or -1 if the MasterUserRecord has no UserAccount in this cluster
+func findUserAccount(mur *toolchainv1alpha1.MasterUserRecord, targetCluster string) (toolchainv1alpha1.UserAccountEmbedded, int) {
+	for i, ua := range mur.Spec.UserAccounts {
+		if ua.TargetCluster == targetCluster {
+			return ua, i
+		}
+	}
+	return toolchainv1alpha1.UserAccountEmbedded{}, -1
+}
+
+// withoutRemovedUserAccountStatuses returns the UserAccount statuses of the MasterUserRecord whose cluster
+// is still in the spec
+func withoutRemovedUserAccountStatuses(mur *toolchainv1alpha1.MasterUserRecord) []toolchainv1alpha1.UserAccountStatusEmbedded {
+	remaining := []toolchainv1alpha1.UserAccountStatusEmbedded{}
+	for _, status := range mur.Status.UserAccounts {
+		if _, index := findUserAccount(mur, status.Cluster.Name); index >= 0 {
+			remaining = append(remaining, status)
+		}
+	}
+	return remaining
+}
+
+// TargetClusterSelector returns the name of the member cluster to which the UserAccount of the given
+// MasterUserRecord should be migrated
+type TargetClusterSelector func(mur *toolchainv1alpha1.MasterUserRecord) (string, error)
+
+// ToCluster returns a TargetClusterSelector which always selects the given member cluster
+func ToCluster(name string) TargetClusterSelector {
+	return func(_ *toolchainv1alpha1.MasterUserRecord) (string, error) {
+		return name, nil
+	}
+}
+
+// MigrateUserAccounts requests the migration of all the MasterUserRecords of the given namespace which have a
+// UserAccount in the `from` member cluster to the member cluster returned by `selectTarget`, by setting their
+// migration annotations.
+// No more than `maxInFlight` MasterUserRecords are migrated at the same time, so this function should be called
+// again until there is no MasterUserRecord left to migrate.
+// Returns the number of MasterUserRecords which still have a UserAccount in the `from` cluster (including the
+// ones being migrated), and the number of MasterUserRecords whose migration failed (which are not migrated again).
+func MigrateUserAccounts(cl client.Client, namespace, from string, selectTarget TargetClusterSelector, maxInFlight int) (int, int, error) {
+	murs := &toolchainv1alpha1.MasterUserRecordList{}
+	if err := cl.List(context.TODO(), murs, client.InNamespace(namespace)); err != nil {
+		return 0, 0, err
+	}
+	remaining, failed, inFlight := 0, 0, 0
+	pending := []toolchainv1alpha1.MasterUserRecord{}
+	for _, mur := range murs.Items {
+		if _, index := findUserAccount(&mur, from); index < 0 {
+			continue
+		}
+		if migrationFailed(mur) {
+			failed++
+			continue
+		}
+		remaining++
+		if mur.Annotations[MigrateFromClusterAnnotationKey] != "" {
+			inFlight++
+			continue
+		}
+		pending = append(pending, mur)
+	}
+	for i := 0; i < len(pending) && inFlight < maxInFlight; i++ {
+		mur := pending[i]
+		to, err := selectTarget(&mur)
+		if err != nil {
+			return remaining, failed, errors.Wrapf(err, "unable to select the member cluster to which the MasterUserRecord '%s' should be migrated", mur.Name)
+		}
+		if mur.Annotations == nil {
+			mur.Annotations = map[string]string{}
+		}
+		mur.Annotations[MigrateFromClusterAnnotationKey] = from
+		mur.Annotations[MigrateToClusterAnnotationKey] = to
+		if err := cl.Update(context.TODO(), &mur); err != nil {
+			return remaining, failed, err
+		}
+		inFlight++
+	}
+	return remaining, failed, nil
+}
+
+// migrationFailed returns true if the last migration of the MasterUserRecord failed
+func migrationFailed(mur toolchainv1alpha1.MasterUserRecord) bool {
+	for _, cond := range mur.Status.Conditions {
+		if cond.Type == migrated {
+			return cond.Reason == migrationFailedReason
+		}
+	}
+	return false
+}
+
+func removeMigrationAnnotations(mur *toolchainv1alpha1.MasterUserRecord) {
+	delete(mur.Annotations, MigrateFromClusterAnnotationKey)
+	delete(mur.Annotations, MigrateToClusterAnnotationKey)
+	delete(mur.Annotations, migrationStartedAnnotationKey)
+	delete(mur.Annotations, migrationAttemptsAnnotationKey)
+}
@@ -0,0 +1,307 @@
+package masteruserrecord
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	murtest "github.com/codeready-toolchain/toolchain-common/pkg/test/masteruserrecord"
+	uatest "github.com/codeready-toolchain/toolchain-common/pkg/test/useraccount"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestMigrate(t *testing.T) {
+	// given
+	logf.SetLogger(logf.ZapLogger(true))
+	s := apiScheme(t)
+
+	migrationAnnotations := func(started time.Time, attempts string) map[string]string {
+		annotations := map[string]string{
+			MigrateFromClusterAnnotationKey: test.MemberClusterName,
+			MigrateToClusterAnnotationKey:   "member2-cluster",
+		}
+		if !started.IsZero() {
+			annotations[migrationStartedAnnotationKey] = started.UTC().Format(time.RFC3339)
+		}
+		if attempts != "" {
+			annotations[migrationAttemptsAnnotationKey] = attempts
+		}
+		return annotations
+	}
+
+	targetStatus := func(conditions ...toolchainv1alpha1.Condition) toolchainv1alpha1.UserAccountStatusEmbedded {
+		return toolchainv1alpha1.UserAccountStatusEmbedded{
+			Cluster: toolchainv1alpha1.Cluster{
+				Name: "member2-cluster",
+			},
+			UserAccountStatus: toolchainv1alpha1.UserAccountStatus{
+				Conditions: conditions,
+			},
+		}
+	}
+
+	t.Run("provision the UserAccount in the target cluster", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john", murtest.StatusCondition(toBeProvisioned()))
+		mur.Annotations = migrationAnnotations(time.Time{}, "")
+		userAccount := uatest.NewUserAccountFromMur(mur, uatest.StatusCondition(toBeProvisioned()))
+		memberClient := test.NewFakeClient(t, userAccount, consoleRoute())
+		memberClient2 := test.NewFakeClient(t, consoleRoute())
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, v1.ConditionTrue),
+			clusterClient(test.MemberClusterName, memberClient), clusterClient("member2-cluster", memberClient2))
+
+		// when
+		result, err := cntrl.Reconcile(newMurRequest(mur))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: migrationCheckPeriod}, result)
+		migrating := getMasterUserRecord(t, hostClient, mur)
+		require.Len(t, migrating.Spec.UserAccounts, 2)
+		assert.Equal(t, "member2-cluster", migrating.Spec.UserAccounts[1].TargetCluster)
+		assert.Equal(t, mur.Spec.UserAccounts[0].Spec, migrating.Spec.UserAccounts[1].Spec)
+		assert.Contains(t, migrating.Annotations, migrationStartedAnnotationKey)
+		uatest.AssertThatUserAccount(t, "john", memberClient).Exists()
+		uatest.AssertThatUserAccount(t, "john", memberClient2).
+			Exists().
+			HasSpec(mur.Spec.UserAccounts[0].Spec)
+		test.AssertConditionsMatch(t, migrating.Status.Conditions,
+			toBeNotReady(provisioningReason, ""),
+			toolchainv1alpha1.Condition{
+				Type:    migrated,
+				Status:  v1.ConditionFalse,
+				Reason:  provisioningTargetAccountReason,
+				Message: "provisioning the UserAccount in the 'member2-cluster' cluster",
+			})
+	})
+
+	t.Run("wait for the UserAccount in the target cluster", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john", murtest.AdditionalAccounts("member2-cluster"))
+		mur.Annotations = migrationAnnotations(time.Now(), "")
+		mur.Status.UserAccounts = []toolchainv1alpha1.UserAccountStatusEmbedded{targetStatus(toBeNotReady(provisioningReason, ""))}
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, v1.ConditionTrue))
+
+		// when
+		result, err := cntrl.migrate(log, mur)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: migrationCheckPeriod}, result)
+		murtest.AssertThatMasterUserRecord(t, "john", hostClient).
+			HasConditions(toolchainv1alpha1.Condition{
+				Type:    migrated,
+				Status:  v1.ConditionFalse,
+				Reason:  waitingForTargetAccountReason,
+				Message: "waiting for the UserAccount in the 'member2-cluster' cluster to be ready",
+			})
+	})
+
+	t.Run("remove the UserAccount in the source cluster when the target one is ready", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john", murtest.AdditionalAccounts("member2-cluster"))
+		mur.Annotations = migrationAnnotations(time.Now(), "1")
+		mur.Status.UserAccounts = []toolchainv1alpha1.UserAccountStatusEmbedded{
+			{
+				Cluster: toolchainv1alpha1.Cluster{
+					Name: test.MemberClusterName,
+				},
+			},
+			targetStatus(toBeProvisioned()),
+		}
+		memberClient := test.NewFakeClient(t, uatest.NewUserAccountFromMur(mur))
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, v1.ConditionTrue),
+			clusterClient(test.MemberClusterName, memberClient))
+
+		// when
+		result, err := cntrl.migrate(log, mur)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, result)
+		uatest.AssertThatUserAccount(t, "john", memberClient).DoesNotExist()
+		done := getMasterUserRecord(t, hostClient, mur)
+		require.Len(t, done.Spec.UserAccounts, 1)
+		assert.Equal(t, "member2-cluster", done.Spec.UserAccounts[0].TargetCluster)
+		assert.Empty(t, done.Annotations)
+		murtest.AssertThatMasterUserRecord(t, "john", hostClient).
+			HasStatusUserAccounts("member2-cluster").
+			HasConditions(toolchainv1alpha1.Condition{
+				Type:    migrated,
+				Status:  v1.ConditionTrue,
+				Reason:  migratedReason,
+				Message: "migrated from the 'member-cluster' cluster to the 'member2-cluster' cluster",
+			})
+	})
+
+	t.Run("roll back when the UserAccount in the target cluster is not ready in time", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john", murtest.AdditionalAccounts("member2-cluster"))
+		mur.Annotations = migrationAnnotations(time.Now().Add(-migrationTimeout-time.Minute), "")
+		mur.Status.UserAccounts = []toolchainv1alpha1.UserAccountStatusEmbedded{targetStatus(toBeNotReady(provisioningReason, ""))}
+		memberClient2 := test.NewFakeClient(t, uatest.NewUserAccountFromMur(mur))
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, v1.ConditionTrue),
+			clusterClient("member2-cluster", memberClient2))
+
+		// when
+		_, err := cntrl.migrate(log, mur)
+
+		// then
+		require.NoError(t, err)
+		uatest.AssertThatUserAccount(t, "john", memberClient2).DoesNotExist()
+		rolledBack := getMasterUserRecord(t, hostClient, mur)
+		require.Len(t, rolledBack.Spec.UserAccounts, 1)
+		assert.Equal(t, test.MemberClusterName, rolledBack.Spec.UserAccounts[0].TargetCluster)
+		assert.Equal(t, "1", rolledBack.Annotations[migrationAttemptsAnnotationKey])
+		assert.NotContains(t, rolledBack.Annotations, migrationStartedAnnotationKey)
+		assert.Empty(t, rolledBack.Status.UserAccounts)
+		test.AssertConditionsMatch(t, rolledBack.Status.Conditions, toolchainv1alpha1.Condition{
+			Type:    migrated,
+			Status:  v1.ConditionFalse,
+			Reason:  migrationRolledBackReason,
+			Message: "the UserAccount in the 'member2-cluster' cluster was not ready after 10m0s (attempt 1 of 3)",
+		})
+	})
+
+	t.Run("abandon after the maximum number of attempts", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john", murtest.AdditionalAccounts("member2-cluster"))
+		mur.Annotations = migrationAnnotations(time.Now().Add(-migrationTimeout-time.Minute), "2")
+		memberClient2 := test.NewFakeClient(t, uatest.NewUserAccountFromMur(mur))
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, v1.ConditionTrue),
+			clusterClient("member2-cluster", memberClient2))
+
+		// when
+		_, err := cntrl.migrate(log, mur)
+
+		// then
+		require.NoError(t, err)
+		uatest.AssertThatUserAccount(t, "john", memberClient2).DoesNotExist()
+		abandoned := getMasterUserRecord(t, hostClient, mur)
+		require.Len(t, abandoned.Spec.UserAccounts, 1)
+		assert.Equal(t, test.MemberClusterName, abandoned.Spec.UserAccounts[0].TargetCluster)
+		assert.Empty(t, abandoned.Annotations)
+		test.AssertConditionsMatch(t, abandoned.Status.Conditions, toolchainv1alpha1.Condition{
+			Type:    migrated,
+			Status:  v1.ConditionFalse,
+			Reason:  migrationFailedReason,
+			Message: "the UserAccount in the 'member2-cluster' cluster was not ready after 10m0s (attempt 3 of 3)",
+		})
+	})
+
+	t.Run("abandon when there is no UserAccount in the source cluster", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john")
+		mur.Annotations = map[string]string{
+			MigrateFromClusterAnnotationKey: "unknown-cluster",
+			MigrateToClusterAnnotationKey:   "member2-cluster",
+		}
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, v1.ConditionTrue))
+
+		// when
+		_, err := cntrl.migrate(log, mur)
+
+		// then
+		require.NoError(t, err)
+		abandoned := getMasterUserRecord(t, hostClient, mur)
+		assert.Empty(t, abandoned.Annotations)
+		test.AssertConditionsMatch(t, abandoned.Status.Conditions, toolchainv1alpha1.Condition{
+			Type:    migrated,
+			Status:  v1.ConditionFalse,
+			Reason:  migrationFailedReason,
+			Message: "no UserAccount in the 'unknown-cluster' cluster",
+		})
+	})
+}
+
+func TestMigrateUserAccounts(t *testing.T) {
+	// given
+	apiScheme(t)
+	objs := []runtime.Object{}
+	for i := 0; i < 3; i++ {
+		objs = append(objs, murtest.NewMasterUserRecord(fmt.Sprintf("user-%d", i)))
+	}
+	failed := murtest.NewMasterUserRecord("failed")
+	failed.Status.Conditions = []toolchainv1alpha1.Condition{{
+		Type:   migrated,
+		Status: v1.ConditionFalse,
+		Reason: migrationFailedReason,
+	}}
+	other := murtest.NewMasterUserRecord("other")
+	other.Spec.UserAccounts[0].TargetCluster = "member2-cluster"
+	objs = append(objs, failed, other)
+	hostClient := test.NewFakeClient(t, objs...)
+
+	t.Run("migrate the first batch", func(t *testing.T) {
+		// when
+		remaining, failedCount, err := MigrateUserAccounts(hostClient, test.HostOperatorNs, test.MemberClusterName, ToCluster("member3-cluster"), 2)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 3, remaining)
+		assert.Equal(t, 1, failedCount)
+		assert.Equal(t, 2, countMigrating(t, hostClient))
+	})
+
+	t.Run("do not exceed the maximum number of migrations in flight", func(t *testing.T) {
+		// when
+		remaining, _, err := MigrateUserAccounts(hostClient, test.HostOperatorNs, test.MemberClusterName, ToCluster("member3-cluster"), 2)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 3, remaining)
+		assert.Equal(t, 2, countMigrating(t, hostClient))
+	})
+
+	t.Run("failed to select the target cluster", func(t *testing.T) {
+		// given
+		hostClient := test.NewFakeClient(t, objs...)
+		selectTarget := func(_ *toolchainv1alpha1.MasterUserRecord) (string, error) {
+			return "", fmt.Errorf("no capacity")
+		}
+
+		// when
+		_, _, err := MigrateUserAccounts(hostClient, test.HostOperatorNs, test.MemberClusterName, selectTarget, 2)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no capacity")
+		assert.Equal(t, 0, countMigrating(t, hostClient))
+	})
+}
+
+func getMasterUserRecord(t *testing.T, cl client.Client, mur *toolchainv1alpha1.MasterUserRecord) *toolchainv1alpha1.MasterUserRecord {
+	result := &toolchainv1alpha1.MasterUserRecord{}
+	err := cl.Get(context.TODO(), namespacedName(mur.Namespace, mur.Name), result)
+	require.NoError(t, err)
+	return result
+}
+
+func countMigrating(t *testing.T, cl client.Client) int {
+	murs := &toolchainv1alpha1.MasterUserRecordList{}
+	err := cl.List(context.TODO(), murs)
+	require.NoError(t, err)
+	count := 0
+	for _, mur := range murs.Items {
+		if mur.Annotations[MigrateToClusterAnnotationKey] == "member3-cluster" {
+			count++
+		}
+	}
+	return count
+}
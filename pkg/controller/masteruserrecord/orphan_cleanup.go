@@ -0,0 +1,117 @@
+package masteruserrecord
+
+import (
+	"context"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+	"sigs.k8s.io/kubefed/pkg/controller/util"
+)
+
+// addOrphanUserAccountsCleanup adds a Controller to mgr which deletes the orphan UserAccounts (ie, the UserAccounts
+// abandoned when their MasterUserRecord was deleted) in the member clusters which are reachable again
+func addOrphanUserAccountsCleanup(mgr manager.Manager) error {
+	r := &ReconcileOrphanUserAccounts{
+		client:                mgr.GetClient(),
+		retrieveMemberCluster: cluster.GetFedCluster,
+	}
+	c, err := controller.New("orphanuseraccounts-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+	// Watch for all changes of the KubeFedClusters, including the changes of their readiness
+	err = c.Watch(&source.Kind{Type: &v1beta1.KubeFedCluster{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+	// Watch for changes to the orphan report, and reconcile all the member clusters listed in it
+	return c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(clustersInOrphanReport),
+	})
+}
+
+// clustersInOrphanReport maps the orphan report to the requests for the member clusters listed in it
+func clustersInOrphanReport(obj handler.MapObject) []reconcile.Request {
+	report, ok := obj.Object.(*corev1.ConfigMap)
+	if !ok || report.Name != OrphanUserAccountsConfigMapName {
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for clusterName := range report.Data {
+		requests = append(requests, reconcile.Request{NamespacedName: namespacedName(report.Namespace, clusterName)})
+	}
+	return requests
+}
+
+var _ reconcile.Reconciler = &ReconcileOrphanUserAccounts{}
+
+// ReconcileOrphanUserAccounts deletes the orphan UserAccounts listed in the orphan report
+type ReconcileOrphanUserAccounts struct {
+	client                client.Client
+	retrieveMemberCluster func(name string) (*cluster.FedCluster, bool)
+}
+
+// Reconcile deletes the orphan UserAccounts in the member cluster of the KubeFedCluster, if the latter exists and is
+// ready, and removes the member cluster from the orphan report. An orphan UserAccount is kept if a MasterUserRecord
+// with the same name was provisioned again in the member cluster in the meantime.
+func (r *ReconcileOrphanUserAccounts) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+
+	report := &corev1.ConfigMap{}
+	if err := r.client.Get(context.TODO(), namespacedName(request.Namespace, OrphanUserAccountsConfigMapName), report); err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	names := orphanUserAccounts(report, request.Name)
+	if len(names) == 0 {
+		return reconcile.Result{}, nil
+	}
+	// wait for the member cluster to come back
+	kubeFedCluster := &v1beta1.KubeFedCluster{}
+	if err := r.client.Get(context.TODO(), request.NamespacedName, kubeFedCluster); err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	memberCluster, ok := r.retrieveMemberCluster(request.Name)
+	if !ok {
+		// the member cluster is added to the registry by another controller
+		return reconcile.Result{RequeueAfter: registryRequeueDelay}, nil
+	}
+	if !util.IsClusterReady(memberCluster.ClusterStatus) {
+		// the KubeFedCluster is reconciled again when its status changes
+		return reconcile.Result{}, nil
+	}
+
+	for _, name := range names {
+		mur := &toolchainv1alpha1.MasterUserRecord{}
+		if err := r.client.Get(context.TODO(), namespacedName(request.Namespace, name), mur); err != nil {
+			if !errors.IsNotFound(err) {
+				return reconcile.Result{}, err
+			}
+		} else if _, index := findUserAccount(mur, request.Name); index >= 0 {
+			reqLogger.Info("Keeping the orphan UserAccount which was provisioned again", "Name", name)
+			continue
+		}
+		if err := deleteUserAccountIn(memberCluster, name); err != nil {
+			return reconcile.Result{}, errs.Wrapf(err, "failed to delete the orphan UserAccount '%s'", name)
+		}
+		reqLogger.Info("Deleted the orphan UserAccount", "Name", name)
+	}
+	delete(report.Data, request.Name)
+	return reconcile.Result{}, r.client.Update(context.TODO(), report)
+}
@@ -0,0 +1,168 @@
+package masteruserrecord
+
+import (
+	"testing"
+
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	murtest "github.com/codeready-toolchain/toolchain-common/pkg/test/masteruserrecord"
+	uatest "github.com/codeready-toolchain/toolchain-common/pkg/test/useraccount"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/kubefed/pkg/apis/core/common"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+)
+
+func TestReconcileOrphanUserAccounts(t *testing.T) {
+	// given
+	apiScheme(t)
+	kubeFedCluster := &v1beta1.KubeFedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      test.MemberClusterName,
+			Namespace: test.HostOperatorNs,
+		},
+	}
+	john := murtest.NewMasterUserRecord("john")
+	jack := murtest.NewMasterUserRecord("jack")
+	request := reconcile.Request{NamespacedName: namespacedName(test.HostOperatorNs, test.MemberClusterName)}
+
+	t.Run("delete the orphan UserAccounts when the member cluster is back", func(t *testing.T) {
+		// given
+		memberClient := test.NewFakeClient(t, uatest.NewUserAccountFromMur(john), uatest.NewUserAccountFromMur(jack))
+		report := orphanReport(map[string]string{
+			test.MemberClusterName: "jack,jane,john",
+			"member2-cluster":      "john",
+		})
+		// jack signed up again in the meantime
+		r, hostClient := prepareOrphanUserAccounts(t, memberClient, corev1.ConditionTrue, kubeFedCluster, report, jack)
+
+		// when
+		result, err := r.Reconcile(request)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, result)
+		uatest.AssertThatUserAccount(t, "john", memberClient).DoesNotExist()
+		uatest.AssertThatUserAccount(t, "jack", memberClient).Exists()
+		assertOrphanReport(t, hostClient, map[string]string{"member2-cluster": "john"})
+	})
+
+	t.Run("wait while the member cluster is not ready", func(t *testing.T) {
+		// given
+		memberClient := test.NewFakeClient(t, uatest.NewUserAccountFromMur(john))
+		report := orphanReport(map[string]string{test.MemberClusterName: "john"})
+		r, hostClient := prepareOrphanUserAccounts(t, memberClient, corev1.ConditionFalse, kubeFedCluster, report)
+
+		// when
+		result, err := r.Reconcile(request)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, result)
+		uatest.AssertThatUserAccount(t, "john", memberClient).Exists()
+		assertOrphanReport(t, hostClient, map[string]string{test.MemberClusterName: "john"})
+	})
+
+	t.Run("wait while the KubeFedCluster does not exist", func(t *testing.T) {
+		// given
+		report := orphanReport(map[string]string{test.MemberClusterName: "john"})
+		r, hostClient := prepareOrphanUserAccounts(t, test.NewFakeClient(t), corev1.ConditionTrue, report)
+
+		// when
+		result, err := r.Reconcile(request)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, result)
+		assertOrphanReport(t, hostClient, map[string]string{test.MemberClusterName: "john"})
+	})
+
+	t.Run("requeue when the member cluster is not in the registry yet", func(t *testing.T) {
+		// given
+		report := orphanReport(map[string]string{test.MemberClusterName: "john"})
+		r, hostClient := prepareOrphanUserAccounts(t, test.NewFakeClient(t), corev1.ConditionTrue, kubeFedCluster, report)
+		r.retrieveMemberCluster = func(name string) (*cluster.FedCluster, bool) {
+			return nil, false
+		}
+
+		// when
+		result, err := r.Reconcile(request)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: registryRequeueDelay}, result)
+		assertOrphanReport(t, hostClient, map[string]string{test.MemberClusterName: "john"})
+	})
+
+	t.Run("no orphan UserAccount in the member cluster", func(t *testing.T) {
+		// given
+		r, _ := prepareOrphanUserAccounts(t, test.NewFakeClient(t), corev1.ConditionTrue, kubeFedCluster)
+
+		// when
+		result, err := r.Reconcile(request)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, result)
+	})
+}
+
+func TestClustersInOrphanReport(t *testing.T) {
+
+	t.Run("map the orphan report to its member clusters", func(t *testing.T) {
+		// given
+		report := orphanReport(map[string]string{
+			test.MemberClusterName: "john",
+			"member2-cluster":      "jack",
+		})
+
+		// when
+		requests := clustersInOrphanReport(handler.MapObject{Meta: report, Object: report})
+
+		// then
+		assert.ElementsMatch(t, []reconcile.Request{
+			{NamespacedName: namespacedName(test.HostOperatorNs, test.MemberClusterName)},
+			{NamespacedName: namespacedName(test.HostOperatorNs, "member2-cluster")},
+		}, requests)
+	})
+
+	t.Run("ignore the other ConfigMaps", func(t *testing.T) {
+		// given
+		cm := toolchainConfigMap(map[string]string{test.MemberClusterName: "john"})
+
+		// when
+		requests := clustersInOrphanReport(handler.MapObject{Meta: cm, Object: cm})
+
+		// then
+		assert.Empty(t, requests)
+	})
+}
+
+func prepareOrphanUserAccounts(t *testing.T, memberClient client.Client, ready corev1.ConditionStatus, initObjs ...runtime.Object) (*ReconcileOrphanUserAccounts, client.Client) {
+	hostClient := test.NewFakeClient(t, initObjs...)
+	r := &ReconcileOrphanUserAccounts{
+		client: hostClient,
+		retrieveMemberCluster: func(name string) (*cluster.FedCluster, bool) {
+			return &cluster.FedCluster{
+				Name:              name,
+				Client:            memberClient,
+				Type:              cluster.Member,
+				OperatorNamespace: test.MemberOperatorNs,
+				ClusterStatus: &v1beta1.KubeFedClusterStatus{
+					Conditions: []v1beta1.ClusterCondition{{
+						Type:   common.ClusterReady,
+						Status: ready,
+					}},
+				},
+			}, true
+		},
+	}
+	return r, hostClient
+}
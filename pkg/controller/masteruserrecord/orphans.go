@@ -0,0 +1,67 @@
+package masteruserrecord
+
+import (
+	"context"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+
+	"github.com/go-logr/logr"
+	errs "github.com/pkg/errors"
+	"sigs.k8s.io/kubefed/pkg/controller/util"
+)
+
+const (
+	// unableToDeleteOrphanUserAccountReason the reason of the Ready condition when a UserAccount which was removed
+	// from the spec of the MasterUserRecord could not be deleted in its member cluster
+	unableToDeleteOrphanUserAccountReason = "UnableToDeleteOrphanUserAccount"
+
+	// orphanRetryPeriod the delay before trying again to delete the UserAccounts in the member clusters which are not ready
+	orphanRetryPeriod = 30 * time.Second
+)
+
+// removeOrphanUserAccounts deletes the UserAccounts which were removed from the spec of the MasterUserRecord but
+// are still listed in its status, and removes them from the status once deleted.
+// The orphan UserAccounts in member clusters which are not ready are kept in the status, so that their deletion
+// can be retried later: returns true in this case. The orphan UserAccounts in member clusters which are no longer
+// in the registry are added to the orphan report and removed from the status, since they can't be deleted anymore.
+func (r *ReconcileMasterUserRecord) removeOrphanUserAccounts(logger logr.Logger, mur *toolchainv1alpha1.MasterUserRecord) (bool, error) {
+	remaining := []toolchainv1alpha1.UserAccountStatusEmbedded{}
+	unregistered := []string{}
+	pending := false
+	for _, status := range mur.Status.UserAccounts {
+		targetCluster := status.Cluster.Name
+		if _, index := findUserAccount(mur, targetCluster); index >= 0 {
+			remaining = append(remaining, status)
+			continue
+		}
+		memberCluster, ok := r.retrieveMemberCluster(targetCluster)
+		if !ok {
+			logger.Info("member cluster of the orphan UserAccount not found in the registry", "TargetCluster", targetCluster)
+			unregistered = append(unregistered, targetCluster)
+			continue
+		}
+		if !util.IsClusterReady(memberCluster.ClusterStatus) {
+			logger.Info("member cluster of the orphan UserAccount is not ready", "TargetCluster", targetCluster)
+			remaining = append(remaining, status)
+			pending = true
+			continue
+		}
+		if err := r.deleteUserAccount(targetCluster, mur.Name); err != nil {
+			return false, r.wrapErrorWithStatusUpdate(logger, mur, r.setStatusFailed(unableToDeleteOrphanUserAccountReason), err,
+				"failed to delete the orphan UserAccount in the member cluster '%s'", targetCluster)
+		}
+		logger.Info("deleted the orphan UserAccount", "TargetCluster", targetCluster)
+	}
+	if len(remaining) == len(mur.Status.UserAccounts) {
+		return pending, nil
+	}
+	if len(unregistered) > 0 {
+		if err := reportOrphanUserAccounts(r.client, mur.Namespace, mur.Name, unregistered); err != nil {
+			return false, errs.Wrap(err, "unable to report the orphan UserAccounts in the member clusters which are not in the registry")
+		}
+		logger.Info("reported the orphan UserAccounts in the member clusters which are not in the registry", "TargetClusters", unregistered)
+	}
+	mur.Status.UserAccounts = remaining
+	return pending, r.client.Status().Update(context.TODO(), mur)
+}
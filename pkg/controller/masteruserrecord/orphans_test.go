@@ -0,0 +1,154 @@
+package masteruserrecord
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	murtest "github.com/codeready-toolchain/toolchain-common/pkg/test/masteruserrecord"
+	uatest "github.com/codeready-toolchain/toolchain-common/pkg/test/useraccount"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestRemoveOrphanUserAccounts(t *testing.T) {
+	// given
+	logf.SetLogger(logf.ZapLogger(true))
+	s := apiScheme(t)
+
+	userAccountStatus := func(cluster string) toolchainv1alpha1.UserAccountStatusEmbedded {
+		return toolchainv1alpha1.UserAccountStatusEmbedded{
+			Cluster: toolchainv1alpha1.Cluster{
+				Name: cluster,
+			},
+			UserAccountStatus: toolchainv1alpha1.UserAccountStatus{
+				Conditions: []toolchainv1alpha1.Condition{toBeProvisioned()},
+			},
+		}
+	}
+
+	newMasterUserRecord := func() *toolchainv1alpha1.MasterUserRecord {
+		mur := murtest.NewMasterUserRecord("john", murtest.StatusCondition(toBeProvisioned()))
+		mur.Status.UserAccounts = []toolchainv1alpha1.UserAccountStatusEmbedded{
+			userAccountStatus(test.MemberClusterName),
+			userAccountStatus("member2-cluster"),
+		}
+		return mur
+	}
+
+	t.Run("delete the UserAccount removed from the spec", func(t *testing.T) {
+		// given
+		mur := newMasterUserRecord()
+		memberClient := test.NewFakeClient(t, uatest.NewUserAccountFromMur(mur, uatest.StatusCondition(toBeProvisioned())), consoleRoute())
+		memberClient2 := test.NewFakeClient(t, uatest.NewUserAccountFromMur(mur))
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, v1.ConditionTrue),
+			clusterClient(test.MemberClusterName, memberClient), clusterClient("member2-cluster", memberClient2))
+
+		// when
+		result, err := cntrl.Reconcile(newMurRequest(mur))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, result)
+		uatest.AssertThatUserAccount(t, "john", memberClient).Exists()
+		uatest.AssertThatUserAccount(t, "john", memberClient2).DoesNotExist()
+		assertUserAccountStatuses(t, hostClient, mur, test.MemberClusterName)
+		assertNoOrphanReport(t, hostClient)
+	})
+
+	t.Run("report and remove the status of the UserAccount in a cluster which is not in the registry", func(t *testing.T) {
+		// given
+		mur := newMasterUserRecord()
+		memberClient := test.NewFakeClient(t, uatest.NewUserAccountFromMur(mur, uatest.StatusCondition(toBeProvisioned())), consoleRoute())
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, v1.ConditionTrue),
+			clusterClient(test.MemberClusterName, memberClient))
+
+		// when
+		result, err := cntrl.Reconcile(newMurRequest(mur))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, result)
+		assertUserAccountStatuses(t, hostClient, mur, test.MemberClusterName)
+		assertOrphanReport(t, hostClient, map[string]string{"member2-cluster": "john"})
+	})
+
+	t.Run("keep the status of the UserAccount in a cluster which is not in the registry when it cannot be reported", func(t *testing.T) {
+		// given
+		mur := newMasterUserRecord()
+		hostClient := test.NewFakeClient(t, mur)
+		hostClient.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			return errors.New("unable to create")
+		}
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, v1.ConditionTrue),
+			clusterClient(test.MemberClusterName, test.NewFakeClient(t)))
+
+		// when
+		_, err := cntrl.removeOrphanUserAccounts(log, mur)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to report the orphan UserAccounts in the member clusters which are not in the registry")
+		assertUserAccountStatuses(t, hostClient, mur, test.MemberClusterName, "member2-cluster")
+	})
+
+	t.Run("keep the status of the UserAccount in a cluster which is not ready", func(t *testing.T) {
+		// given
+		mur := newMasterUserRecord()
+		memberClient2 := test.NewFakeClient(t, uatest.NewUserAccountFromMur(mur))
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, v1.ConditionFalse),
+			clusterClient("member2-cluster", memberClient2))
+
+		// when
+		pending, err := cntrl.removeOrphanUserAccounts(log, mur)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, pending)
+		uatest.AssertThatUserAccount(t, "john", memberClient2).Exists()
+		assertUserAccountStatuses(t, hostClient, mur, test.MemberClusterName, "member2-cluster")
+	})
+
+	t.Run("failed to delete the UserAccount", func(t *testing.T) {
+		// given
+		mur := newMasterUserRecord()
+		memberClient2 := test.NewFakeClient(t, uatest.NewUserAccountFromMur(mur))
+		memberClient2.MockDelete = func(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+			return errors.New("unable to delete")
+		}
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, v1.ConditionTrue),
+			clusterClient("member2-cluster", memberClient2))
+
+		// when
+		_, err := cntrl.removeOrphanUserAccounts(log, mur)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to delete the orphan UserAccount in the member cluster 'member2-cluster'")
+		uatest.AssertThatUserAccount(t, "john", memberClient2).Exists()
+		murtest.AssertThatMasterUserRecord(t, "john", hostClient).
+			HasConditions(toBeNotReady(unableToDeleteOrphanUserAccountReason, "unable to delete"))
+		assertUserAccountStatuses(t, hostClient, mur, test.MemberClusterName, "member2-cluster")
+	})
+}
+
+func assertUserAccountStatuses(t *testing.T, cl client.Client, mur *toolchainv1alpha1.MasterUserRecord, clusters ...string) {
+	actual := getMasterUserRecord(t, cl, mur)
+	names := []string{}
+	for _, status := range actual.Status.UserAccounts {
+		names = append(names, status.Cluster.Name)
+	}
+	assert.ElementsMatch(t, clusters, names)
+}
@@ -0,0 +1,125 @@
+package masteruserrecord
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/templates/nstemplatetiers"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// promoteToTierAnnotationKey the annotation to set on a MasterUserRecord to move all its UserAccounts to another NSTemplateTier
+	promoteToTierAnnotationKey = "toolchain.dev.openshift.com/promote-to-tier"
+	// previousTierAnnotationKey the annotation in which the tier used before the last promotion is recorded,
+	// so that the promotion can be rolled back by setting it in the `promote-to-tier` annotation
+	previousTierAnnotationKey = "toolchain.dev.openshift.com/previous-tier"
+
+	// tierPromoted the type of the condition which tracks the progress of the tier promotion
+	tierPromoted toolchainv1alpha1.ConditionType = "TierPromoted"
+
+	// Tier promotion condition reasons
+	promotingReason       = "Promoting"
+	promotedReason        = "Promoted"
+	unableToPromoteReason = "UnableToPromote"
+)
+
+// promoteTier moves all the UserAccounts of the given MasterUserRecord to the NSTemplateTier set in the
+// `toolchain.dev.openshift.com/promote-to-tier` annotation (if any), by rewriting their NSTemplateSet with the
+// namespaces and revisions of this tier. The member UserAccounts are then updated by the regular spec synchronization.
+// The annotation is removed once the promotion is completed (or if there is nothing to promote).
+func (r *ReconcileMasterUserRecord) promoteTier(logger logr.Logger, mur *toolchainv1alpha1.MasterUserRecord) error {
+	tierName := mur.Annotations[promoteToTierAnnotationKey]
+	if tierName == "" {
+		return nil
+	}
+	tier := &toolchainv1alpha1.NSTemplateTier{}
+	if err := r.client.Get(context.TODO(), namespacedName(mur.Namespace, tierName), tier); err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, mur, r.setTierPromotionFailed, err,
+			"unable to get the NSTemplateTier '%s'", tierName)
+	}
+	nsTemplateSet := nstemplatetiers.NewNSTemplateSet(*tier)
+	var previousTier string
+	updated := false
+	for i, ua := range mur.Spec.UserAccounts {
+		if reflect.DeepEqual(ua.Spec.NSTemplateSet, nsTemplateSet) {
+			continue
+		}
+		if ua.Spec.NSTemplateSet.TierName != tierName {
+			previousTier = ua.Spec.NSTemplateSet.TierName
+		}
+		mur.Spec.UserAccounts[i].Spec.NSTemplateSet = nsTemplateSet
+		updated = true
+	}
+	if !updated {
+		if isPromoting(mur) {
+			return nil
+		}
+		// the UserAccounts already are in the requested tier
+		return r.removePromotionRequest(mur)
+	}
+	if previousTier != "" {
+		mur.Annotations[previousTierAnnotationKey] = previousTier
+	}
+	if err := r.client.Update(context.TODO(), mur); err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, mur, r.setTierPromotionFailed, err,
+			"unable to promote the MasterUserRecord to the NSTemplateTier '%s'", tierName)
+	}
+	logger.Info("promoting MasterUserRecord", "PreviousTier", previousTier, "Tier", tierName)
+	return updateStatusConditions(r.client, mur, toolchainv1alpha1.Condition{
+		Type:    tierPromoted,
+		Status:  corev1.ConditionFalse,
+		Reason:  promotingReason,
+		Message: fmt.Sprintf("promoting to the '%s' tier", tierName),
+	})
+}
+
+// completeTierPromotion marks the ongoing tier promotion (if any) as completed once the MasterUserRecord is ready again,
+// and removes the `toolchain.dev.openshift.com/promote-to-tier` annotation so that the tier is not pinned by it anymore
+func (r *ReconcileMasterUserRecord) completeTierPromotion(mur *toolchainv1alpha1.MasterUserRecord) error {
+	if !isPromoting(mur) || !IsReady(mur.Status.Conditions) {
+		return nil
+	}
+	tierName := mur.Annotations[promoteToTierAnnotationKey]
+	if err := r.removePromotionRequest(mur); err != nil {
+		return err
+	}
+	return updateStatusConditions(r.client, mur, toolchainv1alpha1.Condition{
+		Type:    tierPromoted,
+		Status:  corev1.ConditionTrue,
+		Reason:  promotedReason,
+		Message: fmt.Sprintf("promoted to the '%s' tier", tierName),
+	})
+}
+
+// removePromotionRequest removes the `toolchain.dev.openshift.com/promote-to-tier` annotation from the MasterUserRecord
+func (r *ReconcileMasterUserRecord) removePromotionRequest(mur *toolchainv1alpha1.MasterUserRecord) error {
+	if _, found := mur.Annotations[promoteToTierAnnotationKey]; !found {
+		return nil
+	}
+	delete(mur.Annotations, promoteToTierAnnotationKey)
+	return r.client.Update(context.TODO(), mur)
+}
+
+// isPromoting returns true if the MasterUserRecord is being promoted to another tier
+func isPromoting(mur *toolchainv1alpha1.MasterUserRecord) bool {
+	for _, cond := range mur.Status.Conditions {
+		if cond.Type == tierPromoted && cond.Reason == promotingReason {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ReconcileMasterUserRecord) setTierPromotionFailed(mur *toolchainv1alpha1.MasterUserRecord, message string) error {
+	return updateStatusConditions(r.client, mur, toolchainv1alpha1.Condition{
+		Type:    tierPromoted,
+		Status:  corev1.ConditionFalse,
+		Reason:  unableToPromoteReason,
+		Message: message,
+	})
+}
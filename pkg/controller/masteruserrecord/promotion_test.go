@@ -0,0 +1,195 @@
+package masteruserrecord
+
+import (
+	"context"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/templates/nstemplatetiers"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	murtest "github.com/codeready-toolchain/toolchain-common/pkg/test/masteruserrecord"
+	uatest "github.com/codeready-toolchain/toolchain-common/pkg/test/useraccount"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestPromoteTier(t *testing.T) {
+	// given
+	logf.SetLogger(logf.ZapLogger(true))
+	s := apiScheme(t)
+	goldTier := &toolchainv1alpha1.NSTemplateTier{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "gold",
+			Namespace: test.HostOperatorNs,
+		},
+		Spec: toolchainv1alpha1.NSTemplateTierSpec{
+			Namespaces: []toolchainv1alpha1.NSTemplateTierNamespace{
+				{Type: "code", Revision: "abcdef1"},
+				{Type: "dev", Revision: "abcdef2"},
+			},
+		},
+	}
+
+	t.Run("promote all user accounts", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john", murtest.StatusCondition(toBeProvisioned()),
+			murtest.AdditionalAccounts("member2-cluster"))
+		previousTier := mur.Spec.UserAccounts[0].Spec.NSTemplateSet.TierName
+		mur.Annotations = map[string]string{promoteToTierAnnotationKey: "gold"}
+		userAccount := uatest.NewUserAccountFromMur(mur)
+		userAccount2 := uatest.NewUserAccountFromMur(mur)
+		memberClient := test.NewFakeClient(t, userAccount, consoleRoute())
+		memberClient2 := test.NewFakeClient(t, userAccount2, consoleRoute())
+		hostClient := test.NewFakeClient(t, mur, goldTier)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, v1.ConditionTrue),
+			clusterClient(test.MemberClusterName, memberClient), clusterClient("member2-cluster", memberClient2))
+
+		// when
+		_, err := cntrl.Reconcile(newMurRequest(mur))
+
+		// then
+		require.NoError(t, err)
+		expectedNSTemplateSet := toolchainv1alpha1.NSTemplateSetSpec{
+			TierName: "gold",
+			Namespaces: []toolchainv1alpha1.NSTemplateSetNamespace{
+				{Type: "code", Revision: "abcdef1"},
+				{Type: "dev", Revision: "abcdef2"},
+			},
+		}
+		promoted := &toolchainv1alpha1.MasterUserRecord{}
+		err = hostClient.Get(context.TODO(), namespacedName(mur.Namespace, mur.Name), promoted)
+		require.NoError(t, err)
+		require.Len(t, promoted.Spec.UserAccounts, 2)
+		for _, ua := range promoted.Spec.UserAccounts {
+			assert.Equal(t, expectedNSTemplateSet, ua.Spec.NSTemplateSet)
+		}
+		assert.Equal(t, previousTier, promoted.Annotations[previousTierAnnotationKey])
+		test.AssertConditionsMatch(t, promoted.Status.Conditions,
+			toBeNotReady(updatingReason, ""),
+			toolchainv1alpha1.Condition{
+				Type:    tierPromoted,
+				Status:  v1.ConditionFalse,
+				Reason:  promotingReason,
+				Message: "promoting to the 'gold' tier",
+			})
+		uatest.AssertThatUserAccount(t, "john", memberClient).
+			Exists().
+			HasSpec(promoted.Spec.UserAccounts[0].Spec)
+		uatest.AssertThatUserAccount(t, "john", memberClient2).
+			Exists().
+			HasSpec(promoted.Spec.UserAccounts[1].Spec)
+	})
+
+	t.Run("tier does not exist", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john", murtest.StatusCondition(toBeProvisioned()))
+		mur.Annotations = map[string]string{promoteToTierAnnotationKey: "platinum"}
+		userAccount := uatest.NewUserAccountFromMur(mur)
+		memberClient := test.NewFakeClient(t, userAccount, consoleRoute())
+		hostClient := test.NewFakeClient(t, mur, goldTier)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, v1.ConditionTrue),
+			clusterClient(test.MemberClusterName, memberClient))
+
+		// when
+		_, err := cntrl.Reconcile(newMurRequest(mur))
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to get the NSTemplateTier 'platinum'")
+		uatest.AssertThatUserAccount(t, "john", memberClient).
+			Exists().
+			HasSpec(mur.Spec.UserAccounts[0].Spec)
+		murtest.AssertThatMasterUserRecord(t, "john", hostClient).
+			HasConditions(toBeProvisioned(), toolchainv1alpha1.Condition{
+				Type:    tierPromoted,
+				Status:  v1.ConditionFalse,
+				Reason:  unableToPromoteReason,
+				Message: "nstemplatetiers.toolchain.dev.openshift.com \"platinum\" not found",
+			})
+	})
+
+	t.Run("complete promotion when ready", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john")
+		mur.Status.Conditions = []toolchainv1alpha1.Condition{
+			toBeProvisioned(),
+			{
+				Type:   tierPromoted,
+				Status: v1.ConditionFalse,
+				Reason: promotingReason,
+			},
+		}
+		mur.Annotations = map[string]string{promoteToTierAnnotationKey: "gold"}
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, v1.ConditionTrue))
+
+		// when
+		err := cntrl.completeTierPromotion(mur)
+
+		// then
+		require.NoError(t, err)
+		murtest.AssertThatMasterUserRecord(t, "john", hostClient).
+			HasConditions(toBeProvisioned(), toolchainv1alpha1.Condition{
+				Type:    tierPromoted,
+				Status:  v1.ConditionTrue,
+				Reason:  promotedReason,
+				Message: "promoted to the 'gold' tier",
+			})
+		completed := &toolchainv1alpha1.MasterUserRecord{}
+		err = hostClient.Get(context.TODO(), namespacedName(mur.Namespace, mur.Name), completed)
+		require.NoError(t, err)
+		assert.NotContains(t, completed.Annotations, promoteToTierAnnotationKey)
+	})
+
+	t.Run("remove promotion request when already in the requested tier", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john", murtest.StatusCondition(toBeProvisioned()))
+		for i := range mur.Spec.UserAccounts {
+			mur.Spec.UserAccounts[i].Spec.NSTemplateSet = nstemplatetiers.NewNSTemplateSet(*goldTier)
+		}
+		mur.Annotations = map[string]string{promoteToTierAnnotationKey: "gold"}
+		hostClient := test.NewFakeClient(t, mur, goldTier)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, v1.ConditionTrue))
+
+		// when
+		err := cntrl.promoteTier(logf.Log, mur)
+
+		// then
+		require.NoError(t, err)
+		actual := &toolchainv1alpha1.MasterUserRecord{}
+		err = hostClient.Get(context.TODO(), namespacedName(mur.Namespace, mur.Name), actual)
+		require.NoError(t, err)
+		assert.NotContains(t, actual.Annotations, promoteToTierAnnotationKey)
+		murtest.AssertThatMasterUserRecord(t, "john", hostClient).HasConditions(toBeProvisioned())
+	})
+
+	t.Run("do not complete promotion when not ready", func(t *testing.T) {
+		// given
+		promoting := toolchainv1alpha1.Condition{
+			Type:   tierPromoted,
+			Status: v1.ConditionFalse,
+			Reason: promotingReason,
+		}
+		mur := murtest.NewMasterUserRecord("john")
+		mur.Status.Conditions = []toolchainv1alpha1.Condition{toBeNotReady(updatingReason, ""), promoting}
+		mur.Annotations = map[string]string{promoteToTierAnnotationKey: "gold"}
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, v1.ConditionTrue))
+
+		// when
+		err := cntrl.completeTierPromotion(mur)
+
+		// then
+		require.NoError(t, err)
+		murtest.AssertThatMasterUserRecord(t, "john", hostClient).
+			HasConditions(toBeNotReady(updatingReason, ""), promoting)
+		actual := &toolchainv1alpha1.MasterUserRecord{}
+		err = hostClient.Get(context.TODO(), namespacedName(mur.Namespace, mur.Name), actual)
+		require.NoError(t, err)
+		assert.Equal(t, "gold", actual.Annotations[promoteToTierAnnotationKey])
+	})
+}
@@ -0,0 +1,264 @@
+package masteruserrecord
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+
+	"github.com/go-logr/logr"
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// partiallyProvisionedReason the reason of the Ready condition when the UserAccounts could be provisioned
+	// in some of the member clusters only
+	partiallyProvisionedReason = "PartiallyProvisioned"
+
+	// maxConcurrentProvisionings the maximum number of member clusters in which the UserAccounts of a MasterUserRecord
+	// are provisioned at the same time
+	maxConcurrentProvisionings = 5
+)
+
+// provisioningFailureReasons the reasons of the Ready condition of a UserAccount status when its provisioning failed
+var provisioningFailureReasons = []string{
+	targetClusterNotReadyReason,
+	unableToGetUserAccountReason,
+	unableToCreateUserAccountReason,
+	unableToSynchronizeUserAccountSpecReason,
+}
+
+// userAccountProvisioning the outcome of the provisioning of a UserAccount in a member cluster
+type userAccountProvisioning struct {
+	account       toolchainv1alpha1.UserAccountEmbedded
+	memberCluster *cluster.FedCluster
+	userAccount   *toolchainv1alpha1.UserAccount
+	// created true if the UserAccount did not exist in the member cluster and was created
+	created bool
+	// drift the differences of the UserAccount spec which was modified in the member cluster, if any
+	drift []string
+	// reason the reason of the failure, or an empty string if the failure is not specific to the member cluster
+	reason string
+	// message the message of the failure, to be set in the Ready condition
+	message string
+	err     error
+}
+
+// failed returns the provisioning with the given failure
+func (p userAccountProvisioning) failed(reason string, cause, err error) userAccountProvisioning {
+	p.reason = reason
+	p.message = cause.Error()
+	p.err = err
+	return p
+}
+
+// ensureUserAccounts creates or synchronizes the UserAccounts in all the member clusters of the MasterUserRecord.
+// The member clusters are contacted concurrently, and a failure in one of them does not prevent the UserAccounts
+// from being provisioned in the other ones: the failure is recorded in the status of the UserAccount, and the Ready
+// condition of the MasterUserRecord reports a partial provisioning.
+// If all the failures are due to member clusters which are not ready, then the MasterUserRecord is requeued (with a
+// delay depending on how long the clusters have been unavailable) without any error.
+// Returns the errors which occurred in the member clusters, if any.
+func (r *ReconcileMasterUserRecord) ensureUserAccounts(logger logr.Logger, mur *toolchainv1alpha1.MasterUserRecord) (reconcile.Result, error) {
+	cfg, err := r.configLoader.Load(mur.Namespace)
+	if err != nil {
+		return reconcile.Result{}, errs.Wrap(err, "unable to load the toolchain configuration")
+	}
+	repairDrift, err := cfg.DriftAutoRepair()
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	failures := []userAccountProvisioning{}
+	driftsPerCluster := map[string][]string{}
+	created := false
+	for _, p := range r.provisionUserAccounts(mur) {
+		if p.err == nil && p.created {
+			forgetFailure(mur, p.account.TargetCluster)
+			r.drifts.set(p.account.TargetCluster, mur.Name, false)
+			created = true
+			continue
+		}
+		if p.err == nil {
+			p = r.synchronizeUserAccount(logger, p, mur, repairDrift)
+		}
+		if p.err != nil {
+			failures = append(failures, p)
+			continue
+		}
+		drifted := len(p.drift) > 0 && !repairDrift
+		if drifted {
+			driftsPerCluster[p.account.TargetCluster] = p.drift
+		} else if len(p.drift) > 0 {
+			repairedDrifts.WithLabelValues(p.account.TargetCluster).Inc()
+		}
+		r.drifts.set(p.account.TargetCluster, mur.Name, drifted)
+	}
+	if err := updateDriftedCondition(r.client, mur, driftsPerCluster); err != nil {
+		return reconcile.Result{}, errs.Wrap(err, "unable to update the Drifted condition of the MasterUserRecord")
+	}
+	if len(failures) > 0 {
+		err := r.reportFailures(logger, mur, failures)
+		if delay, ok := notReadyRequeueDelay(failures); ok {
+			logger.Info("member clusters not ready", "RequeueAfter", delay)
+			return reconcile.Result{RequeueAfter: delay}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if created {
+		return reconcile.Result{}, updateStatusConditions(r.client, mur, toBeNotReady(provisioningReason, ""))
+	}
+	return reconcile.Result{}, nil
+}
+
+// provisionUserAccounts gets the UserAccounts of the MasterUserRecord in their member clusters, and creates the missing
+// ones. No more than `maxConcurrentProvisionings` member clusters are contacted at the same time.
+// Returns the outcome of the provisioning for each UserAccount, in the order of the MasterUserRecord spec.
+func (r *ReconcileMasterUserRecord) provisionUserAccounts(mur *toolchainv1alpha1.MasterUserRecord) []userAccountProvisioning {
+	provisionings := make([]userAccountProvisioning, len(mur.Spec.UserAccounts))
+	semaphore := make(chan struct{}, maxConcurrentProvisionings)
+	var wg sync.WaitGroup
+	for i, account := range mur.Spec.UserAccounts {
+		wg.Add(1)
+		go func(i int, account toolchainv1alpha1.UserAccountEmbedded) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			provisionings[i] = r.provisionUserAccount(account, mur.Name)
+		}(i, account)
+	}
+	wg.Wait()
+	return provisionings
+}
+
+// provisionUserAccount gets the UserAccount with the given name in the member cluster, or creates it if it does not exist
+func (r *ReconcileMasterUserRecord) provisionUserAccount(account toolchainv1alpha1.UserAccountEmbedded, name string) userAccountProvisioning {
+	p := userAccountProvisioning{account: account}
+	// get & check member cluster
+	memberCluster, err := r.getMemberCluster(account.TargetCluster)
+	if err != nil {
+		return p.failed(targetClusterNotReadyReason, err,
+			errs.Wrapf(err, "failed to get the member cluster '%s'", account.TargetCluster))
+	}
+	p.memberCluster = memberCluster
+
+	// get UserAccount from member
+	nsdName := namespacedName(memberCluster.OperatorNamespace, name)
+	userAccount := &toolchainv1alpha1.UserAccount{}
+	if err := memberCluster.Client.Get(context.TODO(), nsdName, userAccount); err != nil {
+		if !errors.IsNotFound(err) {
+			return p.failed(unableToGetUserAccountReason, err,
+				errs.Wrapf(err, "failed to get userAccount '%s' from cluster '%s'", name, account.TargetCluster))
+		}
+		// does not exist - should create
+		userAccount = newUserAccount(nsdName, account.Spec)
+		if err := memberCluster.Client.Create(context.TODO(), userAccount); err != nil {
+			return p.failed(unableToCreateUserAccountReason, err,
+				errs.Wrapf(err, "failed to create UserAccount in the member cluster '%s'", account.TargetCluster))
+		}
+		p.created = true
+	}
+	p.userAccount = userAccount
+	return p
+}
+
+// synchronizeUserAccount synchronizes the spec of the UserAccount with the one in the MasterUserRecord, and the
+// status of the MasterUserRecord with the one of the UserAccount
+func (r *ReconcileMasterUserRecord) synchronizeUserAccount(logger logr.Logger, p userAccountProvisioning, mur *toolchainv1alpha1.MasterUserRecord, repairDrift bool) userAccountProvisioning {
+	// the status recorded after a previous failure is replaced with the one of the UserAccount
+	forgetFailure(mur, p.account.TargetCluster)
+	synchronizer := Synchronizer{
+		record:            mur,
+		hostClient:        r.client,
+		memberCluster:     p.memberCluster,
+		memberUserAcc:     p.userAccount,
+		recordSpecUserAcc: p.account,
+		log:               logger,
+		consoleURLs:       r.consoleURLs,
+		repairDrift:       repairDrift,
+	}
+	if err := synchronizer.synchronizeSpec(); err != nil {
+		return p.failed(unableToSynchronizeUserAccountSpecReason, err,
+			errs.Wrapf(err, "update of the UserAccount.spec in the cluster '%s' failed", p.account.TargetCluster))
+	}
+	p.drift = synchronizer.drift
+	if err := synchronizer.synchronizeStatus(); err != nil {
+		err = errs.Wrapf(err, "update of the MasterUserRecord failed while synchronizing with UserAccount status from the cluster '%s'", p.account.TargetCluster)
+		return p.failed("", err, err)
+	}
+	return p
+}
+
+// reportFailures records the failures in the statuses of the UserAccounts and in the Ready condition of the MasterUserRecord,
+// which reports a partial provisioning if the UserAccounts were provisioned in some member clusters.
+// Returns the errors of the failures.
+func (r *ReconcileMasterUserRecord) reportFailures(logger logr.Logger, mur *toolchainv1alpha1.MasterUserRecord, failures []userAccountProvisioning) error {
+	messages := make([]string, len(failures))
+	failureErrs := make([]error, len(failures))
+	for i, f := range failures {
+		if f.reason != "" {
+			setFailure(mur, f)
+		}
+		messages[i] = fmt.Sprintf("'%s' (%s)", f.account.TargetCluster, f.message)
+		failureErrs[i] = f.err
+	}
+	ready := toBeNotReady(failures[0].reason, failures[0].message)
+	if len(failures) < len(mur.Spec.UserAccounts) {
+		ready = toBeNotReady(partiallyProvisionedReason, fmt.Sprintf("provisioned in %d out of %d member clusters, failed in %s",
+			len(mur.Spec.UserAccounts)-len(failures), len(mur.Spec.UserAccounts), strings.Join(messages, ", ")))
+	} else if len(failures) > 1 {
+		ready.Message = fmt.Sprintf("failed in %s", strings.Join(messages, ", "))
+	}
+	if ready.Reason == "" {
+		// keep the reason of the current Ready condition
+		for _, cond := range mur.Status.Conditions {
+			if cond.Type == toolchainv1alpha1.ConditionReady {
+				ready.Reason = cond.Reason
+			}
+		}
+	}
+	mur.Status.Conditions, _ = condition.AddOrUpdateStatusConditions(mur.Status.Conditions, ready)
+	if err := r.client.Status().Update(context.TODO(), mur); err != nil {
+		logger.Error(err, "status update failed")
+	}
+	if len(failureErrs) == 1 {
+		return failureErrs[0]
+	}
+	return utilerrors.NewAggregate(failureErrs)
+}
+
+// setFailure sets the failure in the Ready condition of the status of the UserAccount
+func setFailure(mur *toolchainv1alpha1.MasterUserRecord, f userAccountProvisioning) {
+	status, index := getUserAccountStatus(f.account.TargetCluster, mur)
+	status.Conditions, _ = condition.AddOrUpdateStatusConditions(status.Conditions, toBeNotReady(f.reason, f.message))
+	if index < 0 {
+		mur.Status.UserAccounts = append(mur.Status.UserAccounts, status)
+	} else {
+		mur.Status.UserAccounts[index] = status
+	}
+}
+
+// forgetFailure removes the status of the UserAccount in the given cluster if it was set after a failure
+func forgetFailure(mur *toolchainv1alpha1.MasterUserRecord, targetCluster string) {
+	status, index := getUserAccountStatus(targetCluster, mur)
+	if index < 0 {
+		return
+	}
+	for _, cond := range status.Conditions {
+		if cond.Type != toolchainv1alpha1.ConditionReady {
+			continue
+		}
+		for _, reason := range provisioningFailureReasons {
+			if cond.Reason == reason {
+				mur.Status.UserAccounts = append(mur.Status.UserAccounts[:index], mur.Status.UserAccounts[index+1:]...)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package masteruserrecord
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	murtest "github.com/codeready-toolchain/toolchain-common/pkg/test/masteruserrecord"
+	uatest "github.com/codeready-toolchain/toolchain-common/pkg/test/useraccount"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestEnsureUserAccountsWithFailures(t *testing.T) {
+	// given
+	logf.SetLogger(logf.ZapLogger(true))
+	s := apiScheme(t)
+
+	t.Run("provision the UserAccounts in the other clusters when one of them is not in the registry", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john", murtest.AdditionalAccounts("member2-cluster"))
+		memberClient := test.NewFakeClient(t)
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, v1.ConditionTrue),
+			clusterClient(test.MemberClusterName, memberClient))
+
+		// when
+		_, err := cntrl.Reconcile(newMurRequest(mur))
+
+		// then
+		require.Error(t, err)
+		msg := "the member cluster member2-cluster not found in the registry"
+		assert.Contains(t, err.Error(), msg)
+		uatest.AssertThatUserAccount(t, "john", memberClient).Exists()
+		murtest.AssertThatMasterUserRecord(t, "john", hostClient).
+			HasConditions(toBeNotReady(partiallyProvisionedReason,
+				fmt.Sprintf("provisioned in 1 out of 2 member clusters, failed in 'member2-cluster' (%s)", msg)))
+		assertUserAccountStatusConditions(t, hostClient, mur, "member2-cluster", toBeNotReady(targetClusterNotReadyReason, msg))
+	})
+
+	t.Run("synchronize the UserAccounts in the other clusters when one of them cannot be updated", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john", murtest.AdditionalAccounts("member2-cluster"))
+		memberClient := test.NewFakeClient(t, uatest.NewUserAccountFromMur(mur, uatest.StatusCondition(toBeProvisioned())), consoleRoute())
+		memberClient2 := test.NewFakeClient(t, uatest.NewUserAccountFromMur(mur))
+		memberClient2.MockUpdate = func(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+			return fmt.Errorf("unable to update user account %s", mur.Name)
+		}
+		murtest.ModifyUaInMur(mur, "member2-cluster", murtest.TierName("admin"))
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, v1.ConditionTrue),
+			clusterClient(test.MemberClusterName, memberClient), clusterClient("member2-cluster", memberClient2))
+
+		// when
+		_, err := cntrl.Reconcile(newMurRequest(mur))
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "update of the UserAccount.spec in the cluster 'member2-cluster' failed")
+		murtest.AssertThatMasterUserRecord(t, "john", hostClient).
+			HasConditions(toBeNotReady(partiallyProvisionedReason,
+				"provisioned in 1 out of 2 member clusters, failed in 'member2-cluster' (unable to update user account john)"))
+		assertUserAccountStatusConditions(t, hostClient, mur, test.MemberClusterName, toBeProvisioned())
+		assertUserAccountStatusConditions(t, hostClient, mur, "member2-cluster",
+			toBeNotReady(unableToSynchronizeUserAccountSpecReason, "unable to update user account john"))
+	})
+
+	t.Run("all clusters failed", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john", murtest.AdditionalAccounts("member2-cluster"))
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(false, v1.ConditionTrue))
+
+		// when
+		_, err := cntrl.Reconcile(newMurRequest(mur))
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to get the member cluster 'member-cluster'")
+		assert.Contains(t, err.Error(), "failed to get the member cluster 'member2-cluster'")
+		murtest.AssertThatMasterUserRecord(t, "john", hostClient).
+			HasConditions(toBeNotReady(targetClusterNotReadyReason,
+				"failed in 'member-cluster' (the member cluster member-cluster not found in the registry), "+
+					"'member2-cluster' (the member cluster member2-cluster not found in the registry)"))
+	})
+
+	t.Run("replace the status recorded after a failure once the UserAccount is provisioned", func(t *testing.T) {
+		// given
+		mur := murtest.NewMasterUserRecord("john")
+		mur.Status.UserAccounts = []toolchainv1alpha1.UserAccountStatusEmbedded{{
+			Cluster: toolchainv1alpha1.Cluster{
+				Name: test.MemberClusterName,
+			},
+			UserAccountStatus: toolchainv1alpha1.UserAccountStatus{
+				Conditions: []toolchainv1alpha1.Condition{toBeNotReady(targetClusterNotReadyReason, "not ready")},
+			},
+		}}
+		memberClient := test.NewFakeClient(t, uatest.NewUserAccountFromMur(mur, uatest.StatusCondition(toBeProvisioned())), consoleRoute())
+		hostClient := test.NewFakeClient(t, mur)
+		cntrl := newController(hostClient, s, newGetMemberCluster(true, v1.ConditionTrue),
+			clusterClient(test.MemberClusterName, memberClient))
+
+		// when
+		_, err := cntrl.Reconcile(newMurRequest(mur))
+
+		// then
+		require.NoError(t, err)
+		murtest.AssertThatMasterUserRecord(t, "john", hostClient).
+			HasConditions(toBeProvisioned())
+		assertUserAccountStatusConditions(t, hostClient, mur, test.MemberClusterName, toBeProvisioned())
+	})
+}
+
+func assertUserAccountStatusConditions(t *testing.T, cl client.Client, mur *toolchainv1alpha1.MasterUserRecord, targetCluster string, expected ...toolchainv1alpha1.Condition) {
+	actual := getMasterUserRecord(t, cl, mur)
+	status, index := getUserAccountStatus(targetCluster, actual)
+	require.True(t, index >= 0, "no status for the UserAccount in the cluster '%s'", targetCluster)
+	test.AssertConditionsMatch(t, status.Conditions, expected...)
+}
@@ -0,0 +1,122 @@
+package masteruserrecord
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/kubefed/pkg/apis/core/common"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1/defaults"
+)
+
+// clusterNotFoundError the error returned when a member cluster is not in the registry
+type clusterNotFoundError struct {
+	name string
+}
+
+func (e clusterNotFoundError) Error() string {
+	return fmt.Sprintf("the member cluster %s not found in the registry", e.name)
+}
+
+// isUnreachable returns true if the given error is due to a member cluster which is not in the registry or not ready
+func isUnreachable(err error) bool {
+	switch errs.Cause(err).(type) {
+	case clusterNotFoundError, clusterNotReadyError:
+		return true
+	default:
+		return false
+	}
+}
+
+// clusterNotReadyError the error returned when a member cluster is not ready
+type clusterNotReadyError struct {
+	name string
+	// unavailableFor how long the member cluster has been unavailable (0 if unknown)
+	unavailableFor time.Duration
+}
+
+func (e clusterNotReadyError) Error() string {
+	if e.unavailableFor <= 0 {
+		return fmt.Sprintf("the member cluster %s is not ready", e.name)
+	}
+	return fmt.Sprintf("the member cluster %s is not ready (unavailable for %s)", e.name, e.unavailableFor.Round(time.Second))
+}
+
+// requeueDelay returns the delay before reconciling again a MasterUserRecord which has a UserAccount in the member
+// cluster: the longer the cluster has been unavailable, the longer the delay, from the health-check period of the
+// KubeFedClusters to the delay before checking again an unavailable cluster. The MasterUserRecord is reconciled
+// anyway as soon as the member cluster becomes ready.
+func (e clusterNotReadyError) requeueDelay() time.Duration {
+	if e.unavailableFor < defaults.DefaultClusterHealthCheckPeriod {
+		return defaults.DefaultClusterHealthCheckPeriod
+	}
+	if e.unavailableFor > defaults.DefaultClusterUnavailableDelay {
+		return defaults.DefaultClusterUnavailableDelay
+	}
+	return e.unavailableFor
+}
+
+// unavailableFor returns how long the member cluster with the given status has been unavailable,
+// or 0 if its Ready condition has no transition time
+func unavailableFor(status *v1beta1.KubeFedClusterStatus) time.Duration {
+	if status == nil {
+		return 0
+	}
+	for _, cond := range status.Conditions {
+		if cond.Type == common.ClusterReady && cond.Status != corev1.ConditionTrue && cond.LastTransitionTime != nil {
+			return time.Since(cond.LastTransitionTime.Time)
+		}
+	}
+	return 0
+}
+
+// notReadyRequeueDelay returns the shortest delay before reconciling the MasterUserRecord again, if all the given
+// failures are due to member clusters which are not ready. Returns false otherwise.
+func notReadyRequeueDelay(failures []userAccountProvisioning) (time.Duration, bool) {
+	var delay time.Duration
+	for _, f := range failures {
+		notReady, ok := errs.Cause(f.err).(clusterNotReadyError)
+		if !ok {
+			return 0, false
+		}
+		if d := notReady.requeueDelay(); delay == 0 || d < delay {
+			delay = d
+		}
+	}
+	return delay, delay > 0
+}
+
+// requeueAfter returns the given result, requeued after the given delay unless it is already requeued sooner
+func requeueAfter(result reconcile.Result, delay time.Duration) reconcile.Result {
+	if delay > 0 && (result.RequeueAfter == 0 || result.RequeueAfter > delay) {
+		result.RequeueAfter = delay
+	}
+	return result
+}
+
+// masterUserRecordsInCluster returns a mapper which returns the requests to reconcile the MasterUserRecords which have
+// a UserAccount in the member cluster of the KubeFedCluster
+func masterUserRecordsInCluster(cl client.Client) handler.ToRequestsFunc {
+	return func(obj handler.MapObject) []reconcile.Request {
+		murs := &toolchainv1alpha1.MasterUserRecordList{}
+		if err := cl.List(context.TODO(), murs, client.InNamespace(obj.Meta.GetNamespace())); err != nil {
+			log.Error(err, "unable to list the MasterUserRecords", "TargetCluster", obj.Meta.GetName())
+			return nil
+		}
+		requests := []reconcile.Request{}
+		for _, mur := range murs.Items {
+			if _, index := findUserAccount(&mur, obj.Meta.GetName()); index >= 0 {
+				requests = append(requests, reconcile.Request{NamespacedName: namespacedName(mur.Namespace, mur.Name)})
+			}
+		}
+		return requests
+	}
+}
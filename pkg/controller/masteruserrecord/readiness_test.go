@@ -0,0 +1,111 @@
+package masteruserrecord
+
+import (
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	murtest "github.com/codeready-toolchain/toolchain-common/pkg/test/masteruserrecord"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/kubefed/pkg/apis/core/common"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1/defaults"
+)
+
+func TestClusterNotReadyError(t *testing.T) {
+
+	t.Run("unavailability unknown", func(t *testing.T) {
+		// given
+		err := clusterNotReadyError{name: "member-cluster"}
+
+		// then
+		assert.Equal(t, "the member cluster member-cluster is not ready", err.Error())
+		assert.Equal(t, defaults.DefaultClusterHealthCheckPeriod, err.requeueDelay())
+	})
+
+	t.Run("unavailable for a while", func(t *testing.T) {
+		// given
+		err := clusterNotReadyError{name: "member-cluster", unavailableFor: 30*time.Second + 200*time.Millisecond}
+
+		// then
+		assert.Equal(t, "the member cluster member-cluster is not ready (unavailable for 30s)", err.Error())
+		assert.Equal(t, 30*time.Second+200*time.Millisecond, err.requeueDelay())
+	})
+
+	t.Run("unavailable for a long time", func(t *testing.T) {
+		// given
+		err := clusterNotReadyError{name: "member-cluster", unavailableFor: 2 * time.Hour}
+
+		// then
+		assert.Equal(t, "the member cluster member-cluster is not ready (unavailable for 2h0m0s)", err.Error())
+		assert.Equal(t, defaults.DefaultClusterUnavailableDelay, err.requeueDelay())
+	})
+}
+
+func TestReconcileWhenClusterNotReady(t *testing.T) {
+	// given
+	logf.SetLogger(logf.ZapLogger(true))
+	s := apiScheme(t)
+	mur := murtest.NewMasterUserRecord("john")
+	hostClient := test.NewFakeClient(t, mur)
+	since := metav1.NewTime(time.Now().Add(-40 * time.Second))
+	cntrl := newController(hostClient, s, func(clusters ...clientForCluster) func(name string) (*cluster.FedCluster, bool) {
+		return func(name string) (*cluster.FedCluster, bool) {
+			return &cluster.FedCluster{
+				Client:            test.NewFakeClient(t),
+				Type:              cluster.Member,
+				OperatorNamespace: test.MemberOperatorNs,
+				OwnerClusterName:  test.HostClusterName,
+				ClusterStatus: &v1beta1.KubeFedClusterStatus{
+					Conditions: []v1beta1.ClusterCondition{{
+						Type:               common.ClusterReady,
+						Status:             v1.ConditionFalse,
+						LastTransitionTime: &since,
+					}},
+				},
+			}, true
+		}
+	})
+
+	// when
+	result, err := cntrl.Reconcile(newMurRequest(mur))
+
+	// then
+	require.NoError(t, err)
+	assert.True(t, result.RequeueAfter >= 40*time.Second)
+	assert.True(t, result.RequeueAfter <= defaults.DefaultClusterUnavailableDelay)
+	actual := getMasterUserRecord(t, hostClient, mur)
+	require.Len(t, actual.Status.Conditions, 1)
+	assert.Equal(t, targetClusterNotReadyReason, actual.Status.Conditions[0].Reason)
+	assert.Contains(t, actual.Status.Conditions[0].Message, "the member cluster member-cluster is not ready (unavailable for 40s)")
+}
+
+func TestMasterUserRecordsInCluster(t *testing.T) {
+	// given
+	apiScheme(t)
+	john := murtest.NewMasterUserRecord("john")
+	jane := murtest.NewMasterUserRecord("jane", murtest.AdditionalAccounts("member2-cluster"))
+	jack := murtest.NewMasterUserRecord("jack")
+	jack.Spec.UserAccounts[0].TargetCluster = "member2-cluster"
+	mapper := masterUserRecordsInCluster(test.NewFakeClient(t, john, jane, jack))
+	kubeFedCluster := &v1beta1.KubeFedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "member2-cluster",
+			Namespace: test.HostOperatorNs,
+		},
+	}
+
+	// when
+	requests := mapper(handler.MapObject{Meta: kubeFedCluster, Object: kubeFedCluster})
+
+	// then
+	assert.ElementsMatch(t, []reconcile.Request{newMurRequest(jane), newMurRequest(jack)}, requests)
+}
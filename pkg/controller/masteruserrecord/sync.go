@@ -2,34 +2,18 @@ package masteruserrecord
 
 import (
 	"context"
-	"crypto/tls"
-	"fmt"
-	"io/ioutil"
-	"net/http"
 	"reflect"
-	"time"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/console"
 	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
 	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
 
 	"github.com/go-logr/logr"
-	routev1 "github.com/openshift/api/route/v1"
-	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
-	kuberrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-// consoleClient to be used to test connection to a public Web Console
-var consoleClient = &http.Client{
-	Timeout: time.Duration(1 * time.Second),
-	Transport: &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	},
-}
-
 type Synchronizer struct {
 	hostClient        client.Client
 	memberCluster     *cluster.FedCluster
@@ -37,12 +21,26 @@ type Synchronizer struct {
 	recordSpecUserAcc toolchainv1alpha1.UserAccountEmbedded
 	record            *toolchainv1alpha1.MasterUserRecord
 	log               logr.Logger
+	consoleURLs       *console.Discovery
+	// repairDrift true if the UserAccount spec must be reverted when it was modified in the member cluster
+	repairDrift bool
+	// drift the differences of the UserAccount spec which was modified in the member cluster, if any
+	drift []string
 }
 
 func (s *Synchronizer) synchronizeSpec() error {
 	if !reflect.DeepEqual(s.memberUserAcc.Spec, s.recordSpecUserAcc.Spec) {
+		if hasDrifted(s.memberUserAcc, s.recordSpecUserAcc.Spec) {
+			// when UserAccount spec in member was modified since it was synchronized with the record
+			s.drift = specDiff(s.memberUserAcc.Spec, s.recordSpecUserAcc.Spec)
+			s.log.Info("UserAccount modified in the member cluster", "TargetCluster", s.recordSpecUserAcc.TargetCluster, "Drift", s.drift)
+			if !s.repairDrift {
+				return nil
+			}
+		}
 		// when UserAccount spec in record is updated - is not same as in member
 		s.memberUserAcc.Spec = s.recordSpecUserAcc.Spec
+		markSynchronized(s.memberUserAcc, s.recordSpecUserAcc.Spec)
 		if err := updateStatusConditions(s.hostClient, s.record, toBeNotReady(updatingReason, "")); err != nil {
 			return err
 		}
@@ -56,7 +54,8 @@ func (s *Synchronizer) synchronizeSpec() error {
 
 func (s *Synchronizer) synchronizeStatus() error {
 	recordStatusUserAcc, index := getUserAccountStatus(s.recordSpecUserAcc.TargetCluster, s.record)
-	if index < 0 || s.recordSpecUserAcc.SyncIndex != recordStatusUserAcc.SyncIndex {
+	if index < 0 || s.recordSpecUserAcc.SyncIndex != recordStatusUserAcc.SyncIndex ||
+		!reflect.DeepEqual(s.memberUserAcc.Status, recordStatusUserAcc.UserAccountStatus) || s.hasOutdatedConsoleURL(recordStatusUserAcc) {
 		// when record should update status
 		recordStatusUserAcc.SyncIndex = s.recordSpecUserAcc.SyncIndex
 		recordStatusUserAcc.UserAccountStatus = s.memberUserAcc.Status
@@ -88,65 +87,50 @@ func (s *Synchronizer) synchronizeStatus() error {
 }
 
 // withClusterDetails returns the given user account status with additional information about
-// the target cluster such as API endpoint and Console URL if not set yet
+// the target cluster such as API endpoint and Console URL if not set yet (or if the Console URL changed)
 func (s *Synchronizer) withClusterDetails(status toolchainv1alpha1.UserAccountStatusEmbedded) (toolchainv1alpha1.UserAccountStatusEmbedded, error) {
-	if status.Cluster.Name != "" && (status.Cluster.APIEndpoint == "" || status.Cluster.ConsoleURL == "") {
+	if status.Cluster.Name != "" && (status.Cluster.APIEndpoint == "" || status.Cluster.ConsoleURL == "" || s.hasOutdatedConsoleURL(status)) {
 		status.Cluster.APIEndpoint = s.memberCluster.APIEndpoint
-		route := &routev1.Route{}
-		namespacedName := types.NamespacedName{Namespace: "openshift-console", Name: "console"}
-		err := s.memberCluster.Client.Get(context.TODO(), namespacedName, route)
+		consoleURL, err := s.consoleURLs.URL(s.record.Namespace, s.memberCluster)
 		if err != nil {
-			s.log.Error(err, "unable to get console route")
-			if kuberrors.IsNotFound(err) {
-				// It can happen if running in old OpenShift version like 3.x (minishift) in dev environment
-				// TODO do this only if run in dev environment
-				consoleURL, consoleErr := s.openShift3XConsoleURL(s.memberCluster.APIEndpoint)
-				if consoleErr != nil {
-					// Log the openShift3XConsoleURL() error but return the original missing route error
-					s.log.Error(err, "OpenShit 3.x web console unreachable", "url", consoleURL)
-					return status, errors.Wrapf(err, "unable to get web console route for cluster %s", s.memberCluster.Name)
-				}
-				s.log.Info("OpenShit 3.x web console URL used", "url", consoleURL)
-				status.Cluster.ConsoleURL = consoleURL
-				return status, nil
-			}
+			s.log.Error(err, "unable to get the web console URL")
 			return status, err
 		}
-
-		status.Cluster.ConsoleURL = fmt.Sprintf("https://%s/%s", route.Spec.Host, route.Spec.Path)
+		status.Cluster.ConsoleURL = consoleURL
 	}
 	return status, nil
 }
 
-// openShift3XConsoleURL checks if <apiEndpoint>/console URL is reachable.
-// This URL is used by web console in OpenShift 3.x
-func (s *Synchronizer) openShift3XConsoleURL(apiEndpoint string) (string, error) {
-	url := fmt.Sprintf("%s/console", apiEndpoint)
-	resp, err := consoleClient.Get(url)
-	if err != nil {
-		return url, err
+// hasOutdatedConsoleURL returns true if the Console URL in the given user account status differs from the one of the
+// target cluster, which is discovered again if it was forgotten (ie, when the KubeFedCluster changed). If the discovery
+// fails, then the Console URL in the status is kept.
+func (s *Synchronizer) hasOutdatedConsoleURL(status toolchainv1alpha1.UserAccountStatusEmbedded) bool {
+	if status.Cluster.ConsoleURL == "" {
+		return false
 	}
-	defer func() {
-		_, _ = ioutil.ReadAll(resp.Body)
-		_ = resp.Body.Close()
-	}()
-	if resp.StatusCode != http.StatusOK {
-		return url, errors.Errorf("status is not 200 OK: %s", resp.Status)
+	consoleURL, err := s.consoleURLs.URL(s.record.Namespace, s.memberCluster)
+	if err != nil {
+		s.log.Error(err, "unable to discover the web console URL again")
+		return false
 	}
-	return url, nil
+	return status.Cluster.ConsoleURL != consoleURL
 }
 
-// alignReadiness checks if all embedded SAs are ready
+// alignReadiness checks if all embedded SAs are ready, ie, if the UserAccounts in all the target clusters of the
+// MasterUserRecord have a ready status
 func (s *Synchronizer) alignReadiness() {
-	for _, uaStatus := range s.record.Status.UserAccounts {
-		if !isReady(uaStatus.Conditions) {
+	for _, ua := range s.record.Spec.UserAccounts {
+		uaStatus, index := getUserAccountStatus(ua.TargetCluster, s.record)
+		if index < 0 || !IsReady(uaStatus.Conditions) {
 			return
 		}
 	}
 	s.record.Status.Conditions, _ = condition.AddOrUpdateStatusConditions(s.record.Status.Conditions, toBeProvisioned())
 }
 
-func isReady(conditions []toolchainv1alpha1.Condition) bool {
+// IsReady returns true if the given conditions contain a Ready condition with a `True` status (eg: the conditions
+// of a MasterUserRecord or of a UserAccount)
+func IsReady(conditions []toolchainv1alpha1.Condition) bool {
 	for _, con := range conditions {
 		if con.Type == toolchainv1alpha1.ConditionReady {
 			return con.Status == corev1.ConditionTrue
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/console"
 	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
 	"github.com/codeready-toolchain/toolchain-common/pkg/test"
 	murtest "github.com/codeready-toolchain/toolchain-common/pkg/test/masteruserrecord"
@@ -15,7 +16,6 @@ import (
 	routev1 "github.com/openshift/api/route/v1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	gock "gopkg.in/h2non/gock.v1"
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -43,6 +43,7 @@ func TestSynchronizeSpec(t *testing.T) {
 		record:            mur,
 		hostClient:        hostClient,
 		memberCluster:     newMemberCluster(memberClient),
+		consoleURLs:       console.NewDiscovery(hostClient),
 		memberUserAcc:     userAccount,
 		recordSpecUserAcc: mur.Spec.UserAccounts[0],
 		log:               l,
@@ -127,6 +128,103 @@ func TestSyncMurStatusWithUserAccountStatusWhenCompleted(t *testing.T) {
 	testSyncMurStatusWithUserAccountStatus(t, userAccount, mur, toBeProvisioned())
 }
 
+func TestSyncMurStatusWithUserAccountStatusWhenBecameReadyWithoutSpecChange(t *testing.T) {
+	// given
+	logf.SetLogger(logf.ZapLogger(true))
+	apiScheme(t)
+
+	mur := murtest.NewMasterUserRecord("john",
+		murtest.StatusCondition(toBeNotReady(provisioningReason, "")))
+
+	userAccount := uatest.NewUserAccountFromMur(mur,
+		uatest.StatusCondition(toBeNotReady(provisioningReason, "")), uatest.ResourceVersion("123abc"))
+
+	// the status is already synchronized with the current spec of the UserAccount
+	mur.Status.UserAccounts = []toolchainv1alpha1.UserAccountStatusEmbedded{{
+		SyncIndex: mur.Spec.UserAccounts[0].SyncIndex,
+		Cluster: toolchainv1alpha1.Cluster{
+			Name:        test.MemberClusterName,
+			APIEndpoint: "https://api.member-cluster:6433",
+			ConsoleURL:  "https://console.member-cluster/",
+		},
+		UserAccountStatus: userAccount.Status,
+	}}
+
+	uatest.Modify(userAccount, uatest.StatusCondition(toBeProvisioned()))
+
+	// when and then
+	testSyncMurStatusWithUserAccountStatus(t, userAccount, mur, toBeProvisioned())
+}
+
+func TestSyncMurStatusWithOutdatedConsoleURL(t *testing.T) {
+	// given
+	logf.SetLogger(logf.ZapLogger(true))
+	apiScheme(t)
+
+	mur := murtest.NewMasterUserRecord("john", murtest.StatusCondition(toBeProvisioned()))
+	userAccount := uatest.NewUserAccountFromMur(mur,
+		uatest.StatusCondition(toBeProvisioned()), uatest.ResourceVersion("123abc"))
+
+	// the status is synchronized, but the console URL was forgotten since it changed
+	mur.Status.UserAccounts = []toolchainv1alpha1.UserAccountStatusEmbedded{{
+		SyncIndex: mur.Spec.UserAccounts[0].SyncIndex,
+		Cluster: toolchainv1alpha1.Cluster{
+			Name:        test.MemberClusterName,
+			APIEndpoint: "https://api.member-cluster:6433",
+			ConsoleURL:  "https://old-console.member-cluster/",
+		},
+		UserAccountStatus: userAccount.Status,
+	}}
+
+	// when and then
+	testSyncMurStatusWithUserAccountStatus(t, userAccount, mur, toBeProvisioned())
+}
+
+func TestAlignReadinessWithSeveralUserAccounts(t *testing.T) {
+	// given
+	mur := murtest.NewMasterUserRecord("john",
+		murtest.StatusCondition(toBeNotReady(provisioningReason, "")))
+	mur.Spec.UserAccounts = append(mur.Spec.UserAccounts, toolchainv1alpha1.UserAccountEmbedded{
+		TargetCluster: "other-cluster",
+		Spec:          mur.Spec.UserAccounts[0].Spec,
+	})
+	mur.Status.UserAccounts = []toolchainv1alpha1.UserAccountStatusEmbedded{{
+		Cluster: toolchainv1alpha1.Cluster{
+			Name: test.MemberClusterName,
+		},
+		UserAccountStatus: toolchainv1alpha1.UserAccountStatus{
+			Conditions: []toolchainv1alpha1.Condition{toBeProvisioned()},
+		},
+	}}
+	sync := Synchronizer{record: mur}
+
+	t.Run("not ready until the UserAccounts in all the clusters are ready", func(t *testing.T) {
+		// when
+		sync.alignReadiness()
+
+		// then
+		test.AssertConditionsMatch(t, mur.Status.Conditions, toBeNotReady(provisioningReason, ""))
+	})
+
+	t.Run("ready when the UserAccounts in all the clusters are ready", func(t *testing.T) {
+		// given
+		mur.Status.UserAccounts = append(mur.Status.UserAccounts, toolchainv1alpha1.UserAccountStatusEmbedded{
+			Cluster: toolchainv1alpha1.Cluster{
+				Name: "other-cluster",
+			},
+			UserAccountStatus: toolchainv1alpha1.UserAccountStatus{
+				Conditions: []toolchainv1alpha1.Condition{toBeProvisioned()},
+			},
+		})
+
+		// when
+		sync.alignReadiness()
+
+		// then
+		test.AssertConditionsMatch(t, mur.Status.Conditions, toBeProvisioned())
+	})
+}
+
 func TestSynchronizeUserAccountFailed(t *testing.T) {
 	// given
 	l := logf.ZapLogger(true)
@@ -147,6 +245,7 @@ func TestSynchronizeUserAccountFailed(t *testing.T) {
 			record:            mur,
 			hostClient:        hostClient,
 			memberCluster:     newMemberCluster(memberClient),
+			consoleURLs:       console.NewDiscovery(hostClient),
 			memberUserAcc:     userAcc,
 			recordSpecUserAcc: mur.Spec.UserAccounts[0],
 			log:               l,
@@ -175,6 +274,7 @@ func TestSynchronizeUserAccountFailed(t *testing.T) {
 			record:            provisionedMur,
 			hostClient:        hostClient,
 			memberCluster:     newMemberCluster(memberClient),
+			consoleURLs:       console.NewDiscovery(hostClient),
 			memberUserAcc:     userAcc,
 			recordSpecUserAcc: provisionedMur.Spec.UserAccounts[0],
 			log:               l,
@@ -253,6 +353,7 @@ func TestSynchronizeUserAccountFailed(t *testing.T) {
 					record:            mur,
 					hostClient:        hostClient,
 					memberCluster:     newMemberCluster(memberClient),
+					consoleURLs:       console.NewDiscovery(hostClient),
 					memberUserAcc:     userAccount,
 					recordSpecUserAcc: mur.Spec.UserAccounts[0],
 					log:               l,
@@ -270,47 +371,6 @@ func TestSynchronizeUserAccountFailed(t *testing.T) {
 				assert.EqualError(t, err, `unable to get web console route for cluster member-cluster: routes.route.openshift.io "console" not found`)
 			})
 
-			t.Run("OpenShift 3.11 console available", func(t *testing.T) {
-				// given
-				sync, _ := prepareSync()
-				defer gock.OffAll()
-				gock.InterceptClient(consoleClient)
-				gock.
-					New(fmt.Sprintf("https://api.%s:6433", test.MemberClusterName)).
-					Get("console").
-					Reply(200)
-
-				// when
-				err := sync.synchronizeStatus()
-				require.NoError(t, err)
-
-				// then
-				require.Len(t, sync.record.Status.UserAccounts, 1)
-				murtest.AssertThatMasterUserRecord(t, "john", hostClient).
-					AllUserAccountsHaveCluster(toolchainv1alpha1.Cluster{
-						Name:        test.MemberClusterName,
-						APIEndpoint: "https://api.member-cluster:6433",
-						ConsoleURL:  "https://api.member-cluster:6433/console",
-					})
-			})
-
-			t.Run("OpenShift 3.11 console returns status other than 200", func(t *testing.T) {
-				// given
-				sync, _ := prepareSync()
-				defer gock.OffAll()
-				gock.InterceptClient(consoleClient)
-				gock.
-					New(fmt.Sprintf("https://api.%s:6433", test.MemberClusterName)).
-					Get("console").
-					Reply(404)
-
-				// when
-				err := sync.synchronizeStatus()
-
-				// then
-				assert.EqualError(t, err, `unable to get web console route for cluster member-cluster: routes.route.openshift.io "console" not found`)
-			})
-
 			t.Run("get route fails with error other than 404", func(t *testing.T) {
 				// given
 				sync, memberClient := prepareSync()
@@ -341,6 +401,7 @@ func testSyncMurStatusWithUserAccountStatus(t *testing.T, userAccount *toolchain
 		record:            mur,
 		hostClient:        hostClient,
 		memberCluster:     newMemberCluster(memberClient),
+		consoleURLs:       console.NewDiscovery(hostClient),
 		memberUserAcc:     userAccount,
 		recordSpecUserAcc: mur.Spec.UserAccounts[0],
 		log:               l,
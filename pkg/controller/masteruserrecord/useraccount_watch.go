@@ -0,0 +1,204 @@
+package masteruserrecord
+
+import (
+	"context"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+	"sigs.k8s.io/kubefed/pkg/client/generic"
+	"sigs.k8s.io/kubefed/pkg/controller/util"
+)
+
+const (
+	// registryRequeueDelay the delay before checking again if a member cluster was added to the registry
+	registryRequeueDelay = 5 * time.Second
+)
+
+// userAccountWatchFunc starts watching the UserAccounts in the given namespace of the member cluster with the given
+// config, and notifies the given handler of the changes until the stop channel is closed
+type userAccountWatchFunc func(config *rest.Config, namespace string, eventHandler toolscache.ResourceEventHandler, stop <-chan struct{}) error
+
+// addUserAccountWatches adds a Controller to mgr which watches the UserAccounts in all the member clusters (as long as
+// their KubeFedCluster exists), and sends an event to the given channel for the MasterUserRecord of the UserAccount
+// when the latter changes
+func addUserAccountWatches(mgr manager.Manager, events chan<- event.GenericEvent) error {
+	fedClient, err := generic.New(mgr.GetConfig())
+	if err != nil {
+		return err
+	}
+	r := &ReconcileUserAccountWatches{
+		client:                mgr.GetClient(),
+		retrieveMemberCluster: cluster.GetFedCluster,
+		buildClusterConfig: func(kubeFedCluster *v1beta1.KubeFedCluster) (*rest.Config, error) {
+			// same config as the one of the client of the member cluster in the registry
+			return util.BuildClusterConfig(kubeFedCluster, fedClient, kubeFedCluster.Namespace)
+		},
+		watchUserAccounts: newUserAccountInformer(mgr.GetScheme()),
+		events:            events,
+		watches:           map[string]userAccountWatch{},
+	}
+	c, err := controller.New("useraccountwatch-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+	// Watch for all changes of the KubeFedClusters, including their deletion
+	return c.Watch(&source.Kind{Type: &v1beta1.KubeFedCluster{}}, &handler.EnqueueRequestForObject{})
+}
+
+// newUserAccountInformer returns a userAccountWatchFunc which starts an informer on the UserAccounts in the member cluster
+func newUserAccountInformer(scheme *runtime.Scheme) userAccountWatchFunc {
+	return func(config *rest.Config, namespace string, eventHandler toolscache.ResourceEventHandler, stop <-chan struct{}) error {
+		memberCache, err := cache.New(config, cache.Options{Scheme: scheme, Namespace: namespace})
+		if err != nil {
+			return err
+		}
+		informer, err := memberCache.GetInformer(&toolchainv1alpha1.UserAccount{})
+		if err != nil {
+			return err
+		}
+		informer.AddEventHandler(eventHandler)
+		go func() {
+			if err := memberCache.Start(stop); err != nil {
+				log.Error(err, "unable to watch the UserAccounts in the member cluster", "Host", config.Host)
+			}
+		}()
+		return nil
+	}
+}
+
+// userAccountWatch a watch of the UserAccounts in a member cluster
+type userAccountWatch struct {
+	// generation the generation of the KubeFedCluster when the watch was started
+	generation int64
+	// namespace the operator namespace of the member cluster in the registry when the watch was started
+	namespace string
+	stop      chan struct{}
+}
+
+var _ reconcile.Reconciler = &ReconcileUserAccountWatches{}
+
+// ReconcileUserAccountWatches starts and stops the watches of the UserAccounts in the member clusters.
+// The controller runs with a single worker, so the watches are never accessed concurrently.
+type ReconcileUserAccountWatches struct {
+	client                client.Client
+	retrieveMemberCluster func(name string) (*cluster.FedCluster, bool)
+	buildClusterConfig    func(kubeFedCluster *v1beta1.KubeFedCluster) (*rest.Config, error)
+	watchUserAccounts     userAccountWatchFunc
+	events                chan<- event.GenericEvent
+	watches               map[string]userAccountWatch
+}
+
+// Reconcile starts watching the UserAccounts in the member cluster of the KubeFedCluster, or stops watching them
+// if the KubeFedCluster was deleted. The watch is restarted when the spec of the KubeFedCluster or the operator
+// namespace of the member cluster in the registry changes.
+func (r *ReconcileUserAccountWatches) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+
+	kubeFedCluster := &v1beta1.KubeFedCluster{}
+	if err := r.client.Get(context.TODO(), request.NamespacedName, kubeFedCluster); err != nil {
+		if errors.IsNotFound(err) {
+			if r.stopWatch(request.Name) {
+				reqLogger.Info("Stopped watching the UserAccounts of the deleted member cluster")
+			}
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	memberCluster, ok := r.retrieveMemberCluster(kubeFedCluster.Name)
+	if !ok {
+		// the member cluster is added to the registry by another controller
+		return reconcile.Result{RequeueAfter: registryRequeueDelay}, nil
+	}
+	if watch, exists := r.watches[kubeFedCluster.Name]; exists {
+		if watch.generation == kubeFedCluster.Generation && watch.namespace == memberCluster.OperatorNamespace {
+			return reconcile.Result{}, nil
+		}
+		reqLogger.Info("Restarting the watch of the UserAccounts of the updated member cluster")
+		r.stopWatch(kubeFedCluster.Name)
+	}
+	if memberCluster.Type != cluster.Member {
+		return reconcile.Result{}, nil
+	}
+	config, err := r.buildClusterConfig(kubeFedCluster)
+	if err != nil {
+		return reconcile.Result{}, errs.Wrapf(err, "unable to build the config of the member cluster '%s'", kubeFedCluster.Name)
+	}
+	stop := make(chan struct{})
+	if err := r.watchUserAccounts(config, memberCluster.OperatorNamespace, r.userAccountEventHandler(request.Namespace), stop); err != nil {
+		close(stop)
+		return reconcile.Result{}, errs.Wrapf(err, "unable to watch the UserAccounts in the member cluster '%s'", kubeFedCluster.Name)
+	}
+	r.watches[kubeFedCluster.Name] = userAccountWatch{
+		generation: kubeFedCluster.Generation,
+		namespace:  memberCluster.OperatorNamespace,
+		stop:       stop,
+	}
+	reqLogger.Info("Watching the UserAccounts of the member cluster")
+	return reconcile.Result{}, nil
+}
+
+// stopWatch stops watching the UserAccounts in the given member cluster.
+// Returns false if the UserAccounts of this member cluster were not watched.
+func (r *ReconcileUserAccountWatches) stopWatch(clusterName string) bool {
+	watch, exists := r.watches[clusterName]
+	if !exists {
+		return false
+	}
+	close(watch.stop)
+	delete(r.watches, clusterName)
+	return true
+}
+
+// userAccountEventHandler returns a handler which sends an event for the MasterUserRecord (in the given namespace)
+// of the UserAccount which was added, updated or deleted. The periodic resyncs of the informer, which notify updates
+// of unchanged UserAccounts, are ignored.
+func (r *ReconcileUserAccountWatches) userAccountEventHandler(namespace string) toolscache.ResourceEventHandler {
+	notify := func(obj interface{}) {
+		if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		userAccount, err := meta.Accessor(obj)
+		if err != nil {
+			log.Error(err, "unable to get the metadata of the UserAccount")
+			return
+		}
+		// the UserAccount has the same name as its MasterUserRecord
+		mur := &toolchainv1alpha1.MasterUserRecord{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: namespace,
+				Name:      userAccount.GetName(),
+			},
+		}
+		r.events <- event.GenericEvent{Meta: mur, Object: mur}
+	}
+	return toolscache.ResourceEventHandlerFuncs{
+		AddFunc: notify,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if oldUserAccount, err := meta.Accessor(oldObj); err == nil {
+				if newUserAccount, err := meta.Accessor(newObj); err == nil && oldUserAccount.GetResourceVersion() == newUserAccount.GetResourceVersion() {
+					return
+				}
+			}
+			notify(newObj)
+		},
+		DeleteFunc: notify,
+	}
+}
@@ -0,0 +1,223 @@
+package masteruserrecord
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	murtest "github.com/codeready-toolchain/toolchain-common/pkg/test/masteruserrecord"
+	uatest "github.com/codeready-toolchain/toolchain-common/pkg/test/useraccount"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+)
+
+func TestReconcileUserAccountWatches(t *testing.T) {
+	// given
+	apiScheme(t)
+	kubeFedCluster := &v1beta1.KubeFedCluster{
+		ObjectMeta: v1.ObjectMeta{
+			Name:       test.MemberClusterName,
+			Namespace:  test.HostOperatorNs,
+			Generation: 1,
+		},
+		Spec: v1beta1.KubeFedClusterSpec{
+			APIEndpoint: "https://api.member-cluster:6443",
+		},
+	}
+	request := reconcile.Request{NamespacedName: namespacedName(test.HostOperatorNs, test.MemberClusterName)}
+	newRegistry := func() memberRegistry {
+		return memberRegistry{
+			test.MemberClusterName: {
+				Name:              test.MemberClusterName,
+				Type:              cluster.Member,
+				OperatorNamespace: test.MemberOperatorNs,
+			},
+		}
+	}
+
+	t.Run("watch the UserAccounts and notify the changes", func(t *testing.T) {
+		// given
+		registry := newRegistry()
+		r, watches, events := prepareUserAccountWatches(t, registry, kubeFedCluster.DeepCopy())
+
+		// when
+		result, err := r.Reconcile(request)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, result)
+		require.Len(t, *watches, 1)
+		watch := (*watches)[0]
+		assert.Equal(t, "https://api.member-cluster:6443", watch.config.Host)
+		assert.Equal(t, test.MemberOperatorNs, watch.namespace)
+
+		t.Run("notify the MasterUserRecord of the UserAccount", func(t *testing.T) {
+			// given
+			previous := uatest.NewUserAccountFromMur(murtest.NewMasterUserRecord("john"))
+			previous.ResourceVersion = "1"
+			updated := previous.DeepCopy()
+			updated.ResourceVersion = "2"
+
+			// when
+			watch.handler.OnUpdate(previous, updated)
+
+			// then
+			evt := <-events
+			assert.Equal(t, test.HostOperatorNs, evt.Meta.GetNamespace())
+			assert.Equal(t, "john", evt.Meta.GetName())
+		})
+
+		t.Run("ignore the resync of an unchanged UserAccount", func(t *testing.T) {
+			// given
+			userAccount := uatest.NewUserAccountFromMur(murtest.NewMasterUserRecord("john"))
+			userAccount.ResourceVersion = "2"
+
+			// when
+			watch.handler.OnUpdate(userAccount, userAccount.DeepCopy())
+
+			// then
+			assert.Empty(t, events)
+		})
+
+		t.Run("do not watch twice", func(t *testing.T) {
+			// when
+			_, err := r.Reconcile(request)
+
+			// then
+			require.NoError(t, err)
+			assert.Len(t, *watches, 1)
+		})
+
+		t.Run("restart the watch when the KubeFedCluster is updated", func(t *testing.T) {
+			// given
+			updated := &v1beta1.KubeFedCluster{}
+			err := r.client.Get(context.TODO(), request.NamespacedName, updated)
+			require.NoError(t, err)
+			updated.Generation = 2
+			updated.Spec.APIEndpoint = "https://api.member-cluster:8443"
+			err = r.client.Update(context.TODO(), updated)
+			require.NoError(t, err)
+
+			// when
+			_, err = r.Reconcile(request)
+
+			// then
+			require.NoError(t, err)
+			require.Len(t, *watches, 2)
+			assertStopped(t, watch.stop)
+			assert.Equal(t, "https://api.member-cluster:8443", (*watches)[1].config.Host)
+			assert.Contains(t, r.watches, test.MemberClusterName)
+		})
+
+		t.Run("restart the watch when the operator namespace of the member cluster changed", func(t *testing.T) {
+			// given
+			registry[test.MemberClusterName].OperatorNamespace = "other-member-operator"
+
+			// when
+			_, err := r.Reconcile(request)
+
+			// then
+			require.NoError(t, err)
+			require.Len(t, *watches, 3)
+			assertStopped(t, (*watches)[1].stop)
+			assert.Equal(t, "other-member-operator", (*watches)[2].namespace)
+		})
+
+		t.Run("stop the watch when the KubeFedCluster is deleted", func(t *testing.T) {
+			// given
+			err := r.client.Delete(context.TODO(), kubeFedCluster.DeepCopy())
+			require.NoError(t, err)
+
+			// when
+			_, err = r.Reconcile(request)
+
+			// then
+			require.NoError(t, err)
+			assertStopped(t, (*watches)[2].stop)
+			assert.Empty(t, r.watches)
+		})
+	})
+
+	t.Run("requeue when the member cluster is not in the registry yet", func(t *testing.T) {
+		// given
+		r, watches, _ := prepareUserAccountWatches(t, memberRegistry{}, kubeFedCluster.DeepCopy())
+
+		// when
+		result, err := r.Reconcile(request)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: registryRequeueDelay}, result)
+		assert.Empty(t, *watches)
+	})
+
+	t.Run("failed to build the config of the member cluster", func(t *testing.T) {
+		// given
+		noEndpoint := kubeFedCluster.DeepCopy()
+		noEndpoint.Spec.APIEndpoint = ""
+		r, watches, _ := prepareUserAccountWatches(t, newRegistry(), noEndpoint)
+
+		// when
+		_, err := r.Reconcile(request)
+
+		// then
+		require.EqualError(t, err, "unable to build the config of the member cluster 'member-cluster': no API endpoint")
+		assert.Empty(t, *watches)
+		assert.Empty(t, r.watches)
+	})
+}
+
+type startedWatch struct {
+	config    *rest.Config
+	namespace string
+	handler   toolscache.ResourceEventHandler
+	stop      <-chan struct{}
+}
+
+// memberRegistry the entries of the member clusters in the registry, by name
+type memberRegistry map[string]*cluster.FedCluster
+
+func (m memberRegistry) retrieve(name string) (*cluster.FedCluster, bool) {
+	memberCluster, ok := m[name]
+	return memberCluster, ok
+}
+
+func prepareUserAccountWatches(t *testing.T, registry memberRegistry, initObjs ...runtime.Object) (*ReconcileUserAccountWatches, *[]startedWatch, chan event.GenericEvent) {
+	watches := &[]startedWatch{}
+	events := make(chan event.GenericEvent, 1)
+	r := &ReconcileUserAccountWatches{
+		client:                test.NewFakeClient(t, initObjs...),
+		retrieveMemberCluster: registry.retrieve,
+		buildClusterConfig: func(kubeFedCluster *v1beta1.KubeFedCluster) (*rest.Config, error) {
+			if kubeFedCluster.Spec.APIEndpoint == "" {
+				return nil, errors.New("no API endpoint")
+			}
+			return &rest.Config{Host: kubeFedCluster.Spec.APIEndpoint}, nil
+		},
+		watchUserAccounts: func(config *rest.Config, namespace string, handler toolscache.ResourceEventHandler, stop <-chan struct{}) error {
+			*watches = append(*watches, startedWatch{config: config, namespace: namespace, handler: handler, stop: stop})
+			return nil
+		},
+		events:  events,
+		watches: map[string]userAccountWatch{},
+	}
+	return r, watches, events
+}
+
+func assertStopped(t *testing.T, stop <-chan struct{}) {
+	select {
+	case <-stop:
+	default:
+		assert.Fail(t, "the watch was not stopped")
+	}
+}
@@ -0,0 +1,188 @@
+package nstemplatetier
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/config"
+	"github.com/codeready-toolchain/host-operator/pkg/controller/masteruserrecord"
+	"github.com/codeready-toolchain/host-operator/pkg/templates/nstemplatetiers"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+
+	"github.com/operator-framework/operator-sdk/pkg/predicate"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_nstemplatetier")
+
+const (
+	// rolloutRequeueDelay the delay before processing the next batch of outdated MasterUserRecords
+	rolloutRequeueDelay = 10 * time.Second
+
+	// updatingReason the reason set in the Ready condition of the MasterUserRecords which are updated with new revisions
+	updatingReason = "Updating"
+)
+
+// Add creates a new NSTemplateTier Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager, configLoader *config.Loader) error {
+	return add(mgr, newReconciler(mgr, configLoader))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager, configLoader *config.Loader) reconcile.Reconciler {
+	return &ReconcileNSTemplateTier{
+		client:       mgr.GetClient(),
+		scheme:       mgr.GetScheme(),
+		configLoader: configLoader,
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New("nstemplatetier-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource NSTemplateTier
+	return c.Watch(&source.Kind{Type: &toolchainv1alpha1.NSTemplateTier{}}, &handler.EnqueueRequestForObject{},
+		predicate.GenerationChangedPredicate{})
+}
+
+var _ reconcile.Reconciler = &ReconcileNSTemplateTier{}
+
+// ReconcileNSTemplateTier reconciles a NSTemplateTier object
+type ReconcileNSTemplateTier struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	scheme *runtime.Scheme
+	// configLoader loads the toolchain configuration, and caches it until the ConfigMap changes (shared by all the controllers)
+	configLoader *config.Loader
+}
+
+// Reconcile rolls out the revisions of the NSTemplateTier to all the MasterUserRecords which are on this tier.
+// The MasterUserRecords are updated in batches: no more than `max-unavailable` MasterUserRecords of the tier
+// can be updating (ie, not ready) at the same time, and the request is requeued until all MasterUserRecords
+// are up-to-date.
+func (r *ReconcileNSTemplateTier) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	reqLogger.Info("Reconciling NSTemplateTier")
+
+	// Fetch the NSTemplateTier instance
+	tier := &toolchainv1alpha1.NSTemplateTier{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, tier)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Return and don't requeue
+			return reconcile.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return reconcile.Result{}, err
+	}
+
+	cfg, err := r.configLoader.Load(request.Namespace)
+	if err != nil {
+		return reconcile.Result{}, errs.Wrap(err, "unable to load the toolchain configuration")
+	}
+	maxUnavailable, err := cfg.TierUpdateMaxUnavailable()
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	murs := &toolchainv1alpha1.MasterUserRecordList{}
+	if err := r.client.List(context.TODO(), murs, client.InNamespace(request.Namespace)); err != nil {
+		return reconcile.Result{}, errs.Wrap(err, "unable to list the MasterUserRecords")
+	}
+
+	nsTemplateSet := nstemplatetiers.NewNSTemplateSet(*tier)
+	var outdated []*toolchainv1alpha1.MasterUserRecord
+	unavailable := 0
+	for i := range murs.Items {
+		mur := &murs.Items[i]
+		if !isOnTier(mur, tier.Name) {
+			continue
+		}
+		if isOutdated(mur, nsTemplateSet) {
+			outdated = append(outdated, mur)
+		} else if !masteruserrecord.IsReady(mur.Status.Conditions) {
+			unavailable++
+		}
+	}
+	if len(outdated) == 0 {
+		reqLogger.Info("All MasterUserRecords are up-to-date")
+		return reconcile.Result{}, nil
+	}
+
+	for _, mur := range outdated {
+		if unavailable >= maxUnavailable {
+			break
+		}
+		if err := r.updateRevisions(mur, nsTemplateSet); err != nil {
+			return reconcile.Result{}, errs.Wrapf(err, "unable to update the MasterUserRecord '%s' with the revisions of the tier '%s'", mur.Name, tier.Name)
+		}
+		reqLogger.Info("MasterUserRecord updated with new revisions", "MasterUserRecord", mur.Name)
+		unavailable++
+	}
+	reqLogger.Info("MasterUserRecords are being updated", "outdated", len(outdated), "unavailable", unavailable)
+	return reconcile.Result{RequeueAfter: rolloutRequeueDelay}, nil
+}
+
+// updateRevisions sets the given NSTemplateSet in all the UserAccounts of the MasterUserRecord which are on
+// the same tier, and marks the MasterUserRecord as not ready until the member clusters are updated
+func (r *ReconcileNSTemplateTier) updateRevisions(mur *toolchainv1alpha1.MasterUserRecord, nsTemplateSet toolchainv1alpha1.NSTemplateSetSpec) error {
+	for i, ua := range mur.Spec.UserAccounts {
+		if ua.Spec.NSTemplateSet.TierName == nsTemplateSet.TierName {
+			mur.Spec.UserAccounts[i].Spec.NSTemplateSet = nsTemplateSet
+		}
+	}
+	if err := r.client.Update(context.TODO(), mur); err != nil {
+		return err
+	}
+	var updated bool
+	mur.Status.Conditions, updated = condition.AddOrUpdateStatusConditions(mur.Status.Conditions, toolchainv1alpha1.Condition{
+		Type:   toolchainv1alpha1.ConditionReady,
+		Status: corev1.ConditionFalse,
+		Reason: updatingReason,
+	})
+	if !updated {
+		return nil
+	}
+	return r.client.Status().Update(context.TODO(), mur)
+}
+
+// isOnTier returns true if at least one of the UserAccounts of the MasterUserRecord is on the given tier
+func isOnTier(mur *toolchainv1alpha1.MasterUserRecord, tierName string) bool {
+	for _, ua := range mur.Spec.UserAccounts {
+		if ua.Spec.NSTemplateSet.TierName == tierName {
+			return true
+		}
+	}
+	return false
+}
+
+// isOutdated returns true if at least one of the UserAccounts of the MasterUserRecord which are on the same tier
+// does not have the given namespaces and revisions
+func isOutdated(mur *toolchainv1alpha1.MasterUserRecord, nsTemplateSet toolchainv1alpha1.NSTemplateSetSpec) bool {
+	for _, ua := range mur.Spec.UserAccounts {
+		if ua.Spec.NSTemplateSet.TierName == nsTemplateSet.TierName && !reflect.DeepEqual(ua.Spec.NSTemplateSet, nsTemplateSet) {
+			return true
+		}
+	}
+	return false
+}
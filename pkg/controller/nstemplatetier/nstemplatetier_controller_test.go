@@ -0,0 +1,234 @@
+package nstemplatetier
+
+import (
+	"context"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/apis"
+	"github.com/codeready-toolchain/host-operator/pkg/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const operatorNamespace = "toolchain-host-operator"
+
+func TestReconcileNSTemplateTier(t *testing.T) {
+	// given
+	s := scheme.Scheme
+	err := apis.AddToScheme(s)
+	require.NoError(t, err)
+	basicTier := newNSTemplateTier("basic", "222222a", "222222b")
+
+	t.Run("update outdated MasterUserRecords in batch", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, basicTier, configMap("2"),
+			newMasterUserRecord("john", "basic", "111111a", "111111b", true),
+			newMasterUserRecord("jane", "basic", "111111a", "111111b", true),
+			newMasterUserRecord("jack", "basic", "111111a", "111111b", true),
+			newMasterUserRecord("joe", "advanced", "111111a", "111111b", true))
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: rolloutRequeueDelay}, res)
+		assert.Equal(t, 1, countMasterUserRecordsWithRevisions(t, cl, "basic", "111111a", "111111b"))
+		assert.Equal(t, 2, countMasterUserRecordsWithRevisions(t, cl, "basic", "222222a", "222222b"))
+		assert.Equal(t, 1, countMasterUserRecordsWithRevisions(t, cl, "advanced", "111111a", "111111b"))
+
+		t.Run("wait while MasterUserRecords are not ready", func(t *testing.T) {
+			// when
+			res, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, reconcile.Result{RequeueAfter: rolloutRequeueDelay}, res)
+			assert.Equal(t, 1, countMasterUserRecordsWithRevisions(t, cl, "basic", "111111a", "111111b"))
+		})
+	})
+
+	t.Run("update outdated MasterUserRecords with default max unavailable", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, basicTier,
+			newMasterUserRecord("john", "basic", "111111a", "111111b", true),
+			newMasterUserRecord("jane", "basic", "111111a", "111111b", false))
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: rolloutRequeueDelay}, res)
+		assert.Equal(t, 2, countMasterUserRecordsWithRevisions(t, cl, "basic", "222222a", "222222b"))
+		mur := &toolchainv1alpha1.MasterUserRecord{}
+		err = cl.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: "john"}, mur)
+		require.NoError(t, err)
+		test.AssertConditionsMatch(t, mur.Status.Conditions, toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: v1.ConditionFalse,
+			Reason: updatingReason,
+		})
+	})
+
+	t.Run("count MasterUserRecords which are not ready as unavailable", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, basicTier, configMap("2"),
+			newMasterUserRecord("john", "basic", "222222a", "222222b", false),
+			newMasterUserRecord("jane", "basic", "111111a", "111111b", true),
+			newMasterUserRecord("jack", "basic", "111111a", "111111b", true))
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: rolloutRequeueDelay}, res)
+		assert.Equal(t, 1, countMasterUserRecordsWithRevisions(t, cl, "basic", "111111a", "111111b"))
+	})
+
+	t.Run("all MasterUserRecords are up-to-date", func(t *testing.T) {
+		// given
+		r, req, _ := prepareReconcile(t, basicTier,
+			newMasterUserRecord("john", "basic", "222222a", "222222b", true),
+			newMasterUserRecord("joe", "advanced", "111111a", "111111b", true))
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+	})
+
+	t.Run("tier not found", func(t *testing.T) {
+		// given
+		r, req, _ := prepareReconcile(t, newNSTemplateTier("advanced", "222222a", "222222b"))
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+	})
+
+	t.Run("invalid max unavailable config", func(t *testing.T) {
+		// given
+		r, req, _ := prepareReconcile(t, basicTier, configMap("0"),
+			newMasterUserRecord("john", "basic", "111111a", "111111b", true))
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.EqualError(t, err, "invalid value for 'tier-update-max-unavailable': '0'")
+	})
+}
+
+func prepareReconcile(t *testing.T, initObjs ...runtime.Object) (*ReconcileNSTemplateTier, reconcile.Request, *test.FakeClient) {
+	cl := test.NewFakeClient(t, initObjs...)
+	r := &ReconcileNSTemplateTier{
+		client:       cl,
+		scheme:       scheme.Scheme,
+		configLoader: config.NewLoader(cl, record.NewFakeRecorder(10)),
+	}
+	return r, reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: operatorNamespace,
+			Name:      "basic",
+		},
+	}, cl
+}
+
+func newNSTemplateTier(name, codeRevision, devRevision string) *toolchainv1alpha1.NSTemplateTier {
+	return &toolchainv1alpha1.NSTemplateTier{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: operatorNamespace,
+			Name:      name,
+		},
+		Spec: toolchainv1alpha1.NSTemplateTierSpec{
+			Namespaces: []toolchainv1alpha1.NSTemplateTierNamespace{
+				{Type: "code", Revision: codeRevision},
+				{Type: "dev", Revision: devRevision},
+			},
+		},
+	}
+}
+
+func newMasterUserRecord(name, tierName, codeRevision, devRevision string, ready bool) *toolchainv1alpha1.MasterUserRecord {
+	status := v1.ConditionFalse
+	if ready {
+		status = v1.ConditionTrue
+	}
+	return &toolchainv1alpha1.MasterUserRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: operatorNamespace,
+			Name:      name,
+		},
+		Spec: toolchainv1alpha1.MasterUserRecordSpec{
+			UserAccounts: []toolchainv1alpha1.UserAccountEmbedded{
+				{
+					TargetCluster: "member-cluster",
+					Spec: toolchainv1alpha1.UserAccountSpec{
+						UserID:  name,
+						NSLimit: "default",
+						NSTemplateSet: toolchainv1alpha1.NSTemplateSetSpec{
+							TierName: tierName,
+							Namespaces: []toolchainv1alpha1.NSTemplateSetNamespace{
+								{Type: "code", Revision: codeRevision},
+								{Type: "dev", Revision: devRevision},
+							},
+						},
+					},
+				},
+			},
+		},
+		Status: toolchainv1alpha1.MasterUserRecordStatus{
+			Conditions: []toolchainv1alpha1.Condition{
+				{
+					Type:   toolchainv1alpha1.ConditionReady,
+					Status: status,
+				},
+			},
+		},
+	}
+}
+
+func countMasterUserRecordsWithRevisions(t *testing.T, cl *test.FakeClient, tierName, codeRevision, devRevision string) int {
+	murs := &toolchainv1alpha1.MasterUserRecordList{}
+	err := cl.List(context.TODO(), murs)
+	require.NoError(t, err)
+	count := 0
+	for _, mur := range murs.Items {
+		nsTemplateSet := mur.Spec.UserAccounts[0].Spec.NSTemplateSet
+		if nsTemplateSet.TierName == tierName &&
+			nsTemplateSet.Namespaces[0].Revision == codeRevision &&
+			nsTemplateSet.Namespaces[1].Revision == devRevision {
+			count++
+		}
+	}
+	return count
+}
+
+func configMap(maxUnavailable string) *v1.ConfigMap {
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: operatorNamespace,
+			Name:      config.ToolchainConfigMapName,
+		},
+		Data: map[string]string{
+			config.ToolchainConfigMapTierUpdateMaxUnavailable: maxUnavailable,
+		},
+	}
+}
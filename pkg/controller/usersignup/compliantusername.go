@@ -0,0 +1,145 @@
+package usersignup
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// maxCompliantUsernameLength the maximum length of a compliant username, so that the names of the user's
+	// namespaces (eg: `<username>-stage`) are still valid DNS-1123 labels (ie, no more than 63 characters)
+	maxCompliantUsernameLength = 63 - len("-stage")
+
+	// forbiddenUsernamePrefix the prefix added to the compliant usernames which are forbidden
+	forbiddenUsernamePrefix = "crt-"
+)
+
+var (
+	// transliterator removes the diacritics (eg: `é` becomes `e`)
+	transliterator = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	// letters which are not decomposed by the transliterator
+	letterReplacer = strings.NewReplacer("ß", "ss", "æ", "ae", "œ", "oe", "ø", "o", "đ", "d", "ł", "l", "þ", "th")
+	invalidChars   = regexp.MustCompile("[^a-z0-9]+")
+)
+
+// generateCompliantUsername returns a compliant username for the user: a valid DNS-1123 label derived from the
+// username in the UserSignup, which is not forbidden and not already used or reserved by another user.
+func (r *ReconcileUserSignup) generateCompliantUsername(instance *toolchainv1alpha1.UserSignup) (string, error) {
+	sanitized := sanitizeUsername(instance.Spec.Username)
+	if sanitized == "" {
+		return "", NewSignupError(fmt.Sprintf("transformed username [%s] is invalid", instance.Spec.Username))
+	}
+
+	// If the user was provisioned before (and deactivated since), then reuse the same compliant username
+	if instance.Status.CompliantUsername != "" {
+		return instance.Status.CompliantUsername, nil
+	}
+
+	cfg, err := r.configLoader.Load(instance.Namespace)
+	if err != nil {
+		return "", err
+	}
+	if isForbiddenUsername(sanitized, cfg.ForbiddenUsernames()) {
+		sanitized = truncate(forbiddenUsernamePrefix+sanitized, maxCompliantUsernameLength)
+	}
+
+	taken, err := r.takenCompliantUsernames(instance)
+	if err != nil {
+		return "", err
+	}
+
+	transformed := sanitized
+
+	for i := 1; i < 101; i++ { // No more than 100 attempts to find a vacant name
+		if !taken[transformed] {
+			// reserve the username, in case another UserSignup with the same username is reconciled concurrently
+			reserved, err := r.reserveUsername(instance, transformed)
+			if err != nil {
+				return "", err
+			}
+			if reserved {
+				return transformed, nil
+			}
+		}
+		suffix := fmt.Sprintf("-%d", i)
+		transformed = truncate(sanitized, maxCompliantUsernameLength-len(suffix)) + suffix
+	}
+
+	return "", NewSignupError(fmt.Sprintf("unable to transform username [%s] even after 100 attempts", instance.Spec.Username))
+}
+
+// takenCompliantUsernames returns the names of all the MasterUserRecords along with the compliant usernames of
+// all the other UserSignups (including the deactivated ones) in the same namespace
+func (r *ReconcileUserSignup) takenCompliantUsernames(instance *toolchainv1alpha1.UserSignup) (map[string]bool, error) {
+	murs := &toolchainv1alpha1.MasterUserRecordList{}
+	if err := r.client.List(context.TODO(), murs, client.InNamespace(instance.Namespace)); err != nil {
+		return nil, err
+	}
+	userSignups := &toolchainv1alpha1.UserSignupList{}
+	if err := r.client.List(context.TODO(), userSignups, client.InNamespace(instance.Namespace)); err != nil {
+		return nil, err
+	}
+	taken := make(map[string]bool, len(murs.Items)+len(userSignups.Items))
+	for _, mur := range murs.Items {
+		if mur.Labels[toolchainv1alpha1.MasterUserRecordUserIDLabelKey] == instance.Name {
+			// If the found MUR has the same UserID as the UserSignup, then *it* is the correct MUR -
+			// Return an error here and allow the reconcile() function to pick it up on the next loop
+			return nil, NewSignupError(fmt.Sprintf("could not generate compliant username as MasterUserRecord [%s] already exists", mur.Name))
+		}
+		taken[mur.Name] = true
+	}
+	for _, userSignup := range userSignups.Items {
+		if userSignup.Name != instance.Name && userSignup.Status.CompliantUsername != "" {
+			taken[userSignup.Status.CompliantUsername] = true
+		}
+	}
+	return taken, nil
+}
+
+// sanitizeUsername transforms the given username into a valid DNS-1123 label: the diacritics are removed,
+// `@` is replaced with `-at-`, all the other characters which are not lowercase alphanumeric are replaced with
+// a single dash, and the result is truncated to the maximum length of a compliant username.
+// Returns an empty string if nothing is left of the username.
+func sanitizeUsername(username string) string {
+	transliterated, _, err := transform.String(transliterator, username)
+	if err != nil {
+		transliterated = username
+	}
+	sanitized := letterReplacer.Replace(strings.ToLower(transliterated))
+	sanitized = strings.ReplaceAll(sanitized, "@", "-at-")
+	sanitized = invalidChars.ReplaceAllString(sanitized, "-")
+	return truncate(strings.Trim(sanitized, "-"), maxCompliantUsernameLength)
+}
+
+// truncate truncates the name to the given length, without leaving a trailing dash
+func truncate(name string, length int) string {
+	if len(name) <= length {
+		return name
+	}
+	return strings.TrimRight(name[:length], "-")
+}
+
+// isForbiddenUsername returns true if the username matches one of the forbidden usernames, which are either
+// exact names (eg: `default`) or prefixes ending with a `*` (eg: `kube-*`)
+func isForbiddenUsername(username string, forbidden []string) bool {
+	for _, f := range forbidden {
+		if strings.HasSuffix(f, "*") {
+			if strings.HasPrefix(username, strings.TrimSuffix(f, "*")) {
+				return true
+			}
+		} else if username == f {
+			return true
+		}
+	}
+	return false
+}
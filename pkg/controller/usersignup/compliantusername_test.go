@@ -0,0 +1,173 @@
+package usersignup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/config"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestSanitizeUsername(t *testing.T) {
+	for username, expected := range map[string]string{
+		"foo@redhat.com":            "foo-at-redhat-com",
+		"Foo.Bar@RedHat.com":        "foo-bar-at-redhat-com",
+		"foo#bar@redhat.com":        "foo-bar-at-redhat-com",
+		"foo+bar_baz@redhat.com":    "foo-bar-baz-at-redhat-com",
+		"--foo..bar--":              "foo-bar",
+		"jérôme.ångström@redhat.cz": "jerome-angstrom-at-redhat-cz",
+		"straße@redhat.de":          "strasse-at-redhat-de",
+		"søren@redhat.dk":           "soren-at-redhat-dk",
+		"#$%&":                      "",
+		"用户":                        "",
+	} {
+		t.Run(username, func(t *testing.T) {
+			assert.Equal(t, expected, sanitizeUsername(username))
+		})
+	}
+
+	t.Run("truncate long username", func(t *testing.T) {
+		// when
+		sanitized := sanitizeUsername(strings.Repeat("a", 50) + "@redhat.com")
+
+		// then
+		assert.Equal(t, strings.Repeat("a", 50)+"-at-red", sanitized)
+		assert.Empty(t, validation.IsDNS1123Label(sanitized+"-stage"))
+	})
+
+	t.Run("truncate without trailing dash", func(t *testing.T) {
+		// when
+		sanitized := sanitizeUsername(strings.Repeat("a", 53) + "@redhat.com")
+
+		// then
+		assert.Equal(t, strings.Repeat("a", 53)+"-at", sanitized)
+	})
+}
+
+func TestIsForbiddenUsername(t *testing.T) {
+	forbidden := []string{"default", "kube-*"}
+
+	assert.True(t, isForbiddenUsername("default", forbidden))
+	assert.True(t, isForbiddenUsername("kube-system", forbidden))
+	assert.False(t, isForbiddenUsername("default-user", forbidden))
+	assert.False(t, isForbiddenUsername("kube", forbidden))
+}
+
+func TestGenerateCompliantUsername(t *testing.T) {
+
+	newUserSignup := func(username string) *v1alpha1.UserSignup {
+		return &v1alpha1.UserSignup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uuid.NewV4().String(),
+				Namespace: operatorNamespace,
+				UID:       types.UID(uuid.NewV4().String()),
+			},
+			Spec: v1alpha1.UserSignupSpec{
+				Username: username,
+			},
+		}
+	}
+
+	t.Run("invalid username", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup("!?*")
+		r, _, _ := prepareReconcile(t, userSignup.Name, userSignup)
+
+		// when
+		_, err := r.generateCompliantUsername(userSignup)
+
+		// then
+		require.EqualError(t, err, "transformed username [!?*] is invalid")
+	})
+
+	t.Run("forbidden username", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup("kube-admin")
+		r, _, _ := prepareReconcile(t, userSignup.Name, userSignup)
+
+		// when
+		username, err := r.generateCompliantUsername(userSignup)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "crt-kube-admin", username)
+	})
+
+	t.Run("configured forbidden username", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup("foo@redhat.com")
+		cm := configMap(config.UserApprovalPolicyAutomatic)
+		cm.Data[config.ToolchainConfigMapForbiddenUsernames] = "bar, foo-*"
+		r, _, _ := prepareReconcile(t, userSignup.Name, userSignup, cm)
+
+		// when
+		username, err := r.generateCompliantUsername(userSignup)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "crt-foo-at-redhat-com", username)
+	})
+
+	t.Run("username taken by other users", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup("foo@redhat.com")
+		other := newUserSignup("foo@redhat.com")
+		other.Status.CompliantUsername = "foo-at-redhat-com-1"
+		r, _, _ := prepareReconcile(t, userSignup.Name, userSignup, other, newMasterUserRecordInCluster("foo-at-redhat-com", nameMember))
+
+		// when
+		username, err := r.generateCompliantUsername(userSignup)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "foo-at-redhat-com-2", username)
+	})
+
+	t.Run("suffix of a long username", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup(strings.Repeat("a", 60))
+		r, _, _ := prepareReconcile(t, userSignup.Name, userSignup, newMasterUserRecordInCluster(strings.Repeat("a", 57), nameMember))
+
+		// when
+		username, err := r.generateCompliantUsername(userSignup)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, strings.Repeat("a", 55)+"-1", username)
+	})
+
+	t.Run("username checked with a single list of MasterUserRecords", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup("foo@redhat.com")
+		objs := []runtime.Object{userSignup}
+		for i := 1; i < 50; i++ {
+			objs = append(objs, newMasterUserRecordInCluster(fmt.Sprintf("foo-at-redhat-com-%d", i), nameMember))
+		}
+		objs = append(objs, newMasterUserRecordInCluster("foo-at-redhat-com", nameMember))
+		r, _, cl := prepareReconcile(t, userSignup.Name, objs...)
+		gets := 0
+		cl.MockGet = func(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+			gets++
+			return cl.Client.Get(ctx, key, obj)
+		}
+
+		// when
+		username, err := r.generateCompliantUsername(userSignup)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "foo-at-redhat-com-50", username)
+		assert.Equal(t, 1, gets) // only the toolchain config map
+	})
+}
@@ -0,0 +1,58 @@
+package usersignup
+
+import (
+	"context"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// usernameReservationLabelKey the label of the ConfigMaps which reserve a compliant username for a UserSignup
+	usernameReservationLabelKey = "toolchain.dev.openshift.com/username-reservation"
+	// usernameReservationPrefix the prefix of the names of the ConfigMaps which reserve a compliant username
+	usernameReservationPrefix = "username-"
+)
+
+// reserveUsername reserves the compliant username for the given UserSignup, so that no other UserSignup can
+// claim the same username while the MasterUserRecord is not created yet.
+// The reservation is a ConfigMap named after the compliant username: since its creation fails if it already exists,
+// only one UserSignup can hold the reservation. The ConfigMap is controlled by the UserSignup, so that the reservation
+// is released (ie, garbage collected) when the UserSignup is deleted.
+// Returns true if the username is reserved for the given UserSignup (including when it was reserved by a previous
+// reconcile), false if it is reserved for another UserSignup.
+func (r *ReconcileUserSignup) reserveUsername(userSignup *toolchainv1alpha1.UserSignup, username string) (bool, error) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      usernameReservationPrefix + username,
+			Namespace: userSignup.Namespace,
+			Labels: map[string]string{
+				usernameReservationLabelKey:                      "true",
+				toolchainv1alpha1.MasterUserRecordUserIDLabelKey: userSignup.Name,
+			},
+		},
+		Data: map[string]string{
+			"username": username,
+		},
+	}
+	if err := controllerutil.SetControllerReference(userSignup, cm, r.scheme); err != nil {
+		return false, err
+	}
+	err := r.client.Create(context.TODO(), cm)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.IsAlreadyExists(err) {
+		return false, err
+	}
+	existing := &corev1.ConfigMap{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: cm.Namespace, Name: cm.Name}, existing); err != nil {
+		return false, err
+	}
+	return metav1.IsControlledBy(existing, userSignup), nil
+}
@@ -0,0 +1,123 @@
+package usersignup
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReserveUsername(t *testing.T) {
+	// given
+	userSignup := newUserSignupCreatedAt("foo", time.Now())
+	other := newUserSignupCreatedAt("other", time.Now())
+	r, _, _ := prepareReconcile(t, userSignup.Name, userSignup, other)
+
+	t.Run("reserve vacant username", func(t *testing.T) {
+		// when
+		reserved, err := r.reserveUsername(userSignup, "foo-at-redhat-com")
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, reserved)
+		cm := &v1.ConfigMap{}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: "username-foo-at-redhat-com"}, cm)
+		require.NoError(t, err)
+		assert.Equal(t, "foo", cm.Labels[v1alpha1.MasterUserRecordUserIDLabelKey])
+		// the reservation is released when the UserSignup is deleted
+		assert.True(t, metav1.IsControlledBy(cm, userSignup))
+	})
+
+	t.Run("username already reserved for the same signup", func(t *testing.T) {
+		// when
+		reserved, err := r.reserveUsername(userSignup, "foo-at-redhat-com")
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, reserved)
+	})
+
+	t.Run("username already reserved for another signup", func(t *testing.T) {
+		// when
+		reserved, err := r.reserveUsername(other, "foo-at-redhat-com")
+
+		// then
+		require.NoError(t, err)
+		assert.False(t, reserved)
+	})
+}
+
+func TestGenerateCompliantUsernameWithReservation(t *testing.T) {
+	// given
+	userSignup := newUserSignupCreatedAt("foo", time.Now())
+	other := newUserSignupCreatedAt("other", time.Now())
+	other.Spec.Username = userSignup.Spec.Username
+	r, _, _ := prepareReconcile(t, userSignup.Name, userSignup, other)
+	reserved, err := r.reserveUsername(other, "foo-at-redhat-com")
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	// when
+	username, err := r.generateCompliantUsername(userSignup)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "foo-at-redhat-com-1", username)
+}
+
+func TestConcurrentReconcilesWithSameUsername(t *testing.T) {
+	// given
+	newApprovedUserSignup := func() *v1alpha1.UserSignup {
+		return &v1alpha1.UserSignup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uuid.NewV4().String(),
+				Namespace: operatorNamespace,
+				UID:       types.UID(uuid.NewV4().String()),
+			},
+			Spec: v1alpha1.UserSignupSpec{
+				Username: "foo@redhat.com",
+				Approved: true,
+			},
+		}
+	}
+	first := newApprovedUserSignup()
+	second := newApprovedUserSignup()
+	r, _, _ := prepareReconcile(t, first.Name, first, second, basicNSTemplateTier)
+	createMemberCluster(r.client)
+	defer clearMemberClusters(r.client)
+
+	// when
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, userSignup := range []*v1alpha1.UserSignup{first, second} {
+		wg.Add(1)
+		go func(i int, req reconcile.Request) {
+			defer wg.Done()
+			_, errs[i] = r.Reconcile(req)
+		}(i, newReconcileRequest(userSignup.Name))
+	}
+	wg.Wait()
+
+	// then
+	require.NoError(t, errs[0])
+	require.NoError(t, errs[1])
+	murs := &v1alpha1.MasterUserRecordList{}
+	err := r.client.List(context.TODO(), murs)
+	require.NoError(t, err)
+	require.Len(t, murs.Items, 2)
+	assert.ElementsMatch(t, []string{"foo-at-redhat-com", "foo-at-redhat-com-1"}, []string{murs.Items[0].Name, murs.Items[1].Name})
+	assert.ElementsMatch(t, []string{first.Name, second.Name}, []string{
+		murs.Items[0].Labels[v1alpha1.MasterUserRecordUserIDLabelKey],
+		murs.Items[1].Labels[v1alpha1.MasterUserRecordUserIDLabelKey],
+	})
+}
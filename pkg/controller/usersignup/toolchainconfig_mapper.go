@@ -0,0 +1,51 @@
+package usersignup
+
+import (
+	"context"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/config"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// toolchainConfigMapper maps the toolchain ConfigMap to the UserSignups of the same namespace which are not complete
+// (eg: pending approval or waitlisted), after invalidating the cached configuration. The shared configuration loader
+// is also invalidated on its own when the ConfigMap changes, but the UserSignups must not be reconciled before.
+type toolchainConfigMapper struct {
+	client       client.Client
+	configLoader *config.Loader
+}
+
+var _ handler.Mapper = toolchainConfigMapper{}
+
+// Map implements handler.Mapper
+func (m toolchainConfigMapper) Map(obj handler.MapObject) []reconcile.Request {
+	if obj.Meta.GetName() != config.ToolchainConfigMapName {
+		return nil
+	}
+	m.configLoader.Invalidate(obj.Meta.GetNamespace())
+
+	userSignups := &toolchainv1alpha1.UserSignupList{}
+	if err := m.client.List(context.TODO(), userSignups, client.InNamespace(obj.Meta.GetNamespace())); err != nil {
+		log.Error(err, "unable to list the UserSignups")
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, userSignup := range userSignups.Items {
+		if complete, found := findCondition(userSignup.Status.Conditions, toolchainv1alpha1.UserSignupComplete); found && complete.Status == corev1.ConditionTrue {
+			continue
+		}
+		if isDeactivated(&userSignup) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: userSignup.Namespace, Name: userSignup.Name},
+		})
+	}
+	return requests
+}
@@ -0,0 +1,78 @@
+package usersignup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestToolchainConfigMapper(t *testing.T) {
+	// given
+	pending := newUserSignupCreatedAt("pending", time.Now())
+	pending.Status.Conditions = []v1alpha1.Condition{
+		{
+			Type:   v1alpha1.UserSignupComplete,
+			Status: v1.ConditionFalse,
+			Reason: "PendingApproval",
+		},
+	}
+	waitlisted := newWaitlistedUserSignup("waitlisted", time.Now())
+	complete := newUserSignupCreatedAt("complete", time.Now())
+	complete.Status.Conditions = []v1alpha1.Condition{
+		{
+			Type:   v1alpha1.UserSignupComplete,
+			Status: v1.ConditionTrue,
+		},
+	}
+	deactivated := newUserSignupCreatedAt("deactivated", time.Now())
+	deactivated.Annotations = map[string]string{"toolchain.dev.openshift.com/deactivated": "true"}
+	cm := configMap(config.UserApprovalPolicyManual)
+	r, _, cl := prepareReconcile(t, "", pending, waitlisted, complete, deactivated, cm)
+	mapper := toolchainConfigMapper{client: cl, configLoader: r.configLoader}
+
+	t.Run("requeue incomplete signups when the toolchain config map changed", func(t *testing.T) {
+		// given
+		policy, err := r.ReadUserApprovalPolicyConfig(operatorNamespace)
+		require.NoError(t, err)
+		require.Equal(t, config.UserApprovalPolicyManual, policy)
+		cm.Data[config.ToolchainConfigMapUserApprovalPolicy] = config.UserApprovalPolicyAutomatic
+		err = cl.Update(context.TODO(), cm)
+		require.NoError(t, err)
+
+		// when
+		requests := mapper.Map(handler.MapObject{Meta: cm, Object: cm})
+
+		// then
+		assert.ElementsMatch(t, []reconcile.Request{newReconcileRequest("pending"), newReconcileRequest("waitlisted")}, requests)
+		// the cached configuration was refreshed
+		policy, err = r.ReadUserApprovalPolicyConfig(operatorNamespace)
+		require.NoError(t, err)
+		assert.Equal(t, config.UserApprovalPolicyAutomatic, policy)
+	})
+
+	t.Run("ignore other config maps", func(t *testing.T) {
+		// given
+		other := &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "other",
+				Namespace: operatorNamespace,
+			},
+		}
+
+		// when
+		requests := mapper.Map(handler.MapObject{Meta: other, Object: other})
+
+		// then
+		assert.Empty(t, requests)
+	})
+}
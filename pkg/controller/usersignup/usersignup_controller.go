@@ -3,17 +3,21 @@ package usersignup
 import (
 	"context"
 	"fmt"
-	"k8s.io/apimachinery/pkg/util/validation"
 	"strings"
+	"time"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/banneduser"
 	"github.com/codeready-toolchain/host-operator/pkg/config"
+	"github.com/codeready-toolchain/host-operator/pkg/notification"
+	"github.com/codeready-toolchain/host-operator/pkg/placement"
+	"github.com/codeready-toolchain/host-operator/pkg/predicate"
+	"github.com/codeready-toolchain/host-operator/pkg/templates/nstemplatetiers"
 	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
 	commonCondition "github.com/codeready-toolchain/toolchain-common/pkg/condition"
 	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/go-logr/logr"
-	"github.com/operator-framework/operator-sdk/pkg/predicate"
 	errs "github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -32,30 +36,76 @@ import (
 const (
 	// Status condition reasons
 	noClustersAvailableReason            = "NoClustersAvailable"
+	waitlistedReason                     = "Waitlisted"
+	noMatchingClusterAvailableReason     = "NoMatchingClusterAvailable"
+	failedToReadToolchainConfigReason    = "FailedToReadToolchainConfig"
 	noTemplateTierAvailableReason        = "NoTemplateTierAvailable"
+	requestedTierNotFoundReason          = "RequestedTierNotFound"
 	failedToReadUserApprovalPolicyReason = "FailedToReadUserApprovalPolicy"
 	unableToCreateMURReason              = "UnableToCreateMUR"
 	invalidMURState                      = "InvalidMURState"
 	approvedAutomaticallyReason          = "ApprovedAutomatically"
 	approvedByAdminReason                = "ApprovedByAdmin"
+	approvedAutomaticallyByDomainReason  = "ApprovedAutomaticallyByDomain"
+	rejectedAutomaticallyByDomainReason  = "RejectedAutomaticallyByDomain"
 	pendingApprovalReason                = "PendingApproval"
+	deactivatedReason                    = "Deactivated"
+	reactivatedReason                    = "Reactivated"
+	unableToDeactivateReason             = "UnableToDeactivate"
+	rejectedReason                       = "Rejected"
+	bannedReason                         = "Banned"
+	bannedIdentityReason                 = "BannedIdentity"
+	unableToCheckBannedUsersReason       = "UnableToCheckBannedUsers"
+	unableToBanReason                    = "UnableToBan"
+	unableToUnbanReason                  = "UnableToUnban"
+	unableToTearDownReason               = "UnableToTearDown"
+	lifetimeLimitedReason                = "LifetimeLimited"
+	noLifetimeLimitReason                = "NoLifetimeLimit"
+
+	// userSignupDeactivated the type of the condition which tells if the user is deactivated
+	userSignupDeactivated toolchainv1alpha1.ConditionType = "Deactivated"
+	// userSignupExpires the type of the condition which tells when the account of the user expires, if it does
+	userSignupExpires toolchainv1alpha1.ConditionType = "Expires"
+
+	// Annotations
+	userEmailAnnotationKey     = "toolchain.dev.openshift.com/user-email"
+	placementRuleAnnotationKey = "toolchain.dev.openshift.com/placement-rule"
+	requestedTierAnnotationKey = "toolchain.dev.openshift.com/requested-tier"
+	deactivatedAnnotationKey   = "toolchain.dev.openshift.com/deactivated"
+	rejectedAnnotationKey      = "toolchain.dev.openshift.com/rejected"
+	bannedAnnotationKey        = "toolchain.dev.openshift.com/banned"
+	provisionedAnnotationKey   = "toolchain.dev.openshift.com/provisioned-time"
+	expiryAnnotationKey        = "toolchain.dev.openshift.com/expiry-time"
+	// notifiedAnnotationKeyPrefix the prefix of the annotations recording the time at which each type of notification
+	// was sent to the user (eg: `toolchain.dev.openshift.com/notified-approved`)
+	notifiedAnnotationKeyPrefix = "toolchain.dev.openshift.com/notified-"
+
+	// Labels of the ConfigMaps recording the notifications sent to the users
+	notificationTypeLabelKey = "toolchain.dev.openshift.com/notification-type"
+	userSignupLabelKey       = "toolchain.dev.openshift.com/usersignup"
 )
 
 var log = logf.Log.WithName("controller_usersignup")
 
 // Add creates a new UserSignup Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
-func Add(mgr manager.Manager) error {
-	return add(mgr, newReconciler(mgr))
+func Add(mgr manager.Manager, configLoader *config.Loader) error {
+	return add(mgr, newReconciler(mgr, configLoader))
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileUserSignup{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+func newReconciler(mgr manager.Manager, configLoader *config.Loader) *ReconcileUserSignup {
+	return &ReconcileUserSignup{
+		client:       mgr.GetClient(),
+		scheme:       mgr.GetScheme(),
+		placement:    placement.New(mgr.GetClient(), cluster.GetMemberClusters, placement.LeastUtilized),
+		configLoader: configLoader,
+		newNotifier:  notification.NewSMTPNotifierFromConfig,
+	}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
-func add(mgr manager.Manager, r reconcile.Reconciler) error {
+func add(mgr manager.Manager, r *ReconcileUserSignup) error {
 	// Create a new controller
 	c, err := controller.New("usersignup-controller", mgr, controller.Options{Reconciler: r})
 	if err != nil {
@@ -64,7 +114,7 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 
 	// Watch for changes to primary resource UserSignup
 	err = c.Watch(&source.Kind{Type: &toolchainv1alpha1.UserSignup{}}, &handler.EnqueueRequestForObject{},
-		predicate.GenerationChangedPredicate{})
+		predicate.GenerationOrAnnotationsChangedPredicate{})
 	if err != nil {
 		return err
 	}
@@ -78,6 +128,23 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Watch for changes to the toolchain ConfigMap, refresh the configuration and requeue the UserSignups which
+	// are not complete yet, since the new configuration may apply to them (eg: the approval policy)
+	err = c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: toolchainConfigMapper{client: mgr.GetClient(), configLoader: r.configLoader},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for deletions of MasterUserRecords and requeue the waitlisted UserSignups, since some capacity was freed
+	err = c.Watch(&source.Kind{Type: &toolchainv1alpha1.MasterUserRecord{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: waitlistMapper{client: mgr.GetClient()},
+	}, predicate.OnDeletePredicate{})
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -101,8 +168,13 @@ func (err SignupError) Error() string {
 type ReconcileUserSignup struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	client client.Client
-	scheme *runtime.Scheme
+	client    client.Client
+	scheme    *runtime.Scheme
+	placement *placement.Placement
+	// configLoader loads the toolchain configuration, and caches it until the ConfigMap changes (shared by all the controllers)
+	configLoader *config.Loader
+	// newNotifier returns the notifier to use in the given namespace, or nil if no notification should be sent
+	newNotifier func(cl client.Client, namespace string, cfg config.ToolchainConfig) (notification.Notifier, error)
 }
 
 // Reconcile reads that state of the cluster for a UserSignup object and makes changes based on the state read
@@ -137,6 +209,45 @@ func (r *ReconcileUserSignup) Reconcile(request reconcile.Request) (reconcile.Re
 	}
 
 	murs := murList.Items
+	// If the user is banned, then record the user in the registry of banned users, so that further signups are refused.
+	// Rejected or banned users are never provisioned: the MasterUserRecord (if any) is deleted but the UserSignup is kept.
+	if isBanned(instance) {
+		reqLogger.Info("Banning user")
+		if err := banneduser.Ban(r.client, instance.Namespace, instance.Name, instance.Annotations[userEmailAnnotationKey]); err != nil {
+			return reconcile.Result{}, r.wrapErrorWithStatusUpdate(reqLogger, instance, r.setStatusFailedToBan, err, "Error banning user")
+		}
+		return reconcile.Result{}, r.tearDown(reqLogger, instance, murs, r.setStatusBanned, r.setStatusFailedToTearDown)
+	} else if isRejected(instance) {
+		return reconcile.Result{}, r.tearDown(reqLogger, instance, murs, r.setStatusRejected, r.setStatusFailedToTearDown)
+	} else if wasBanned(instance) {
+		// the user was banned, but the annotation was removed since then: remove the user from the registry of banned users
+		reqLogger.Info("Unbanning user")
+		if err := banneduser.Unban(r.client, instance.Namespace, instance.Name); err != nil {
+			return reconcile.Result{}, r.wrapErrorWithStatusUpdate(reqLogger, instance, r.setStatusFailedToUnban, err, "Error unbanning user")
+		}
+	}
+	banned, err := banneduser.IsBanned(r.client, instance.Namespace, instance.Name, instance.Annotations[userEmailAnnotationKey])
+	if err != nil {
+		return reconcile.Result{}, r.wrapErrorWithStatusUpdate(reqLogger, instance, r.setStatusFailedToCheckBannedUsers, err, "Error checking banned users")
+	} else if banned {
+		reqLogger.Info("Refusing signup from a banned user")
+		return reconcile.Result{}, r.tearDown(reqLogger, instance, murs, r.setStatusBannedIdentity, r.setStatusFailedToTearDown)
+	}
+
+	// If the user is deactivated, then delete the MasterUserRecord (if any) but keep the UserSignup
+	if isDeactivated(instance) {
+		return reconcile.Result{}, r.deactivate(reqLogger, instance, murs)
+	} else if condition, found := findCondition(instance.Status.Conditions, userSignupDeactivated); found && condition.Status == corev1.ConditionTrue {
+		reqLogger.Info("Reactivating user")
+		if statusError := r.updateStatus(reqLogger, instance, r.setStatusReactivated); statusError != nil {
+			return reconcile.Result{}, statusError
+		}
+		// the lifetime of the account starts again when the user is provisioned, and the notifications are sent again
+		if err := r.resetLifecycleAnnotations(instance); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
 	// If we found more than one MasterUserRecord, then die
 	if len(murs) > 1 {
 		err = NewSignupError("multiple matching MasterUserRecord resources found")
@@ -145,62 +256,143 @@ func (r *ReconcileUserSignup) Reconcile(request reconcile.Request) (reconcile.Re
 		// If we successfully found an existing MasterUserRecord then our work here is done, set the status
 		// to Complete and return
 		mur := murs[0]
+		if mur.DeletionTimestamp != nil {
+			// the MasterUserRecord of a user who was deactivated is being deleted: wait until it is gone
+			// before provisioning a new one (the deletion of the MasterUserRecord will trigger a new reconcile)
+			reqLogger.Info("MasterUserRecord is being deleted", "MasterUserRecord", mur.Name)
+			return reconcile.Result{}, nil
+		}
 		reqLogger.Info("MasterUserRecord exists, setting status to Complete")
 		instance.Status.CompliantUsername = mur.Name
-		return reconcile.Result{}, r.updateStatus(reqLogger, instance, r.setStatusComplete)
+		if statusError := r.updateStatus(reqLogger, instance, r.setStatusComplete); statusError != nil {
+			return reconcile.Result{}, statusError
+		}
+		if err := r.notifyProvisioning(reqLogger, instance, mur); err != nil {
+			return reconcile.Result{}, err
+		}
+		return r.manageExpiry(reqLogger, instance, mur)
 	}
 
-	// Check the user approval policy.
-	userApprovalPolicy, err := r.ReadUserApprovalPolicyConfig(request.Namespace)
+	// Load the toolchain configuration and check the user approval policy.
+	cfg, err := r.configLoader.Load(request.Namespace)
 	if err != nil {
 		return reconcile.Result{}, r.wrapErrorWithStatusUpdate(reqLogger, instance, r.setStatusFailedToReadUserApprovalPolicy, err, "")
 	}
+	userApprovalPolicy := cfg.UserApprovalPolicy()
+
+	// Unless the signup has been explicitly approved (by an admin), the email domain of the user may be denied
+	// or allowed, regardless of the user approval policy
+	var allowedDomain string
+	if !instance.Spec.Approved {
+		email := instance.Annotations[userEmailAnnotationKey]
+		if deniedDomain, found := matchingDomain(email, cfg.AutoApprovalDeniedDomains()); found {
+			reqLogger.Info("Rejecting signup with a denied email domain", "Domain", deniedDomain)
+			return reconcile.Result{}, r.setStatusRejectedByDomain(instance, fmt.Sprintf("email domain '%s' is denied", deniedDomain))
+		}
+		allowedDomain, _ = matchingDomain(email, cfg.AutoApprovalAllowedDomains())
+	}
 
-	// If the signup has been explicitly approved (by an admin), or the user approval policy is set to automatic,
-	// then proceed with the signup
-	if instance.Spec.Approved || userApprovalPolicy == config.UserApprovalPolicyAutomatic {
+	// If the signup has been explicitly approved (by an admin), or the email domain of the user is allowed,
+	// or the user approval policy is set to automatic, then proceed with the signup
+	if instance.Spec.Approved || allowedDomain != "" || userApprovalPolicy == config.UserApprovalPolicyAutomatic {
 		if instance.Spec.Approved {
 			if statusError := r.updateStatus(reqLogger, instance, r.setStatusApprovedByAdmin); statusError != nil {
 				return reconcile.Result{}, statusError
 			}
+		} else if allowedDomain != "" {
+			if statusError := r.setStatusApprovedByDomain(instance, fmt.Sprintf("email domain '%s' is allowed", allowedDomain)); statusError != nil {
+				reqLogger.Error(statusError, "status update failed")
+				return reconcile.Result{}, statusError
+			}
 		} else {
 			if statusError := r.updateStatus(reqLogger, instance, r.setStatusApprovedAutomatically); statusError != nil {
 				return reconcile.Result{}, statusError
 			}
 		}
 
-		var targetCluster string
+		// The signups are provisioned in the order of the waitlist, as long as the maximum number of active users
+		// is not reached
+		waitlistedBefore, err := r.waitlistedBefore(instance)
+		if err != nil {
+			return reconcile.Result{}, r.wrapErrorWithStatusUpdate(reqLogger, instance, r.setStatusInvalidMURState, err, "Failed to list waitlisted UserSignups")
+		}
+		maxActiveUsers, err := cfg.MaxActiveUsers()
+		if err != nil {
+			return reconcile.Result{}, r.wrapErrorWithStatusUpdate(reqLogger, instance, r.setStatusFailedToReadToolchainConfig, err, "")
+		}
+		if maxActiveUsers > 0 {
+			activeUsers, err := r.countActiveUsers(request.Namespace)
+			if err != nil {
+				return reconcile.Result{}, r.wrapErrorWithStatusUpdate(reqLogger, instance, r.setStatusInvalidMURState, err, "Failed to list MasterUserRecords")
+			}
+			if activeUsers+waitlistedBefore >= maxActiveUsers {
+				return reconcile.Result{}, r.waitlist(reqLogger, instance, waitlistedBefore+1)
+			}
+		}
+
+		var targetClusters []string
 
-		// If a target cluster hasn't been selected, select one from the members
+		// If a target cluster hasn't been selected, select one from the members (or several, if the placement
+		// rule declares several accounts for the user)
 		if instance.Spec.TargetCluster != "" {
-			targetCluster = instance.Spec.TargetCluster
+			targetClusters = []string{instance.Spec.TargetCluster}
 		} else {
 			// Automatic cluster selection
-			members := cluster.GetMemberClusters()
-			if len(members) > 0 {
-				targetCluster = members[0].Name
-			} else {
+			maxUserAccounts, err := cfg.MaxUsersPerCluster()
+			if err != nil {
+				return reconcile.Result{}, r.wrapErrorWithStatusUpdate(reqLogger, instance, r.setStatusFailedToReadToolchainConfig, err, "")
+			}
+			rules, err := placement.ParseRules(cfg.PlacementRules())
+			if err != nil {
+				return reconcile.Result{}, r.wrapErrorWithStatusUpdate(reqLogger, instance, r.setStatusFailedToReadToolchainConfig, err, "")
+			}
+			// the placement rules are consulted first, then the cluster is selected automatically if none matches
+			rule := placement.MatchRule(rules, instance.Annotations[userEmailAnnotationKey], instance.Annotations)
+			targetClusters, err = r.placement.SelectTargetClusters(request.Namespace, maxUserAccounts, rule)
+			if err == placement.ErrNoMemberClusters {
 				reqLogger.Error(err, "No member clusters found")
 				if statusError := r.updateStatus(reqLogger, instance, r.setStatusNoClustersAvailable); statusError != nil {
 					return reconcile.Result{}, statusError
 				}
-
-				err = NewSignupError("no target clusters available")
-				return reconcile.Result{}, err
+				return reconcile.Result{}, NewSignupError("no target clusters available")
+			} else if err == placement.ErrNoCapacity {
+				// the signup is provisioned when a MasterUserRecord is deleted, in the order of the waitlist
+				return reconcile.Result{}, r.waitlistForCapacity(reqLogger, instance, waitlistedBefore+1)
+			} else if err == placement.ErrNoMatchingCluster {
+				reqLogger.Error(err, "No member cluster matching the placement rule", "rule", rule.Name)
+				if statusError := r.updateStatus(reqLogger, instance, r.setStatusNoMatchingClusterAvailable); statusError != nil {
+					return reconcile.Result{}, statusError
+				}
+				return reconcile.Result{}, NewSignupError(fmt.Sprintf("no target cluster matching the placement rule '%s'", rule.Name))
+			} else if err != nil {
+				return reconcile.Result{}, r.wrapErrorWithStatusUpdate(reqLogger, instance, r.setStatusNoClustersAvailable, err,
+					"unable to select a target cluster")
+			}
+			if rule != nil {
+				reqLogger.Info("Target cluster selected with placement rule", "rule", rule.Name, "TargetClusters", targetClusters)
+				if err := r.setPlacementRuleAnnotation(instance, rule.Name); err != nil {
+					return reconcile.Result{}, r.wrapErrorWithStatusUpdate(reqLogger, instance, r.setStatusFailedToCreateMUR, err,
+						"unable to record the placement rule")
+				}
 			}
 		}
-		// look-up the `basic` NSTemplateTier to get the NS templates
+		tierName, requested := selectTierName(reqLogger, instance, cfg)
+		// look-up the NSTemplateTier to get the NS templates
 		var nstemplateTier toolchainv1alpha1.NSTemplateTier
-		err := r.client.Get(context.TODO(), types.NamespacedName{
+		err = r.client.Get(context.TODO(), types.NamespacedName{
 			Namespace: request.Namespace, // assume that NSTemplateTier were created in the same NS as Usersignups
-			Name:      "basic",
+			Name:      tierName,
 		}, &nstemplateTier)
-		if err != nil {
+		if requested && errors.IsNotFound(err) {
+			// the requested tier will not be created by the operator: don't requeue until the request is changed
+			reqLogger.Info("Requested NSTemplateTier not found", "RequestedTier", tierName)
+			return reconcile.Result{}, r.setStatusRequestedTierNotFound(instance, fmt.Sprintf("the requested NSTemplateTier '%s' does not exist", tierName))
+		} else if err != nil {
 			// let's requeue until the NSTemplateTier resource is available
 			return reconcile.Result{Requeue: true}, r.wrapErrorWithStatusUpdate(reqLogger, instance, r.setStatusNoTemplateTierAvailable, err, "")
 		}
 		// Provision the MasterUserRecord
-		err = r.provisionMasterUserRecord(instance, targetCluster, nstemplateTier, reqLogger)
+		err = r.provisionMasterUserRecord(instance, targetClusters, nstemplateTier, reqLogger)
 		if err != nil {
 			return reconcile.Result{}, err
 		}
@@ -211,61 +403,246 @@ func (r *ReconcileUserSignup) Reconcile(request reconcile.Request) (reconcile.Re
 	return reconcile.Result{}, nil
 }
 
-func (r *ReconcileUserSignup) generateCompliantUsername(instance *toolchainv1alpha1.UserSignup) (string, error) {
-	replaced := strings.ReplaceAll(strings.ReplaceAll(instance.Spec.Username, "@", "-at-"), ".", "-")
+// deactivate deletes the MasterUserRecords of the user (the UserAccounts in the member clusters are then deleted
+// by the MasterUserRecord finalizer) while keeping the UserSignup, so that its compliant username remains reserved
+func (r *ReconcileUserSignup) deactivate(logger logr.Logger, userSignup *toolchainv1alpha1.UserSignup, murs []toolchainv1alpha1.MasterUserRecord) error {
+	if err := r.tearDown(logger, userSignup, murs, r.setStatusDeactivated, r.setStatusFailedToDeactivate); err != nil {
+		return err
+	}
+	return r.notify(logger, userSignup, notification.Deactivated, notification.Context{
+		Username: userSignup.Status.CompliantUsername,
+	})
+}
+
+// tearDown deletes the given MasterUserRecords of the user and then updates the status of the UserSignup
+// with the given status updater (or with the given failure status updater if a MasterUserRecord cannot be deleted)
+func (r *ReconcileUserSignup) tearDown(logger logr.Logger, userSignup *toolchainv1alpha1.UserSignup, murs []toolchainv1alpha1.MasterUserRecord,
+	statusUpdater, failureStatusUpdater func(userAcc *toolchainv1alpha1.UserSignup, message string) error) error {
+	for i := range murs {
+		mur := &murs[i]
+		if mur.DeletionTimestamp != nil {
+			continue
+		}
+		logger.Info("Deleting MasterUserRecord", "MasterUserRecord", mur.Name)
+		if err := r.client.Delete(context.TODO(), mur); err != nil && !errors.IsNotFound(err) {
+			return r.wrapErrorWithStatusUpdate(logger, userSignup, failureStatusUpdater, err,
+				"Error deleting MasterUserRecord %s", mur.Name)
+		}
+	}
+	return r.updateStatus(logger, userSignup, statusUpdater)
+}
 
-	errs := validation.IsQualifiedName(replaced)
-	if len(errs) > 0 {
-		return "", NewSignupError(fmt.Sprintf("transformed username [%s] is invalid", replaced))
+// notifyProvisioning notifies the user that the signup was approved and, once the MasterUserRecord is ready,
+// that the account was provisioned
+func (r *ReconcileUserSignup) notifyProvisioning(logger logr.Logger, userSignup *toolchainv1alpha1.UserSignup, mur toolchainv1alpha1.MasterUserRecord) error {
+	ctx := notification.Context{
+		Username:   mur.Name,
+		ExpiryTime: userSignup.Annotations[expiryAnnotationKey],
+	}
+	if approved, found := findCondition(userSignup.Status.Conditions, toolchainv1alpha1.UserSignupApproved); found && approved.Status == corev1.ConditionTrue {
+		if err := r.notify(logger, userSignup, notification.Approved, ctx); err != nil {
+			return err
+		}
+	}
+	if ready, found := findCondition(mur.Status.Conditions, toolchainv1alpha1.ConditionReady); found && ready.Status == corev1.ConditionTrue {
+		return r.notify(logger, userSignup, notification.Provisioned, ctx)
 	}
+	return nil
+}
 
-	transformed := replaced
+// notify sends the notification of the given type to the user, unless it was already sent (according to the
+// UserSignup annotations), no notifier is configured or the email address of the user is unknown.
+// Each notification is also recorded in a ConfigMap owned by the UserSignup, for auditing purposes (the ConfigMaps
+// stand for the Notification resources until such a custom resource is available in the toolchain API).
+// The notification is recorded (in the ConfigMap and in the annotations) before it is sent, so that it is never
+// sent twice, even if the UserSignup cannot be updated afterwards.
+func (r *ReconcileUserSignup) notify(logger logr.Logger, userSignup *toolchainv1alpha1.UserSignup, notificationType notification.Type, ctx notification.Context) error {
+	notifiedAnnotationKey := notifiedAnnotationKeyPrefix + string(notificationType)
+	if _, notified := userSignup.Annotations[notifiedAnnotationKey]; notified || r.newNotifier == nil {
+		return nil
+	}
+	recipient := userSignup.Annotations[userEmailAnnotationKey]
+	if recipient == "" {
+		return nil
+	}
+	cfg, err := r.configLoader.Load(userSignup.Namespace)
+	if err != nil {
+		return err
+	}
+	notifier, err := r.newNotifier(r.client, userSignup.Namespace, cfg)
+	if err != nil || notifier == nil {
+		return err
+	}
+	n, err := notification.New(notificationType, recipient, ctx)
+	if err != nil {
+		return err
+	}
 
-	for i := 1; i < 101; i++ { // No more than 100 attempts to find a vacant name
-		mur := &toolchainv1alpha1.MasterUserRecord{}
-		// Check if a MasterUserRecord exists with the same transformed name
-		namespacedMurName := types.NamespacedName{Namespace: instance.Namespace, Name: transformed}
-		err := r.client.Get(context.TODO(), namespacedMurName, mur)
-		if err != nil {
-			if !errors.IsNotFound(err) {
-				return "", err
-			}
-			// If there was a NotFound error looking up the mur, it means we found an available name
-			return transformed, nil
-		} else if mur.Labels[toolchainv1alpha1.MasterUserRecordUserIDLabelKey] == instance.Name {
-			// If the found MUR has the same UserID as the UserSignup, then *it* is the correct MUR -
-			// Return an error here and allow the reconcile() function to pick it up on the next loop
-			return "", NewSignupError(fmt.Sprintf("could not generate compliant username as MasterUserRecord [%s] already exists", mur.Name))
+	sent := time.Now().UTC()
+	record, err := r.recordNotification(userSignup, n, sent)
+	if err != nil {
+		return errs.Wrapf(err, "unable to record the '%s' notification", notificationType)
+	}
+	userSignup.Annotations[notifiedAnnotationKey] = sent.Format(time.RFC3339)
+	if err := r.client.Update(context.TODO(), userSignup); err != nil {
+		r.deleteNotificationRecord(logger, record)
+		return errs.Wrapf(err, "unable to record the '%s' notification", notificationType)
+	}
+
+	if err := notifier.Notify(n); err != nil {
+		// the notification was not sent: forget about it, so that it is sent again on the next reconcile
+		r.deleteNotificationRecord(logger, record)
+		delete(userSignup.Annotations, notifiedAnnotationKey)
+		if updateErr := r.client.Update(context.TODO(), userSignup); updateErr != nil {
+			logger.Error(updateErr, "Unable to remove the annotation of the notification which was not sent", "Type", notificationType)
+		}
+		return errs.Wrapf(err, "unable to send the '%s' notification", notificationType)
+	}
+	logger.Info("Notification sent", "Type", notificationType)
+	return nil
+}
+
+// deleteNotificationRecord deletes the ConfigMap which recorded a notification that was eventually not sent
+func (r *ReconcileUserSignup) deleteNotificationRecord(logger logr.Logger, record *corev1.ConfigMap) {
+	if err := r.client.Delete(context.TODO(), record); err != nil && !errors.IsNotFound(err) {
+		logger.Error(err, "Unable to delete the record of the notification which was not sent", "ConfigMap", record.Name)
+	}
+}
+
+// recordNotification creates a ConfigMap with the content of the notification which is sent to the user
+func (r *ReconcileUserSignup) recordNotification(userSignup *toolchainv1alpha1.UserSignup, n notification.Notification, sent time.Time) (*corev1.ConfigMap, error) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s-%d", userSignup.Name, n.Type, sent.Unix()),
+			Namespace: userSignup.Namespace,
+			Labels: map[string]string{
+				notificationTypeLabelKey: string(n.Type),
+				userSignupLabelKey:       userSignup.Name,
+			},
+		},
+		Data: map[string]string{
+			"recipient": n.Recipient,
+			"subject":   n.Subject,
+			"body":      n.Body,
+			"sent":      sent.Format(time.RFC3339),
+		},
+	}
+	if err := controllerutil.SetControllerReference(userSignup, cm, r.scheme); err != nil {
+		return nil, err
+	}
+	return cm, r.client.Create(context.TODO(), cm)
+}
+
+// manageExpiry deactivates the user if the lifetime of its account is over. Otherwise, it records the provisioning
+// and the expiry times in the UserSignup annotations (if not done yet) and in its Expires condition, and requeues the
+// request until the expiry. When the provisioning time was not recorded yet (eg: for a user provisioned before the
+// lifetime was configured), the creation time of the MasterUserRecord is used.
+// Nothing is done if no lifetime was configured for the user's tier.
+func (r *ReconcileUserSignup) manageExpiry(logger logr.Logger, userSignup *toolchainv1alpha1.UserSignup, mur toolchainv1alpha1.MasterUserRecord) (reconcile.Result, error) {
+	var tierName string
+	if len(mur.Spec.UserAccounts) > 0 {
+		tierName = mur.Spec.UserAccounts[0].Spec.NSTemplateSet.TierName
+	}
+	cfg, err := r.configLoader.Load(userSignup.Namespace)
+	if err != nil {
+		return reconcile.Result{}, r.wrapErrorWithStatusUpdate(logger, userSignup, r.setStatusFailedToReadToolchainConfig, err, "")
+	}
+	lifetime, err := cfg.UserLifetime(tierName)
+	if err != nil {
+		return reconcile.Result{}, r.wrapErrorWithStatusUpdate(logger, userSignup, r.setStatusFailedToReadToolchainConfig, err, "")
+	}
+	if lifetime == 0 {
+		if condition, found := findCondition(userSignup.Status.Conditions, userSignupExpires); found && condition.Status == corev1.ConditionTrue {
+			return reconcile.Result{}, r.updateStatus(logger, userSignup, r.setStatusNoExpiry)
+		}
+		return reconcile.Result{}, nil
+	}
+
+	provisioned := mur.CreationTimestamp.Time
+	if provisioned.IsZero() {
+		provisioned = time.Now()
+	}
+	if value, found := userSignup.Annotations[provisionedAnnotationKey]; found {
+		if provisioned, err = time.Parse(time.RFC3339, value); err != nil {
+			return reconcile.Result{}, errs.Wrapf(err, "invalid value for the '%s' annotation", provisionedAnnotationKey)
+		}
+	}
+	expiry := provisioned.Add(lifetime)
+	if time.Now().After(expiry) {
+		logger.Info("Account expired, deactivating user", "ExpiryTime", expiry)
+		if userSignup.Annotations == nil {
+			userSignup.Annotations = map[string]string{}
 		}
+		userSignup.Annotations[deactivatedAnnotationKey] = "true"
+		if err := r.client.Update(context.TODO(), userSignup); err != nil {
+			return reconcile.Result{}, r.wrapErrorWithStatusUpdate(logger, userSignup, r.setStatusFailedToDeactivate, err,
+				"Error deactivating expired user")
+		}
+		return reconcile.Result{}, r.deactivate(logger, userSignup, []toolchainv1alpha1.MasterUserRecord{mur})
+	}
 
-		transformed = fmt.Sprintf("%s-%d", replaced, i)
+	provisionedValue := provisioned.UTC().Format(time.RFC3339)
+	expiryValue := expiry.UTC().Format(time.RFC3339)
+	if userSignup.Annotations[provisionedAnnotationKey] != provisionedValue || userSignup.Annotations[expiryAnnotationKey] != expiryValue {
+		if userSignup.Annotations == nil {
+			userSignup.Annotations = map[string]string{}
+		}
+		userSignup.Annotations[provisionedAnnotationKey] = provisionedValue
+		userSignup.Annotations[expiryAnnotationKey] = expiryValue
+		if err := r.client.Update(context.TODO(), userSignup); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	logger.Info("Account will expire", "ExpiryTime", expiryValue)
+	if err := r.setStatusExpires(userSignup, fmt.Sprintf("the account expires at %s", expiryValue)); err != nil {
+		logger.Error(err, "status update failed")
+		return reconcile.Result{}, err
 	}
 
-	return "", NewSignupError(fmt.Sprintf("unable to transform username [%s] even after 100 attempts", instance.Spec.Username))
+	// notify the user when the expiry is near, or requeue until then
+	warning, err := cfg.NotificationExpiryWarning()
+	if err != nil {
+		return reconcile.Result{}, r.wrapErrorWithStatusUpdate(logger, userSignup, r.setStatusFailedToReadToolchainConfig, err, "")
+	}
+	if warning > 0 {
+		if warningTime := expiry.Add(-warning); time.Now().Before(warningTime) {
+			return reconcile.Result{RequeueAfter: time.Until(warningTime)}, nil
+		}
+		if err := r.notify(logger, userSignup, notification.Expiring, notification.Context{Username: mur.Name, ExpiryTime: expiryValue}); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	return reconcile.Result{RequeueAfter: time.Until(expiry)}, nil
 }
 
-// provisionMasterUserRecord does the work of provisioning the MasterUserRecord
-func (r *ReconcileUserSignup) provisionMasterUserRecord(userSignup *toolchainv1alpha1.UserSignup, targetCluster string, nstemplateTier toolchainv1alpha1.NSTemplateTier, logger logr.Logger) error {
-	namespaces := make([]toolchainv1alpha1.NSTemplateSetNamespace, len(nstemplateTier.Spec.Namespaces))
-	for i, ns := range nstemplateTier.Spec.Namespaces {
-		namespaces[i] = toolchainv1alpha1.NSTemplateSetNamespace{
-			Type:     ns.Type,
-			Revision: ns.Revision,
+// resetLifecycleAnnotations removes the provisioning and expiry times, along with the times at which the notifications
+// were sent, from the UserSignup annotations
+func (r *ReconcileUserSignup) resetLifecycleAnnotations(userSignup *toolchainv1alpha1.UserSignup) error {
+	var updated bool
+	for key := range userSignup.Annotations {
+		if key == provisionedAnnotationKey || key == expiryAnnotationKey || strings.HasPrefix(key, notifiedAnnotationKeyPrefix) {
+			delete(userSignup.Annotations, key)
+			updated = true
 		}
 	}
+	if !updated {
+		return nil
+	}
+	return r.client.Update(context.TODO(), userSignup)
+}
 
-	userAccounts := []toolchainv1alpha1.UserAccountEmbedded{
-		{
+// provisionMasterUserRecord does the work of provisioning the MasterUserRecord, with a UserAccount in each of the target clusters
+func (r *ReconcileUserSignup) provisionMasterUserRecord(userSignup *toolchainv1alpha1.UserSignup, targetClusters []string, nstemplateTier toolchainv1alpha1.NSTemplateTier, logger logr.Logger) error {
+	userAccounts := make([]toolchainv1alpha1.UserAccountEmbedded, len(targetClusters))
+	for i, targetCluster := range targetClusters {
+		userAccounts[i] = toolchainv1alpha1.UserAccountEmbedded{
 			TargetCluster: targetCluster,
 			Spec: toolchainv1alpha1.UserAccountSpec{
-				UserID:  userSignup.Name,
-				NSLimit: "default",
-				NSTemplateSet: toolchainv1alpha1.NSTemplateSetSpec{
-					TierName:   nstemplateTier.Name,
-					Namespaces: namespaces,
-				},
+				UserID:        userSignup.Name,
+				NSLimit:       "default",
+				NSTemplateSet: nstemplatetiers.NewNSTemplateSet(nstemplateTier),
 			},
-		},
+		}
 	}
 
 	// TODO Update the MasterUserRecord with NSTemplateTier values
@@ -302,27 +679,53 @@ func (r *ReconcileUserSignup) provisionMasterUserRecord(userSignup *toolchainv1a
 			"Error creating MasterUserRecord")
 	}
 
-	logger.Info("Created MasterUserRecord", "Name", mur.Name, "TargetCluster", targetCluster)
+	logger.Info("Created MasterUserRecord", "Name", mur.Name, "TargetClusters", targetClusters)
 	return nil
 }
 
-// ReadUserApprovalPolicyConfig reads the ConfigMap for the toolchain configuration in the operator namespace, and returns
-// the config map value for the user approval policy (which will either be "manual" or "automatic")
+// ReadUserApprovalPolicyConfig returns the user approval policy of the toolchain configuration in the operator namespace
+// (which will either be "manual" or "automatic")
 func (r *ReconcileUserSignup) ReadUserApprovalPolicyConfig(namespace string) (string, error) {
-	cm := &corev1.ConfigMap{}
-	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: config.ToolchainConfigMapName}, cm)
+	cfg, err := r.configLoader.Load(namespace)
 	if err != nil {
-		if errors.IsNotFound(err) {
-			return config.UserApprovalPolicyManual, nil
-		}
 		return "", err
 	}
+	return cfg.UserApprovalPolicy(), nil
+}
+
+// selectTierName returns the name of the NSTemplateTier to use when provisioning the user. This is the tier
+// requested via the `toolchain.dev.openshift.com/requested-tier` annotation if the signup was approved by an
+// admin or if the tier is in the list of the user-selectable tiers, otherwise it is the default tier.
+// The returned flag is true if the requested tier was selected.
+func selectTierName(logger logr.Logger, userSignup *toolchainv1alpha1.UserSignup, cfg config.ToolchainConfig) (string, bool) {
+	defaultTier := cfg.DefaultTier()
+	requestedTier := userSignup.Annotations[requestedTierAnnotationKey]
+	if requestedTier == "" || requestedTier == defaultTier {
+		return defaultTier, false
+	}
+	if userSignup.Spec.Approved {
+		return requestedTier, true
+	}
+	for _, tier := range cfg.UserSelectableTiers() {
+		if tier == requestedTier {
+			return requestedTier, true
+		}
+	}
+	logger.Info("Requested tier is not allowed without an approval by an admin, using the default tier instead",
+		"RequestedTier", requestedTier, "DefaultTier", defaultTier)
+	return defaultTier, false
+}
 
-	val, ok := cm.Data[config.ToolchainConfigMapUserApprovalPolicy]
-	if !ok {
-		return "", nil
+// setPlacementRuleAnnotation records the name of the placement rule which was used to select the target cluster
+func (r *ReconcileUserSignup) setPlacementRuleAnnotation(userSignup *toolchainv1alpha1.UserSignup, ruleName string) error {
+	if userSignup.Annotations[placementRuleAnnotationKey] == ruleName {
+		return nil
+	}
+	if userSignup.Annotations == nil {
+		userSignup.Annotations = map[string]string{}
 	}
-	return val, nil
+	userSignup.Annotations[placementRuleAnnotationKey] = ruleName
+	return r.client.Update(context.TODO(), userSignup)
 }
 
 func (r *ReconcileUserSignup) setStatusApprovedAutomatically(userSignup *toolchainv1alpha1.UserSignup, message string) error {
@@ -347,6 +750,34 @@ func (r *ReconcileUserSignup) setStatusApprovedByAdmin(userSignup *toolchainv1al
 		})
 }
 
+func (r *ReconcileUserSignup) setStatusApprovedByDomain(userSignup *toolchainv1alpha1.UserSignup, message string) error {
+	return r.updateStatusConditions(
+		userSignup,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.UserSignupApproved,
+			Status:  corev1.ConditionTrue,
+			Reason:  approvedAutomaticallyByDomainReason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileUserSignup) setStatusRejectedByDomain(userSignup *toolchainv1alpha1.UserSignup, message string) error {
+	return r.updateStatusConditions(
+		userSignup,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.UserSignupApproved,
+			Status:  corev1.ConditionFalse,
+			Reason:  rejectedAutomaticallyByDomainReason,
+			Message: message,
+		},
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.UserSignupComplete,
+			Status:  corev1.ConditionFalse,
+			Reason:  rejectedAutomaticallyByDomainReason,
+			Message: message,
+		})
+}
+
 func (r *ReconcileUserSignup) setStatusPendingApproval(userSignup *toolchainv1alpha1.UserSignup, message string) error {
 	return r.updateStatusConditions(
 		userSignup,
@@ -408,6 +839,39 @@ func (r *ReconcileUserSignup) setStatusNoClustersAvailable(userSignup *toolchain
 		})
 }
 
+func (r *ReconcileUserSignup) setStatusWaitlisted(userSignup *toolchainv1alpha1.UserSignup, message string) error {
+	return r.updateStatusConditions(
+		userSignup,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.UserSignupComplete,
+			Status:  corev1.ConditionFalse,
+			Reason:  waitlistedReason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileUserSignup) setStatusNoMatchingClusterAvailable(userSignup *toolchainv1alpha1.UserSignup, message string) error {
+	return r.updateStatusConditions(
+		userSignup,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.UserSignupComplete,
+			Status:  corev1.ConditionFalse,
+			Reason:  noMatchingClusterAvailableReason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileUserSignup) setStatusFailedToReadToolchainConfig(userSignup *toolchainv1alpha1.UserSignup, message string) error {
+	return r.updateStatusConditions(
+		userSignup,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.UserSignupComplete,
+			Status:  corev1.ConditionFalse,
+			Reason:  failedToReadToolchainConfigReason,
+			Message: message,
+		})
+}
+
 func (r *ReconcileUserSignup) setStatusNoTemplateTierAvailable(userSignup *toolchainv1alpha1.UserSignup, message string) error {
 	return r.updateStatusConditions(
 		userSignup,
@@ -419,6 +883,151 @@ func (r *ReconcileUserSignup) setStatusNoTemplateTierAvailable(userSignup *toolc
 		})
 }
 
+func (r *ReconcileUserSignup) setStatusRequestedTierNotFound(userSignup *toolchainv1alpha1.UserSignup, message string) error {
+	return r.updateStatusConditions(
+		userSignup,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.UserSignupComplete,
+			Status:  corev1.ConditionFalse,
+			Reason:  requestedTierNotFoundReason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileUserSignup) setStatusDeactivated(userSignup *toolchainv1alpha1.UserSignup, message string) error {
+	return r.updateStatusConditions(
+		userSignup,
+		toolchainv1alpha1.Condition{
+			Type:    userSignupDeactivated,
+			Status:  corev1.ConditionTrue,
+			Reason:  deactivatedReason,
+			Message: message,
+		},
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.UserSignupComplete,
+			Status:  corev1.ConditionFalse,
+			Reason:  deactivatedReason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileUserSignup) setStatusReactivated(userSignup *toolchainv1alpha1.UserSignup, message string) error {
+	return r.updateStatusConditions(
+		userSignup,
+		toolchainv1alpha1.Condition{
+			Type:    userSignupDeactivated,
+			Status:  corev1.ConditionFalse,
+			Reason:  reactivatedReason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileUserSignup) setStatusExpires(userSignup *toolchainv1alpha1.UserSignup, message string) error {
+	return r.updateStatusConditions(
+		userSignup,
+		toolchainv1alpha1.Condition{
+			Type:    userSignupExpires,
+			Status:  corev1.ConditionTrue,
+			Reason:  lifetimeLimitedReason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileUserSignup) setStatusNoExpiry(userSignup *toolchainv1alpha1.UserSignup, message string) error {
+	return r.updateStatusConditions(
+		userSignup,
+		toolchainv1alpha1.Condition{
+			Type:    userSignupExpires,
+			Status:  corev1.ConditionFalse,
+			Reason:  noLifetimeLimitReason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileUserSignup) setStatusFailedToDeactivate(userSignup *toolchainv1alpha1.UserSignup, message string) error {
+	return r.updateStatusConditions(
+		userSignup,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.UserSignupComplete,
+			Status:  corev1.ConditionFalse,
+			Reason:  unableToDeactivateReason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileUserSignup) setStatusRejected(userSignup *toolchainv1alpha1.UserSignup, message string) error {
+	return r.setStatusNotApproved(userSignup, rejectedReason, message)
+}
+
+func (r *ReconcileUserSignup) setStatusBanned(userSignup *toolchainv1alpha1.UserSignup, message string) error {
+	return r.setStatusNotApproved(userSignup, bannedReason, message)
+}
+
+func (r *ReconcileUserSignup) setStatusBannedIdentity(userSignup *toolchainv1alpha1.UserSignup, message string) error {
+	return r.setStatusNotApproved(userSignup, bannedIdentityReason, message)
+}
+
+func (r *ReconcileUserSignup) setStatusNotApproved(userSignup *toolchainv1alpha1.UserSignup, reason, message string) error {
+	return r.updateStatusConditions(
+		userSignup,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.UserSignupApproved,
+			Status:  corev1.ConditionFalse,
+			Reason:  reason,
+			Message: message,
+		},
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.UserSignupComplete,
+			Status:  corev1.ConditionFalse,
+			Reason:  reason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileUserSignup) setStatusFailedToBan(userSignup *toolchainv1alpha1.UserSignup, message string) error {
+	return r.updateStatusConditions(
+		userSignup,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.UserSignupComplete,
+			Status:  corev1.ConditionFalse,
+			Reason:  unableToBanReason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileUserSignup) setStatusFailedToUnban(userSignup *toolchainv1alpha1.UserSignup, message string) error {
+	return r.updateStatusConditions(
+		userSignup,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.UserSignupComplete,
+			Status:  corev1.ConditionFalse,
+			Reason:  unableToUnbanReason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileUserSignup) setStatusFailedToTearDown(userSignup *toolchainv1alpha1.UserSignup, message string) error {
+	return r.updateStatusConditions(
+		userSignup,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.UserSignupComplete,
+			Status:  corev1.ConditionFalse,
+			Reason:  unableToTearDownReason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileUserSignup) setStatusFailedToCheckBannedUsers(userSignup *toolchainv1alpha1.UserSignup, message string) error {
+	return r.updateStatusConditions(
+		userSignup,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.UserSignupComplete,
+			Status:  corev1.ConditionFalse,
+			Reason:  unableToCheckBannedUsersReason,
+			Message: message,
+		})
+}
+
 func (r *ReconcileUserSignup) setStatusComplete(userSignup *toolchainv1alpha1.UserSignup, message string) error {
 	return r.updateStatusConditions(
 		userSignup,
@@ -462,3 +1071,45 @@ func (r *ReconcileUserSignup) updateStatusConditions(userSignup *toolchainv1alph
 	}
 	return r.client.Status().Update(context.TODO(), userSignup)
 }
+
+// isDeactivated returns true if the UserSignup has the `toolchain.dev.openshift.com/deactivated` annotation set to `true`
+func isDeactivated(userSignup *toolchainv1alpha1.UserSignup) bool {
+	return userSignup.Annotations[deactivatedAnnotationKey] == "true"
+}
+
+// isRejected returns true if the UserSignup has the `toolchain.dev.openshift.com/rejected` annotation set to `true`
+func isRejected(userSignup *toolchainv1alpha1.UserSignup) bool {
+	return userSignup.Annotations[rejectedAnnotationKey] == "true"
+}
+
+// isBanned returns true if the UserSignup has the `toolchain.dev.openshift.com/banned` annotation set to `true`
+func isBanned(userSignup *toolchainv1alpha1.UserSignup) bool {
+	return userSignup.Annotations[bannedAnnotationKey] == "true"
+}
+
+// wasBanned returns true if the status of the UserSignup says that the user was banned
+func wasBanned(userSignup *toolchainv1alpha1.UserSignup) bool {
+	approved, found := findCondition(userSignup.Status.Conditions, toolchainv1alpha1.UserSignupApproved)
+	return found && approved.Reason == bannedReason
+}
+
+// matchingDomain returns the first of the given domains which matches the domain of the email address
+// along with `true`, or an empty string and `false` if none matches
+func matchingDomain(email string, domains []string) (string, bool) {
+	for _, domain := range domains {
+		if placement.EmailInDomains(email, []string{domain}) {
+			return domain, true
+		}
+	}
+	return "", false
+}
+
+// findCondition returns the condition of the given type along with `true`, or an empty condition and `false` if none was found
+func findCondition(conditions []toolchainv1alpha1.Condition, conditionType toolchainv1alpha1.ConditionType) (toolchainv1alpha1.Condition, bool) {
+	for _, c := range conditions {
+		if c.Type == conditionType {
+			return c, true
+		}
+	}
+	return toolchainv1alpha1.Condition{}, false
+}
@@ -5,11 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
 	"github.com/codeready-toolchain/host-operator/pkg/apis"
+	"github.com/codeready-toolchain/host-operator/pkg/banneduser"
 	"github.com/codeready-toolchain/host-operator/pkg/config"
+	"github.com/codeready-toolchain/host-operator/pkg/notification"
+	"github.com/codeready-toolchain/host-operator/pkg/placement"
 	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
 	"github.com/codeready-toolchain/toolchain-common/pkg/test"
 
@@ -24,6 +28,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
@@ -69,6 +74,30 @@ var basicNSTemplateTier = &toolchainv1alpha1.NSTemplateTier{
 	},
 }
 
+var advancedNSTemplateTier = &toolchainv1alpha1.NSTemplateTier{
+	ObjectMeta: metav1.ObjectMeta{
+		Namespace: operatorNamespace,
+		Name:      "advanced",
+		UID:       types.UID(uuid.NewV4().String()),
+	},
+	Spec: toolchainv1alpha1.NSTemplateTierSpec{
+		Namespaces: []toolchainv1alpha1.NSTemplateTierNamespace{
+			{
+				Type:     "code",
+				Revision: "654321a",
+			},
+			{
+				Type:     "dev",
+				Revision: "654321b",
+			},
+			{
+				Type:     "stage",
+				Revision: "654321c",
+			},
+		},
+	},
+}
+
 func TestReadUserApprovalPolicy(t *testing.T) {
 	r, _, _ := prepareReconcile(t, "test", configMap(config.UserApprovalPolicyAutomatic))
 
@@ -514,7 +543,7 @@ func TestUserSignupMURCreateFails(t *testing.T) {
 		case *v1alpha1.MasterUserRecord:
 			return errors.New("unable to create mur")
 		default:
-			return clt.Create(ctx, obj)
+			return clt.Client.Create(ctx, obj, opts...)
 		}
 	}
 
@@ -777,7 +806,7 @@ func TestUserSignupWithInvalidNameNotOK(t *testing.T) {
 			UID:       types.UID(uuid.NewV4().String()),
 		},
 		Spec: v1alpha1.UserSignupSpec{
-			Username: "foo#bar@redhat.com",
+			Username: "#$%&",
 			Approved: false,
 		},
 	}
@@ -788,7 +817,7 @@ func TestUserSignupWithInvalidNameNotOK(t *testing.T) {
 	defer clearMemberClusters(r.client)
 
 	_, err := r.Reconcile(req)
-	assert.EqualError(t, err, "Error generating compliant username for foo#bar@redhat.com: transformed username [foo#bar-at-redhat-com] is invalid")
+	assert.EqualError(t, err, "Error generating compliant username for #$%&: transformed username [#$%&] is invalid")
 
 	key := types.NamespacedName{
 		Namespace: operatorNamespace,
@@ -803,7 +832,7 @@ func TestUserSignupWithInvalidNameNotOK(t *testing.T) {
 			Type:    v1alpha1.UserSignupComplete,
 			Status:  v1.ConditionFalse,
 			Reason:  "UnableToCreateMUR",
-			Message: "transformed username [foo#bar-at-redhat-com] is invalid",
+			Message: "transformed username [#$%&] is invalid",
 		},
 		v1alpha1.Condition{
 			Type:   v1alpha1.UserSignupApproved,
@@ -963,10 +992,1384 @@ func TestUserSignupNoMembersAvailableFails(t *testing.T) {
 	require.IsType(t, SignupError{}, err)
 }
 
+func TestUserSignupWithAutoApprovalSelectsLeastUtilizedCluster(t *testing.T) {
+	// given
+	userSignup := &v1alpha1.UserSignup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      uuid.NewV4().String(),
+			Namespace: operatorNamespace,
+			UID:       types.UID(uuid.NewV4().String()),
+		},
+		Spec: v1alpha1.UserSignupSpec{
+			Username: "foo@redhat.com",
+		},
+	}
+	existingMur := newMasterUserRecordInCluster("bar", nameMember)
+
+	r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, existingMur, configMap(config.UserApprovalPolicyAutomatic), basicNSTemplateTier)
+	createMemberCluster(r.client)
+	createNamedMemberCluster(r.client, "west")
+	defer clearMemberClusters(r.client)
+
+	// when
+	_, err := r.Reconcile(req)
+
+	// then
+	require.NoError(t, err)
+	mur := &v1alpha1.MasterUserRecord{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: "foo-at-redhat-com", Namespace: operatorNamespace}, mur)
+	require.NoError(t, err)
+	require.Len(t, mur.Spec.UserAccounts, 1)
+	assert.Equal(t, "west", mur.Spec.UserAccounts[0].TargetCluster)
+}
+
+func TestUserSignupWithAutoApprovalWhenAllClustersAreFull(t *testing.T) {
+	// given
+	userSignup := &v1alpha1.UserSignup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      uuid.NewV4().String(),
+			Namespace: operatorNamespace,
+			UID:       types.UID(uuid.NewV4().String()),
+		},
+		Spec: v1alpha1.UserSignupSpec{
+			Username: "foo@redhat.com",
+		},
+	}
+	existingMur := newMasterUserRecordInCluster("bar", nameMember)
+	cm := configMap(config.UserApprovalPolicyAutomatic)
+	cm.Data[config.ToolchainConfigMapMaxUsersPerCluster] = "1"
+
+	r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, existingMur, cm, basicNSTemplateTier)
+	createMemberCluster(r.client)
+	defer clearMemberClusters(r.client)
+
+	// when
+	res, err := r.Reconcile(req)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, reconcile.Result{}, res)
+	murs := &v1alpha1.MasterUserRecordList{}
+	err = r.client.List(context.TODO(), murs)
+	require.NoError(t, err)
+	require.Len(t, murs.Items, 1) // only the existing MUR
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+	require.NoError(t, err)
+	test.AssertConditionsMatch(t, userSignup.Status.Conditions,
+		v1alpha1.Condition{
+			Type:   v1alpha1.UserSignupApproved,
+			Status: v1.ConditionTrue,
+			Reason: "ApprovedAutomatically",
+		},
+		v1alpha1.Condition{
+			Type:    v1alpha1.UserSignupComplete,
+			Status:  v1.ConditionFalse,
+			Reason:  "Waitlisted",
+			Message: "waitlist position: 1, no member cluster has capacity left",
+		})
+}
+
+func TestUserSignupWithInvalidMaxUsersPerClusterConfig(t *testing.T) {
+	// given
+	userSignup := &v1alpha1.UserSignup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      uuid.NewV4().String(),
+			Namespace: operatorNamespace,
+			UID:       types.UID(uuid.NewV4().String()),
+		},
+		Spec: v1alpha1.UserSignupSpec{
+			Username: "foo@redhat.com",
+		},
+	}
+	cm := configMap(config.UserApprovalPolicyAutomatic)
+	cm.Data[config.ToolchainConfigMapMaxUsersPerCluster] = "many"
+
+	r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, cm, basicNSTemplateTier)
+	createMemberCluster(r.client)
+	defer clearMemberClusters(r.client)
+
+	// when
+	_, err := r.Reconcile(req)
+
+	// then
+	require.Error(t, err)
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+	require.NoError(t, err)
+	test.AssertConditionsMatch(t, userSignup.Status.Conditions,
+		v1alpha1.Condition{
+			Type:   v1alpha1.UserSignupApproved,
+			Status: v1.ConditionTrue,
+			Reason: "ApprovedAutomatically",
+		},
+		v1alpha1.Condition{
+			Type:    v1alpha1.UserSignupComplete,
+			Status:  v1.ConditionFalse,
+			Reason:  "FailedToReadToolchainConfig",
+			Message: "invalid value for 'max-users-per-cluster': 'many'",
+		})
+}
+
+func TestUserSignupWithAutoApprovalAndPlacementRule(t *testing.T) {
+	// given
+	cm := configMap(config.UserApprovalPolicyAutomatic)
+	cm.Data[config.ToolchainConfigMapPlacementRules] = `
+- name: emea
+  annotations:
+    toolchain.dev.openshift.com/requested-region: emea
+  requiredClusterLabels:
+    region: emea`
+	westCluster := newKubeFedCluster("west", "secret", newClusterStatus(common.ClusterReady, v1.ConditionTrue), map[string]string{"region": "emea"})
+	westCluster.Namespace = operatorNamespace
+
+	t.Run("rule matches", func(t *testing.T) {
+		// given
+		userSignup := &v1alpha1.UserSignup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uuid.NewV4().String(),
+				Namespace: operatorNamespace,
+				UID:       types.UID(uuid.NewV4().String()),
+				Annotations: map[string]string{
+					"toolchain.dev.openshift.com/requested-region": "emea",
+				},
+			},
+			Spec: v1alpha1.UserSignupSpec{
+				Username: "foo@redhat.com",
+			},
+		}
+		existingMur := newMasterUserRecordInCluster("bar", "west")
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, existingMur, cm, westCluster, basicNSTemplateTier)
+		createMemberCluster(r.client)
+		createNamedMemberCluster(r.client, "west")
+		defer clearMemberClusters(r.client)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		mur := &v1alpha1.MasterUserRecord{}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: "foo-at-redhat-com", Namespace: operatorNamespace}, mur)
+		require.NoError(t, err)
+		require.Len(t, mur.Spec.UserAccounts, 1)
+		assert.Equal(t, "west", mur.Spec.UserAccounts[0].TargetCluster) // even if it is not the least utilized cluster
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		assert.Equal(t, "emea", userSignup.Annotations["toolchain.dev.openshift.com/placement-rule"])
+	})
+
+	t.Run("rule with several accounts", func(t *testing.T) {
+		// given
+		cm := configMap(config.UserApprovalPolicyAutomatic)
+		cm.Data[config.ToolchainConfigMapPlacementRules] = `
+- name: emea-and-any
+  annotations:
+    toolchain.dev.openshift.com/requested-region: emea
+  accounts:
+  - requiredClusterLabels:
+      region: emea
+  - preferredClusterLabels:
+      region: apac`
+		userSignup := &v1alpha1.UserSignup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uuid.NewV4().String(),
+				Namespace: operatorNamespace,
+				UID:       types.UID(uuid.NewV4().String()),
+				Annotations: map[string]string{
+					"toolchain.dev.openshift.com/requested-region": "emea",
+				},
+			},
+			Spec: v1alpha1.UserSignupSpec{
+				Username: "foo@redhat.com",
+			},
+		}
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, cm, westCluster, basicNSTemplateTier)
+		createMemberCluster(r.client)
+		createNamedMemberCluster(r.client, "west")
+		defer clearMemberClusters(r.client)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		mur := &v1alpha1.MasterUserRecord{}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: "foo-at-redhat-com", Namespace: operatorNamespace}, mur)
+		require.NoError(t, err)
+		require.Len(t, mur.Spec.UserAccounts, 2)
+		assert.Equal(t, "west", mur.Spec.UserAccounts[0].TargetCluster)
+		assert.Equal(t, nameMember, mur.Spec.UserAccounts[1].TargetCluster) // the other account is in another cluster
+		for _, ua := range mur.Spec.UserAccounts {
+			assert.Equal(t, userSignup.Name, ua.Spec.UserID)
+			assert.Equal(t, "basic", ua.Spec.NSTemplateSet.TierName)
+		}
+	})
+
+	t.Run("rule does not match", func(t *testing.T) {
+		// given
+		userSignup := &v1alpha1.UserSignup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uuid.NewV4().String(),
+				Namespace: operatorNamespace,
+				UID:       types.UID(uuid.NewV4().String()),
+			},
+			Spec: v1alpha1.UserSignupSpec{
+				Username: "foo@redhat.com",
+			},
+		}
+		existingMur := newMasterUserRecordInCluster("bar", "west")
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, existingMur, cm, westCluster, basicNSTemplateTier)
+		createMemberCluster(r.client)
+		createNamedMemberCluster(r.client, "west")
+		defer clearMemberClusters(r.client)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		mur := &v1alpha1.MasterUserRecord{}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: "foo-at-redhat-com", Namespace: operatorNamespace}, mur)
+		require.NoError(t, err)
+		require.Len(t, mur.Spec.UserAccounts, 1)
+		assert.Equal(t, nameMember, mur.Spec.UserAccounts[0].TargetCluster)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		assert.NotContains(t, userSignup.Annotations, "toolchain.dev.openshift.com/placement-rule")
+	})
+
+	t.Run("no cluster matching the rule", func(t *testing.T) {
+		// given
+		userSignup := &v1alpha1.UserSignup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uuid.NewV4().String(),
+				Namespace: operatorNamespace,
+				UID:       types.UID(uuid.NewV4().String()),
+				Annotations: map[string]string{
+					"toolchain.dev.openshift.com/requested-region": "emea",
+				},
+			},
+			Spec: v1alpha1.UserSignupSpec{
+				Username: "foo@redhat.com",
+			},
+		}
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, cm, basicNSTemplateTier)
+		createMemberCluster(r.client)
+		defer clearMemberClusters(r.client)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.Error(t, err)
+		require.IsType(t, SignupError{}, err)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		test.AssertConditionsMatch(t, userSignup.Status.Conditions,
+			v1alpha1.Condition{
+				Type:   v1alpha1.UserSignupApproved,
+				Status: v1.ConditionTrue,
+				Reason: "ApprovedAutomatically",
+			},
+			v1alpha1.Condition{
+				Type:   v1alpha1.UserSignupComplete,
+				Status: v1.ConditionFalse,
+				Reason: "NoMatchingClusterAvailable",
+			})
+	})
+}
+
+func TestUserSignupTierSelection(t *testing.T) {
+
+	newUserSignup := func(approved bool, requestedTier string) *v1alpha1.UserSignup {
+		userSignup := &v1alpha1.UserSignup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        uuid.NewV4().String(),
+				Namespace:   operatorNamespace,
+				UID:         types.UID(uuid.NewV4().String()),
+				Annotations: map[string]string{},
+			},
+			Spec: v1alpha1.UserSignupSpec{
+				Username:      "foo@redhat.com",
+				Approved:      approved,
+				TargetCluster: nameMember,
+			},
+		}
+		if requestedTier != "" {
+			userSignup.Annotations["toolchain.dev.openshift.com/requested-tier"] = requestedTier
+		}
+		return userSignup
+	}
+
+	assertTier := func(t *testing.T, r *ReconcileUserSignup, req reconcile.Request, expectedTier string) {
+		res, err := r.Reconcile(req)
+		require.NoError(t, err)
+		require.Equal(t, reconcile.Result{}, res)
+		mur := &v1alpha1.MasterUserRecord{}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: "foo-at-redhat-com", Namespace: operatorNamespace}, mur)
+		require.NoError(t, err)
+		require.Len(t, mur.Spec.UserAccounts, 1)
+		assert.Equal(t, expectedTier, mur.Spec.UserAccounts[0].Spec.NSTemplateSet.TierName)
+	}
+
+	t.Run("default tier set in config", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup(false, "")
+		cm := configMap(config.UserApprovalPolicyAutomatic)
+		cm.Data[config.ToolchainConfigMapDefaultTier] = "advanced"
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, cm, basicNSTemplateTier, advancedNSTemplateTier)
+
+		// when and then
+		assertTier(t, r, req, "advanced")
+	})
+
+	t.Run("requested tier approved by admin", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup(true, "advanced")
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, configMap(config.UserApprovalPolicyManual), basicNSTemplateTier, advancedNSTemplateTier)
+
+		// when and then
+		assertTier(t, r, req, "advanced")
+	})
+
+	t.Run("requested tier allowed by policy", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup(false, "advanced")
+		cm := configMap(config.UserApprovalPolicyAutomatic)
+		cm.Data[config.ToolchainConfigMapUserSelectableTiers] = "basic, advanced"
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, cm, basicNSTemplateTier, advancedNSTemplateTier)
+
+		// when and then
+		assertTier(t, r, req, "advanced")
+	})
+
+	t.Run("requested tier not allowed", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup(false, "advanced")
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, configMap(config.UserApprovalPolicyAutomatic), basicNSTemplateTier, advancedNSTemplateTier)
+
+		// when and then
+		assertTier(t, r, req, "basic")
+	})
+
+	t.Run("requested tier does not exist", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup(true, "gold")
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, configMap(config.UserApprovalPolicyManual), basicNSTemplateTier)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		test.AssertConditionsMatch(t, userSignup.Status.Conditions,
+			v1alpha1.Condition{
+				Type:   v1alpha1.UserSignupApproved,
+				Status: v1.ConditionTrue,
+				Reason: "ApprovedByAdmin",
+			},
+			v1alpha1.Condition{
+				Type:    v1alpha1.UserSignupComplete,
+				Status:  v1.ConditionFalse,
+				Reason:  "RequestedTierNotFound",
+				Message: "the requested NSTemplateTier 'gold' does not exist",
+			})
+	})
+
+	t.Run("default tier does not exist", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup(true, "")
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, configMap(config.UserApprovalPolicyManual))
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, reconcile.Result{Requeue: true}, res)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		test.AssertConditionsMatch(t, userSignup.Status.Conditions,
+			v1alpha1.Condition{
+				Type:   v1alpha1.UserSignupApproved,
+				Status: v1.ConditionTrue,
+				Reason: "ApprovedByAdmin",
+			},
+			v1alpha1.Condition{
+				Type:    v1alpha1.UserSignupComplete,
+				Status:  v1.ConditionFalse,
+				Reason:  "NoTemplateTierAvailable",
+				Message: "nstemplatetiers.toolchain.dev.openshift.com \"basic\" not found",
+			})
+	})
+}
+
+func TestUserSignupDeactivation(t *testing.T) {
+
+	t.Run("deactivate provisioned user", func(t *testing.T) {
+		// given
+		userSignup := &v1alpha1.UserSignup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        uuid.NewV4().String(),
+				Namespace:   operatorNamespace,
+				UID:         types.UID(uuid.NewV4().String()),
+				Annotations: map[string]string{"toolchain.dev.openshift.com/deactivated": "true"},
+			},
+			Spec: v1alpha1.UserSignupSpec{
+				Username: "foo@redhat.com",
+				Approved: true,
+			},
+			Status: v1alpha1.UserSignupStatus{
+				CompliantUsername: "foo-at-redhat-com",
+			},
+		}
+		mur := newMasterUserRecordInCluster("foo-at-redhat-com", nameMember)
+		mur.Labels[v1alpha1.MasterUserRecordUserIDLabelKey] = userSignup.Name
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, mur, configMap(config.UserApprovalPolicyManual), basicNSTemplateTier)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+		murs := &v1alpha1.MasterUserRecordList{}
+		err = r.client.List(context.TODO(), murs)
+		require.NoError(t, err)
+		assert.Empty(t, murs.Items)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		assert.Equal(t, "foo-at-redhat-com", userSignup.Status.CompliantUsername)
+		test.AssertConditionsMatch(t, userSignup.Status.Conditions,
+			v1alpha1.Condition{
+				Type:   "Deactivated",
+				Status: v1.ConditionTrue,
+				Reason: "Deactivated",
+			},
+			v1alpha1.Condition{
+				Type:   v1alpha1.UserSignupComplete,
+				Status: v1.ConditionFalse,
+				Reason: "Deactivated",
+			})
+
+		t.Run("reactivate user with same compliant username", func(t *testing.T) {
+			// given
+			delete(userSignup.Annotations, "toolchain.dev.openshift.com/deactivated")
+			err := r.client.Update(context.TODO(), userSignup)
+			require.NoError(t, err)
+			createMemberCluster(r.client)
+			defer clearMemberClusters(r.client)
+
+			// when
+			res, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, reconcile.Result{}, res)
+			mur := &v1alpha1.MasterUserRecord{}
+			err = r.client.Get(context.TODO(), types.NamespacedName{Name: "foo-at-redhat-com", Namespace: operatorNamespace}, mur)
+			require.NoError(t, err)
+			assert.Equal(t, userSignup.Name, mur.Labels[v1alpha1.MasterUserRecordUserIDLabelKey])
+			err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+			require.NoError(t, err)
+			test.AssertConditionsMatch(t, userSignup.Status.Conditions,
+				v1alpha1.Condition{
+					Type:   "Deactivated",
+					Status: v1.ConditionFalse,
+					Reason: "Reactivated",
+				},
+				v1alpha1.Condition{
+					Type:   v1alpha1.UserSignupApproved,
+					Status: v1.ConditionTrue,
+					Reason: "ApprovedByAdmin",
+				},
+				v1alpha1.Condition{
+					Type:   v1alpha1.UserSignupComplete,
+					Status: v1.ConditionFalse,
+					Reason: "Deactivated",
+				})
+		})
+	})
+
+	t.Run("deactivation fails", func(t *testing.T) {
+		// given
+		userSignup := &v1alpha1.UserSignup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        uuid.NewV4().String(),
+				Namespace:   operatorNamespace,
+				UID:         types.UID(uuid.NewV4().String()),
+				Annotations: map[string]string{"toolchain.dev.openshift.com/deactivated": "true"},
+			},
+			Spec: v1alpha1.UserSignupSpec{
+				Username: "foo@redhat.com",
+			},
+		}
+		mur := newMasterUserRecordInCluster("foo-at-redhat-com", nameMember)
+		mur.Labels[v1alpha1.MasterUserRecordUserIDLabelKey] = userSignup.Name
+		r, req, fakeClient := prepareReconcile(t, userSignup.Name, userSignup, mur, configMap(config.UserApprovalPolicyManual))
+		fakeClient.MockDelete = func(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+			return errors.New("unable to delete")
+		}
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.Error(t, err)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		test.AssertConditionsMatch(t, userSignup.Status.Conditions,
+			v1alpha1.Condition{
+				Type:    v1alpha1.UserSignupComplete,
+				Status:  v1.ConditionFalse,
+				Reason:  "UnableToDeactivate",
+				Message: "unable to delete",
+			})
+	})
+
+	t.Run("compliant username of deactivated user is reserved", func(t *testing.T) {
+		// given
+		deactivated := &v1alpha1.UserSignup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        uuid.NewV4().String(),
+				Namespace:   operatorNamespace,
+				UID:         types.UID(uuid.NewV4().String()),
+				Annotations: map[string]string{"toolchain.dev.openshift.com/deactivated": "true"},
+			},
+			Spec: v1alpha1.UserSignupSpec{
+				Username: "foo.at.redhat.com", // transformed into the same compliant username as below
+			},
+			Status: v1alpha1.UserSignupStatus{
+				CompliantUsername: "foo-at-redhat-com",
+			},
+		}
+		userSignup := &v1alpha1.UserSignup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uuid.NewV4().String(),
+				Namespace: operatorNamespace,
+				UID:       types.UID(uuid.NewV4().String()),
+			},
+			Spec: v1alpha1.UserSignupSpec{
+				Username:      "foo@redhat.com",
+				TargetCluster: nameMember,
+			},
+		}
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, deactivated, configMap(config.UserApprovalPolicyAutomatic), basicNSTemplateTier)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		mur := &v1alpha1.MasterUserRecord{}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: "foo-at-redhat-com-1", Namespace: operatorNamespace}, mur)
+		require.NoError(t, err)
+		assert.Equal(t, userSignup.Name, mur.Labels[v1alpha1.MasterUserRecordUserIDLabelKey])
+	})
+}
+
+func TestUserSignupExpiry(t *testing.T) {
+
+	newProvisionedUserSignup := func(provisioned *time.Time) (*v1alpha1.UserSignup, *v1alpha1.MasterUserRecord) {
+		userSignup := &v1alpha1.UserSignup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        uuid.NewV4().String(),
+				Namespace:   operatorNamespace,
+				UID:         types.UID(uuid.NewV4().String()),
+				Annotations: map[string]string{},
+			},
+			Spec: v1alpha1.UserSignupSpec{
+				Username: "foo@redhat.com",
+			},
+		}
+		if provisioned != nil {
+			userSignup.Annotations["toolchain.dev.openshift.com/provisioned-time"] = provisioned.UTC().Format(time.RFC3339)
+		}
+		mur := newMasterUserRecordInCluster("foo-at-redhat-com", nameMember)
+		mur.Labels[v1alpha1.MasterUserRecordUserIDLabelKey] = userSignup.Name
+		mur.Spec.UserAccounts[0].Spec.NSTemplateSet.TierName = "basic"
+		return userSignup, mur
+	}
+
+	t.Run("record provisioning and expiry times", func(t *testing.T) {
+		// given
+		userSignup, mur := newProvisionedUserSignup(nil)
+		cm := configMap(config.UserApprovalPolicyAutomatic)
+		cm.Data[config.ToolchainConfigMapUserLifetimeDays] = "30"
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, mur, cm)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, res.RequeueAfter > 29*24*time.Hour && res.RequeueAfter <= 30*24*time.Hour)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		provisioned, err := time.Parse(time.RFC3339, userSignup.Annotations["toolchain.dev.openshift.com/provisioned-time"])
+		require.NoError(t, err)
+		expiry, err := time.Parse(time.RFC3339, userSignup.Annotations["toolchain.dev.openshift.com/expiry-time"])
+		require.NoError(t, err)
+		assert.Equal(t, 30*24*time.Hour, expiry.Sub(provisioned))
+	})
+
+	t.Run("use the creation time of the existing MasterUserRecord when the provisioning time is missing", func(t *testing.T) {
+		// given
+		userSignup, mur := newProvisionedUserSignup(nil)
+		created := time.Now().Add(-10 * 24 * time.Hour)
+		mur.CreationTimestamp = metav1.NewTime(created)
+		cm := configMap(config.UserApprovalPolicyAutomatic)
+		cm.Data[config.ToolchainConfigMapUserLifetimeDays] = "30"
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, mur, cm)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, res.RequeueAfter > 19*24*time.Hour && res.RequeueAfter <= 20*24*time.Hour)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		expiryValue := created.Add(30 * 24 * time.Hour).UTC().Format(time.RFC3339)
+		assert.Equal(t, created.UTC().Format(time.RFC3339), userSignup.Annotations["toolchain.dev.openshift.com/provisioned-time"])
+		assert.Equal(t, expiryValue, userSignup.Annotations["toolchain.dev.openshift.com/expiry-time"])
+		expires, found := findCondition(userSignup.Status.Conditions, "Expires")
+		require.True(t, found)
+		assert.Equal(t, v1.ConditionTrue, expires.Status)
+		assert.Equal(t, "LifetimeLimited", expires.Reason)
+		assert.Equal(t, "the account expires at "+expiryValue, expires.Message)
+	})
+
+	t.Run("deactivate the user when the MasterUserRecord is older than the lifetime", func(t *testing.T) {
+		// given
+		userSignup, mur := newProvisionedUserSignup(nil)
+		mur.CreationTimestamp = metav1.NewTime(time.Now().Add(-31 * 24 * time.Hour))
+		cm := configMap(config.UserApprovalPolicyAutomatic)
+		cm.Data[config.ToolchainConfigMapUserLifetimeDays] = "30"
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, mur, cm)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		assert.Equal(t, "true", userSignup.Annotations["toolchain.dev.openshift.com/deactivated"])
+	})
+
+	t.Run("lifetime overridden for the tier", func(t *testing.T) {
+		// given
+		provisioned := time.Now().Add(-24 * time.Hour)
+		userSignup, mur := newProvisionedUserSignup(&provisioned)
+		cm := configMap(config.UserApprovalPolicyAutomatic)
+		cm.Data[config.ToolchainConfigMapUserLifetimeDays] = "30"
+		cm.Data[config.ToolchainConfigMapUserLifetimeDays+".basic"] = "7"
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, mur, cm)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, res.RequeueAfter > 5*24*time.Hour && res.RequeueAfter <= 6*24*time.Hour)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		assert.Equal(t, provisioned.Add(7*24*time.Hour).UTC().Format(time.RFC3339), userSignup.Annotations["toolchain.dev.openshift.com/expiry-time"])
+	})
+
+	t.Run("deactivate expired user", func(t *testing.T) {
+		// given
+		provisioned := time.Now().Add(-31 * 24 * time.Hour)
+		userSignup, mur := newProvisionedUserSignup(&provisioned)
+		cm := configMap(config.UserApprovalPolicyAutomatic)
+		cm.Data[config.ToolchainConfigMapUserLifetimeDays] = "30"
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, mur, cm)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+		murs := &v1alpha1.MasterUserRecordList{}
+		err = r.client.List(context.TODO(), murs)
+		require.NoError(t, err)
+		assert.Empty(t, murs.Items)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		assert.Equal(t, "true", userSignup.Annotations["toolchain.dev.openshift.com/deactivated"])
+		test.AssertConditionsMatch(t, userSignup.Status.Conditions,
+			v1alpha1.Condition{
+				Type:   "Deactivated",
+				Status: v1.ConditionTrue,
+				Reason: "Deactivated",
+			},
+			v1alpha1.Condition{
+				Type:   v1alpha1.UserSignupComplete,
+				Status: v1.ConditionFalse,
+				Reason: "Deactivated",
+			})
+	})
+
+	t.Run("no lifetime configured", func(t *testing.T) {
+		// given
+		userSignup, mur := newProvisionedUserSignup(nil)
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, mur, configMap(config.UserApprovalPolicyAutomatic))
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		assert.NotContains(t, userSignup.Annotations, "toolchain.dev.openshift.com/expiry-time")
+	})
+
+	t.Run("no expiry when the lifetime was removed", func(t *testing.T) {
+		// given
+		userSignup, mur := newProvisionedUserSignup(nil)
+		userSignup.Status.Conditions = []v1alpha1.Condition{
+			{
+				Type:    "Expires",
+				Status:  v1.ConditionTrue,
+				Reason:  "LifetimeLimited",
+				Message: "the account expires at 2020-01-01T00:00:00Z",
+			},
+		}
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, mur, configMap(config.UserApprovalPolicyAutomatic))
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		expires, found := findCondition(userSignup.Status.Conditions, "Expires")
+		require.True(t, found)
+		assert.Equal(t, v1.ConditionFalse, expires.Status)
+		assert.Equal(t, "NoLifetimeLimit", expires.Reason)
+	})
+}
+
+func TestUserSignupNotifications(t *testing.T) {
+
+	newUserSignup := func() *v1alpha1.UserSignup {
+		return &v1alpha1.UserSignup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        uuid.NewV4().String(),
+				Namespace:   operatorNamespace,
+				UID:         types.UID(uuid.NewV4().String()),
+				Annotations: map[string]string{"toolchain.dev.openshift.com/user-email": "foo@redhat.com"},
+			},
+			Spec: v1alpha1.UserSignupSpec{
+				Username: "foo@redhat.com",
+			},
+			Status: v1alpha1.UserSignupStatus{
+				Conditions: []v1alpha1.Condition{
+					{
+						Type:   v1alpha1.UserSignupApproved,
+						Status: v1.ConditionTrue,
+						Reason: "ApprovedAutomatically",
+					},
+				},
+			},
+		}
+	}
+	newMasterUserRecord := func(userSignup *v1alpha1.UserSignup, ready bool) *v1alpha1.MasterUserRecord {
+		mur := newMasterUserRecordInCluster("foo-at-redhat-com", nameMember)
+		mur.Labels[v1alpha1.MasterUserRecordUserIDLabelKey] = userSignup.Name
+		if ready {
+			mur.Status.Conditions = []v1alpha1.Condition{
+				{
+					Type:   v1alpha1.ConditionReady,
+					Status: v1.ConditionTrue,
+					Reason: "Provisioned",
+				},
+			}
+		}
+		return mur
+	}
+
+	t.Run("notify approval", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup()
+		r, req, cl := prepareReconcile(t, userSignup.Name, userSignup, newMasterUserRecord(userSignup, false), configMap(config.UserApprovalPolicyAutomatic))
+		notifier := useFakeNotifier(r)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, notifier.notifications, 1)
+		assert.Equal(t, notification.Approved, notifier.notifications[0].Type)
+		assert.Equal(t, "foo@redhat.com", notifier.notifications[0].Recipient)
+		assert.Contains(t, notifier.notifications[0].Body, "foo-at-redhat-com")
+		err = cl.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		assert.Contains(t, userSignup.Annotations, "toolchain.dev.openshift.com/notified-approved")
+		assert.NotContains(t, userSignup.Annotations, "toolchain.dev.openshift.com/notified-provisioned")
+		assertNotificationsRecorded(t, cl, userSignup, notification.Approved)
+	})
+
+	t.Run("notify provisioning only once", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup()
+		r, req, cl := prepareReconcile(t, userSignup.Name, userSignup, newMasterUserRecord(userSignup, true), configMap(config.UserApprovalPolicyAutomatic))
+		notifier := useFakeNotifier(r)
+
+		// when
+		_, err := r.Reconcile(req)
+		require.NoError(t, err)
+		_, err = r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, notifier.notifications, 2)
+		assert.Equal(t, notification.Approved, notifier.notifications[0].Type)
+		assert.Equal(t, notification.Provisioned, notifier.notifications[1].Type)
+		assertNotificationsRecorded(t, cl, userSignup, notification.Approved, notification.Provisioned)
+	})
+
+	t.Run("notify upcoming expiry", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup()
+		userSignup.Annotations["toolchain.dev.openshift.com/notified-approved"] = "2019-12-01T00:00:00Z"
+		userSignup.Annotations["toolchain.dev.openshift.com/notified-provisioned"] = "2019-12-01T00:00:00Z"
+		userSignup.Annotations["toolchain.dev.openshift.com/provisioned-time"] = time.Now().Add(-28 * 24 * time.Hour).UTC().Format(time.RFC3339)
+		cm := configMap(config.UserApprovalPolicyAutomatic)
+		cm.Data[config.ToolchainConfigMapUserLifetimeDays] = "30"
+		cm.Data[config.ToolchainConfigMapNotificationExpiryWarningDays] = "3"
+		r, req, cl := prepareReconcile(t, userSignup.Name, userSignup, newMasterUserRecord(userSignup, true), cm)
+		notifier := useFakeNotifier(r)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, res.RequeueAfter > 24*time.Hour && res.RequeueAfter <= 2*24*time.Hour)
+		require.Len(t, notifier.notifications, 1)
+		assert.Equal(t, notification.Expiring, notifier.notifications[0].Type)
+		assertNotificationsRecorded(t, cl, userSignup, notification.Expiring)
+	})
+
+	t.Run("requeue until expiry warning", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup()
+		userSignup.Annotations["toolchain.dev.openshift.com/notified-approved"] = "2019-12-01T00:00:00Z"
+		userSignup.Annotations["toolchain.dev.openshift.com/notified-provisioned"] = "2019-12-01T00:00:00Z"
+		userSignup.Annotations["toolchain.dev.openshift.com/provisioned-time"] = time.Now().Add(-10 * 24 * time.Hour).UTC().Format(time.RFC3339)
+		cm := configMap(config.UserApprovalPolicyAutomatic)
+		cm.Data[config.ToolchainConfigMapUserLifetimeDays] = "30"
+		cm.Data[config.ToolchainConfigMapNotificationExpiryWarningDays] = "3"
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, newMasterUserRecord(userSignup, true), cm)
+		notifier := useFakeNotifier(r)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, res.RequeueAfter > 16*24*time.Hour && res.RequeueAfter <= 17*24*time.Hour)
+		assert.Empty(t, notifier.notifications)
+	})
+
+	t.Run("notify deactivation", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup()
+		userSignup.Annotations["toolchain.dev.openshift.com/deactivated"] = "true"
+		userSignup.Status.CompliantUsername = "foo-at-redhat-com"
+		r, req, cl := prepareReconcile(t, userSignup.Name, userSignup, newMasterUserRecord(userSignup, true), configMap(config.UserApprovalPolicyAutomatic))
+		notifier := useFakeNotifier(r)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, notifier.notifications, 1)
+		assert.Equal(t, notification.Deactivated, notifier.notifications[0].Type)
+		assert.Contains(t, notifier.notifications[0].Body, "foo-at-redhat-com")
+		assertNotificationsRecorded(t, cl, userSignup, notification.Deactivated)
+	})
+
+	t.Run("no notification without email address", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup()
+		userSignup.Annotations = nil
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, newMasterUserRecord(userSignup, true), configMap(config.UserApprovalPolicyAutomatic))
+		notifier := useFakeNotifier(r)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, notifier.notifications)
+	})
+
+	t.Run("notification fails", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup()
+		r, req, cl := prepareReconcile(t, userSignup.Name, userSignup, newMasterUserRecord(userSignup, false), configMap(config.UserApprovalPolicyAutomatic))
+		notifier := useFakeNotifier(r)
+		notifier.err = errors.New("connection refused")
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.EqualError(t, err, "unable to send the 'approved' notification: connection refused")
+		err = cl.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		assert.NotContains(t, userSignup.Annotations, "toolchain.dev.openshift.com/notified-approved")
+		assertNotificationsRecorded(t, cl, userSignup)
+	})
+
+	t.Run("notification not sent when it cannot be recorded", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup()
+		r, req, cl := prepareReconcile(t, userSignup.Name, userSignup, newMasterUserRecord(userSignup, false), configMap(config.UserApprovalPolicyAutomatic))
+		notifier := useFakeNotifier(r)
+		cl.MockUpdate = func(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+			if _, ok := obj.(*v1alpha1.UserSignup); ok {
+				return errors.New("unable to update")
+			}
+			return cl.Client.Update(ctx, obj, opts...)
+		}
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.EqualError(t, err, "unable to record the 'approved' notification: unable to update")
+		assert.Empty(t, notifier.notifications)
+		assertNotificationsRecorded(t, cl, userSignup)
+	})
+}
+
+func TestUserSignupDomainApprovalPolicy(t *testing.T) {
+
+	newUserSignup := func(email string, approved bool) *v1alpha1.UserSignup {
+		return &v1alpha1.UserSignup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        uuid.NewV4().String(),
+				Namespace:   operatorNamespace,
+				UID:         types.UID(uuid.NewV4().String()),
+				Annotations: map[string]string{"toolchain.dev.openshift.com/user-email": email},
+			},
+			Spec: v1alpha1.UserSignupSpec{
+				Username: email,
+				Approved: approved,
+			},
+		}
+	}
+	newConfigMap := func(policy string) *v1.ConfigMap {
+		cm := configMap(policy)
+		cm.Data[config.ToolchainConfigMapAutoApprovalAllowedDomains] = "redhat.com, ibm.com"
+		cm.Data[config.ToolchainConfigMapAutoApprovalDeniedDomains] = "spam.com"
+		return cm
+	}
+
+	t.Run("approve signup with allowed domain", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup("foo@IBM.com", false)
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, newConfigMap(config.UserApprovalPolicyManual), basicNSTemplateTier)
+		createMemberCluster(r.client)
+		defer clearMemberClusters(r.client)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		test.AssertConditionsMatch(t, userSignup.Status.Conditions,
+			v1alpha1.Condition{
+				Type:    v1alpha1.UserSignupApproved,
+				Status:  v1.ConditionTrue,
+				Reason:  "ApprovedAutomaticallyByDomain",
+				Message: "email domain 'ibm.com' is allowed",
+			})
+		murs := &v1alpha1.MasterUserRecordList{}
+		err = r.client.List(context.TODO(), murs)
+		require.NoError(t, err)
+		require.Len(t, murs.Items, 1)
+	})
+
+	t.Run("reject signup with denied domain", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup("foo@spam.com", false)
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, newConfigMap(config.UserApprovalPolicyAutomatic), basicNSTemplateTier)
+		createMemberCluster(r.client)
+		defer clearMemberClusters(r.client)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		test.AssertConditionsMatch(t, userSignup.Status.Conditions,
+			v1alpha1.Condition{
+				Type:    v1alpha1.UserSignupApproved,
+				Status:  v1.ConditionFalse,
+				Reason:  "RejectedAutomaticallyByDomain",
+				Message: "email domain 'spam.com' is denied",
+			},
+			v1alpha1.Condition{
+				Type:    v1alpha1.UserSignupComplete,
+				Status:  v1.ConditionFalse,
+				Reason:  "RejectedAutomaticallyByDomain",
+				Message: "email domain 'spam.com' is denied",
+			})
+		murs := &v1alpha1.MasterUserRecordList{}
+		err = r.client.List(context.TODO(), murs)
+		require.NoError(t, err)
+		assert.Empty(t, murs.Items)
+	})
+
+	t.Run("signup with denied domain approved by admin", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup("foo@spam.com", true)
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, newConfigMap(config.UserApprovalPolicyManual), basicNSTemplateTier)
+		createMemberCluster(r.client)
+		defer clearMemberClusters(r.client)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		test.AssertConditionsMatch(t, userSignup.Status.Conditions,
+			v1alpha1.Condition{
+				Type:   v1alpha1.UserSignupApproved,
+				Status: v1.ConditionTrue,
+				Reason: "ApprovedByAdmin",
+			})
+	})
+
+	t.Run("signup with other domain pending approval", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup("foo@gmail.com", false)
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, newConfigMap(config.UserApprovalPolicyManual), basicNSTemplateTier)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		test.AssertConditionsMatch(t, userSignup.Status.Conditions,
+			v1alpha1.Condition{
+				Type:   v1alpha1.UserSignupApproved,
+				Status: v1.ConditionFalse,
+				Reason: "PendingApproval",
+			},
+			v1alpha1.Condition{
+				Type:   v1alpha1.UserSignupComplete,
+				Status: v1.ConditionFalse,
+				Reason: "PendingApproval",
+			})
+	})
+
+	t.Run("signup with other domain approved automatically", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup("foo@gmail.com", false)
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, newConfigMap(config.UserApprovalPolicyAutomatic), basicNSTemplateTier)
+		createMemberCluster(r.client)
+		defer clearMemberClusters(r.client)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		test.AssertConditionsMatch(t, userSignup.Status.Conditions,
+			v1alpha1.Condition{
+				Type:   v1alpha1.UserSignupApproved,
+				Status: v1.ConditionTrue,
+				Reason: "ApprovedAutomatically",
+			})
+	})
+}
+
+func TestUserSignupRejectionAndBan(t *testing.T) {
+
+	newUserSignup := func(annotations map[string]string) *v1alpha1.UserSignup {
+		annotations["toolchain.dev.openshift.com/user-email"] = "foo@redhat.com"
+		return &v1alpha1.UserSignup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        uuid.NewV4().String(),
+				Namespace:   operatorNamespace,
+				UID:         types.UID(uuid.NewV4().String()),
+				Annotations: annotations,
+			},
+			Spec: v1alpha1.UserSignupSpec{
+				Username: "foo@redhat.com",
+				Approved: true,
+			},
+		}
+	}
+	assertNotApproved := func(t *testing.T, r *ReconcileUserSignup, userSignup *v1alpha1.UserSignup, reason string) {
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: userSignup.Namespace}, userSignup)
+		require.NoError(t, err)
+		test.AssertConditionsMatch(t, userSignup.Status.Conditions,
+			v1alpha1.Condition{
+				Type:   v1alpha1.UserSignupApproved,
+				Status: v1.ConditionFalse,
+				Reason: reason,
+			},
+			v1alpha1.Condition{
+				Type:   v1alpha1.UserSignupComplete,
+				Status: v1.ConditionFalse,
+				Reason: reason,
+			})
+		murs := &v1alpha1.MasterUserRecordList{}
+		err = r.client.List(context.TODO(), murs)
+		require.NoError(t, err)
+		assert.Empty(t, murs.Items)
+	}
+
+	t.Run("reject signup", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup(map[string]string{"toolchain.dev.openshift.com/rejected": "true"})
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, configMap(config.UserApprovalPolicyAutomatic), basicNSTemplateTier)
+		createMemberCluster(r.client)
+		defer clearMemberClusters(r.client)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assertNotApproved(t, r, userSignup, "Rejected")
+		banned, err := banneduser.IsBanned(r.client, operatorNamespace, userSignup.Name, "foo@redhat.com")
+		require.NoError(t, err)
+		assert.False(t, banned)
+	})
+
+	t.Run("reject provisioned user", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup(map[string]string{"toolchain.dev.openshift.com/rejected": "true"})
+		mur := newMasterUserRecordInCluster("foo-at-redhat-com", nameMember)
+		mur.Labels[v1alpha1.MasterUserRecordUserIDLabelKey] = userSignup.Name
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, mur, configMap(config.UserApprovalPolicyAutomatic), basicNSTemplateTier)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assertNotApproved(t, r, userSignup, "Rejected")
+	})
+
+	t.Run("ban provisioned user", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup(map[string]string{"toolchain.dev.openshift.com/banned": "true"})
+		mur := newMasterUserRecordInCluster("foo-at-redhat-com", nameMember)
+		mur.Labels[v1alpha1.MasterUserRecordUserIDLabelKey] = userSignup.Name
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, mur, configMap(config.UserApprovalPolicyAutomatic), basicNSTemplateTier)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assertNotApproved(t, r, userSignup, "Banned")
+		banned, err := banneduser.IsBanned(r.client, operatorNamespace, userSignup.Name, "")
+		require.NoError(t, err)
+		assert.True(t, banned)
+	})
+
+	t.Run("refuse signup from banned email", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup(map[string]string{})
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, configMap(config.UserApprovalPolicyAutomatic), basicNSTemplateTier)
+		err := banneduser.Ban(r.client, operatorNamespace, uuid.NewV4().String(), "FOO@redhat.com")
+		require.NoError(t, err)
+		createMemberCluster(r.client)
+		defer clearMemberClusters(r.client)
+
+		// when
+		_, err = r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assertNotApproved(t, r, userSignup, "BannedIdentity")
+	})
+
+	t.Run("refuse signup from banned user ID", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup(map[string]string{})
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, configMap(config.UserApprovalPolicyAutomatic), basicNSTemplateTier)
+		err := banneduser.Ban(r.client, operatorNamespace, userSignup.Name, "")
+		require.NoError(t, err)
+		createMemberCluster(r.client)
+		defer clearMemberClusters(r.client)
+
+		// when
+		_, err = r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assertNotApproved(t, r, userSignup, "BannedIdentity")
+	})
+
+	t.Run("ban fails", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup(map[string]string{"toolchain.dev.openshift.com/banned": "true"})
+		r, req, cl := prepareReconcile(t, userSignup.Name, userSignup, configMap(config.UserApprovalPolicyAutomatic), basicNSTemplateTier)
+		cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			return errors.New("unable to create")
+		}
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.EqualError(t, err, "Error banning user: unable to create")
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		test.AssertConditionsMatch(t, userSignup.Status.Conditions,
+			v1alpha1.Condition{
+				Type:    v1alpha1.UserSignupComplete,
+				Status:  v1.ConditionFalse,
+				Reason:  "UnableToBan",
+				Message: "unable to create",
+			})
+	})
+
+	t.Run("tear down fails", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup(map[string]string{"toolchain.dev.openshift.com/rejected": "true"})
+		mur := newMasterUserRecordInCluster("foo-at-redhat-com", nameMember)
+		mur.Labels[v1alpha1.MasterUserRecordUserIDLabelKey] = userSignup.Name
+		r, req, cl := prepareReconcile(t, userSignup.Name, userSignup, mur, configMap(config.UserApprovalPolicyAutomatic), basicNSTemplateTier)
+		cl.MockDelete = func(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+			return errors.New("unable to delete")
+		}
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.EqualError(t, err, "Error deleting MasterUserRecord foo-at-redhat-com: unable to delete")
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		test.AssertConditionsMatch(t, userSignup.Status.Conditions,
+			v1alpha1.Condition{
+				Type:    v1alpha1.UserSignupComplete,
+				Status:  v1.ConditionFalse,
+				Reason:  "UnableToTearDown",
+				Message: "unable to delete",
+			})
+	})
+
+	t.Run("unban user", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup(map[string]string{})
+		userSignup.Status.Conditions = []v1alpha1.Condition{
+			{
+				Type:   v1alpha1.UserSignupApproved,
+				Status: v1.ConditionFalse,
+				Reason: "Banned",
+			},
+			{
+				Type:   v1alpha1.UserSignupComplete,
+				Status: v1.ConditionFalse,
+				Reason: "Banned",
+			},
+		}
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, configMap(config.UserApprovalPolicyAutomatic), basicNSTemplateTier)
+		err := banneduser.Ban(r.client, operatorNamespace, userSignup.Name, "foo@redhat.com")
+		require.NoError(t, err)
+		createMemberCluster(r.client)
+		defer clearMemberClusters(r.client)
+
+		// when
+		_, err = r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		banned, err := banneduser.IsBanned(r.client, operatorNamespace, userSignup.Name, "foo@redhat.com")
+		require.NoError(t, err)
+		assert.False(t, banned)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: req.Namespace}, userSignup)
+		require.NoError(t, err)
+		approved, found := findCondition(userSignup.Status.Conditions, v1alpha1.UserSignupApproved)
+		require.True(t, found)
+		assert.Equal(t, v1.ConditionTrue, approved.Status)
+		assert.Equal(t, "ApprovedByAdmin", approved.Reason)
+	})
+}
+
+type fakeNotifier struct {
+	notifications []notification.Notification
+	err           error
+}
+
+func (n *fakeNotifier) Notify(sent notification.Notification) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.notifications = append(n.notifications, sent)
+	return nil
+}
+
+func useFakeNotifier(r *ReconcileUserSignup) *fakeNotifier {
+	notifier := &fakeNotifier{}
+	r.newNotifier = func(_ client.Client, _ string, _ config.ToolchainConfig) (notification.Notifier, error) {
+		return notifier, nil
+	}
+	return notifier
+}
+
+func assertNotificationsRecorded(t *testing.T, cl client.Client, userSignup *v1alpha1.UserSignup, expected ...notification.Type) {
+	cms := &v1.ConfigMapList{}
+	err := cl.List(context.TODO(), cms, client.MatchingLabels(map[string]string{"toolchain.dev.openshift.com/usersignup": userSignup.Name}))
+	require.NoError(t, err)
+	recorded := make([]notification.Type, 0, len(cms.Items))
+	for _, cm := range cms.Items {
+		recorded = append(recorded, notification.Type(cm.Labels["toolchain.dev.openshift.com/notification-type"]))
+		assert.Equal(t, "foo@redhat.com", cm.Data["recipient"])
+	}
+	assert.ElementsMatch(t, expected, recorded)
+}
+
+func newMasterUserRecordInCluster(name, targetCluster string) *v1alpha1.MasterUserRecord {
+	return &v1alpha1.MasterUserRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: operatorNamespace,
+			Labels:    map[string]string{v1alpha1.MasterUserRecordUserIDLabelKey: uuid.NewV4().String()},
+		},
+		Spec: v1alpha1.MasterUserRecordSpec{
+			UserAccounts: []v1alpha1.UserAccountEmbedded{{TargetCluster: targetCluster}},
+		},
+	}
+}
+
 func prepareReconcile(t *testing.T, name string, initObjs ...runtime.Object) (*ReconcileUserSignup, reconcile.Request, *test.FakeClient) {
 	s := scheme.Scheme
 	err := apis.AddToScheme(s)
 	require.NoError(t, err)
+	err = v1beta1.AddToScheme(s)
+	require.NoError(t, err)
 
 	secret := &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
@@ -984,8 +2387,10 @@ func prepareReconcile(t *testing.T, name string, initObjs ...runtime.Object) (*R
 	client := test.NewFakeClient(t, initObjs...)
 
 	r := &ReconcileUserSignup{
-		client: client,
-		scheme: s,
+		client:       client,
+		scheme:       s,
+		placement:    placement.New(client, cluster.GetMemberClusters, placement.LeastUtilized),
+		configLoader: config.NewLoader(client, record.NewFakeRecorder(10)),
 	}
 	return r, newReconcileRequest(name), client
 }
@@ -1000,9 +2405,13 @@ func newReconcileRequest(name string) reconcile.Request {
 }
 
 func createMemberCluster(client client.Client) {
+	createNamedMemberCluster(client, nameMember)
+}
+
+func createNamedMemberCluster(client client.Client, name string) {
 	status := newClusterStatus(common.ClusterReady, v1.ConditionTrue)
 
-	kubeFedCluster := newKubeFedCluster("east", "secret", status, labels(cluster.Member, "", nameMember))
+	kubeFedCluster := newKubeFedCluster(name, "secret", status, labels(cluster.Member, "", name))
 
 	service := cluster.NewKubeFedClusterService(client, logf.Log, operatorNamespace)
 	service.AddKubeFedCluster(kubeFedCluster)
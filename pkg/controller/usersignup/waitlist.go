@@ -0,0 +1,119 @@
+package usersignup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// waitlist adds the UserSignup to the waitlist, at the given position (the first position being 1).
+// The UserSignup will be reconciled again when a MasterUserRecord is deleted.
+func (r *ReconcileUserSignup) waitlist(logger logr.Logger, userSignup *toolchainv1alpha1.UserSignup, position int) error {
+	return r.waitlistWithMessage(logger, userSignup, position, fmt.Sprintf("waitlist position: %d", position))
+}
+
+// waitlistForCapacity adds the UserSignup to the waitlist, at the given position, because none of the member
+// clusters has capacity left for a new user
+func (r *ReconcileUserSignup) waitlistForCapacity(logger logr.Logger, userSignup *toolchainv1alpha1.UserSignup, position int) error {
+	return r.waitlistWithMessage(logger, userSignup, position,
+		fmt.Sprintf("waitlist position: %d, no member cluster has capacity left", position))
+}
+
+func (r *ReconcileUserSignup) waitlistWithMessage(logger logr.Logger, userSignup *toolchainv1alpha1.UserSignup, position int, message string) error {
+	logger.Info("Adding signup to the waitlist", "Position", position)
+	if err := r.setStatusWaitlisted(userSignup, message); err != nil {
+		logger.Error(err, "status update failed")
+		return err
+	}
+	return nil
+}
+
+// waitlistedBefore returns the number of waitlisted UserSignups which were created before the given one
+func (r *ReconcileUserSignup) waitlistedBefore(userSignup *toolchainv1alpha1.UserSignup) (int, error) {
+	waitlisted, err := listWaitlisted(r.client, userSignup.Namespace)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, other := range waitlisted {
+		if other.Name != userSignup.Name && createdBefore(other, *userSignup) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// countActiveUsers returns the number of MasterUserRecords in the given namespace
+func (r *ReconcileUserSignup) countActiveUsers(namespace string) (int, error) {
+	murs := &toolchainv1alpha1.MasterUserRecordList{}
+	if err := r.client.List(context.TODO(), murs, client.InNamespace(namespace)); err != nil {
+		return 0, err
+	}
+	return len(murs.Items), nil
+}
+
+// listWaitlisted returns the waitlisted UserSignups of the given namespace, in the order of the waitlist
+func listWaitlisted(cl client.Client, namespace string) ([]toolchainv1alpha1.UserSignup, error) {
+	userSignups := &toolchainv1alpha1.UserSignupList{}
+	if err := cl.List(context.TODO(), userSignups, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	waitlisted := []toolchainv1alpha1.UserSignup{}
+	for _, userSignup := range userSignups.Items {
+		if isWaitlisted(userSignup) {
+			waitlisted = append(waitlisted, userSignup)
+		}
+	}
+	sort.Slice(waitlisted, func(i, j int) bool {
+		return createdBefore(waitlisted[i], waitlisted[j])
+	})
+	return waitlisted, nil
+}
+
+// isWaitlisted returns true if the UserSignup is in the waitlist
+func isWaitlisted(userSignup toolchainv1alpha1.UserSignup) bool {
+	condition, found := findCondition(userSignup.Status.Conditions, toolchainv1alpha1.UserSignupComplete)
+	return found && condition.Status == corev1.ConditionFalse && condition.Reason == waitlistedReason
+}
+
+// createdBefore returns true if the first UserSignup was created before the second one (using the names to
+// order the UserSignups which were created at the same time)
+func createdBefore(first, second toolchainv1alpha1.UserSignup) bool {
+	if first.CreationTimestamp.Equal(&second.CreationTimestamp) {
+		return first.Name < second.Name
+	}
+	return first.CreationTimestamp.Before(&second.CreationTimestamp)
+}
+
+// waitlistMapper maps a MasterUserRecord to the waitlisted UserSignups of the same namespace, in the order of
+// the waitlist, so that they are reconciled again when the MasterUserRecord is deleted
+type waitlistMapper struct {
+	client client.Client
+}
+
+var _ handler.Mapper = waitlistMapper{}
+
+// Map implements handler.Mapper
+func (m waitlistMapper) Map(obj handler.MapObject) []reconcile.Request {
+	waitlisted, err := listWaitlisted(m.client, obj.Meta.GetNamespace())
+	if err != nil {
+		log.Error(err, "unable to list the waitlisted UserSignups")
+		return nil
+	}
+	requests := make([]reconcile.Request, len(waitlisted))
+	for i, userSignup := range waitlisted {
+		requests[i] = reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: userSignup.Namespace, Name: userSignup.Name},
+		}
+	}
+	return requests
+}
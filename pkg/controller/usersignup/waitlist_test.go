@@ -0,0 +1,230 @@
+package usersignup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestUserSignupWaitlist(t *testing.T) {
+
+	newMaxActiveUsersConfigMap := func(maxActiveUsers string) *v1.ConfigMap {
+		cm := configMap(config.UserApprovalPolicyAutomatic)
+		cm.Data[config.ToolchainConfigMapMaxActiveUsers] = maxActiveUsers
+		return cm
+	}
+
+	t.Run("waitlist signup when max active users is reached", func(t *testing.T) {
+		// given
+		userSignup := newUserSignupCreatedAt("foo", time.Now())
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, newMasterUserRecordInCluster("bar", nameMember),
+			newMaxActiveUsersConfigMap("1"), basicNSTemplateTier)
+		createMemberCluster(r.client)
+		defer clearMemberClusters(r.client)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+		assertWaitlisted(t, r, userSignup, "waitlist position: 1")
+		assertMasterUserRecordCount(t, r, 1) // only the existing MUR
+	})
+
+	t.Run("waitlist signup after the signups already waitlisted", func(t *testing.T) {
+		// given
+		now := time.Now()
+		first := newWaitlistedUserSignup("first", now.Add(-2*time.Hour))
+		second := newWaitlistedUserSignup("second", now.Add(-time.Hour))
+		userSignup := newUserSignupCreatedAt("foo", now)
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, first, second, newMasterUserRecordInCluster("bar", nameMember),
+			newMaxActiveUsersConfigMap("1"), basicNSTemplateTier)
+		createMemberCluster(r.client)
+		defer clearMemberClusters(r.client)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assertWaitlisted(t, r, userSignup, "waitlist position: 3")
+	})
+
+	t.Run("keep the order of the waitlist", func(t *testing.T) {
+		// given
+		now := time.Now()
+		first := newWaitlistedUserSignup("first", now.Add(-time.Hour))
+		userSignup := newUserSignupCreatedAt("foo", now)
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, first, newMasterUserRecordInCluster("bar", nameMember),
+			newMaxActiveUsersConfigMap("2"), basicNSTemplateTier)
+		createMemberCluster(r.client)
+		defer clearMemberClusters(r.client)
+
+		// when
+		_, err := r.Reconcile(req)
+		require.NoError(t, err)
+		_, err = r.Reconcile(newReconcileRequest(first.Name))
+
+		// then
+		require.NoError(t, err)
+		// the signup must wait until the first waitlisted signup is provisioned
+		assertWaitlisted(t, r, userSignup, "waitlist position: 2")
+		assertMasterUserRecordCount(t, r, 2)
+		murs := &v1alpha1.MasterUserRecordList{}
+		err = r.client.List(context.TODO(), murs)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"bar", "first-at-redhat-com"}, []string{murs.Items[0].Name, murs.Items[1].Name})
+	})
+
+	t.Run("provision waitlisted signup when capacity was freed", func(t *testing.T) {
+		// given
+		userSignup := newWaitlistedUserSignup("foo", time.Now())
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, newMaxActiveUsersConfigMap("1"), basicNSTemplateTier)
+		createMemberCluster(r.client)
+		defer clearMemberClusters(r.client)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assertMasterUserRecordCount(t, r, 1)
+	})
+
+	t.Run("waitlist signup when the member cluster is full and provision it when capacity was freed", func(t *testing.T) {
+		// given
+		mur := newMasterUserRecordInCluster("bar", nameMember)
+		userSignup := newUserSignupCreatedAt("foo", time.Now())
+		cm := configMap(config.UserApprovalPolicyAutomatic)
+		cm.Data[config.ToolchainConfigMapMaxUsersPerCluster] = "1"
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, mur, cm, basicNSTemplateTier)
+		createMemberCluster(r.client)
+		defer clearMemberClusters(r.client)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+		assertWaitlisted(t, r, userSignup, "waitlist position: 1, no member cluster has capacity left")
+		assertMasterUserRecordCount(t, r, 1) // only the existing MUR
+
+		t.Run("provision the signup when the MasterUserRecord was deleted", func(t *testing.T) {
+			// given
+			err := r.client.Delete(context.TODO(), mur)
+			require.NoError(t, err)
+			requests := waitlistMapper{client: r.client}.Map(handler.MapObject{Meta: mur, Object: mur})
+			require.Equal(t, []reconcile.Request{req}, requests)
+
+			// when
+			_, err = r.Reconcile(requests[0])
+
+			// then
+			require.NoError(t, err)
+			assertMasterUserRecordCount(t, r, 1)
+			err = r.client.Get(context.TODO(), types.NamespacedName{Name: "foo-at-redhat-com", Namespace: operatorNamespace}, &v1alpha1.MasterUserRecord{})
+			require.NoError(t, err)
+		})
+	})
+
+	t.Run("invalid max active users", func(t *testing.T) {
+		// given
+		userSignup := newUserSignupCreatedAt("foo", time.Now())
+		r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, newMaxActiveUsersConfigMap("-1"), basicNSTemplateTier)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.EqualError(t, err, ": invalid value for 'max-active-users': '-1'")
+		assertMasterUserRecordCount(t, r, 0)
+	})
+}
+
+func TestWaitlistMapper(t *testing.T) {
+	// given
+	now := time.Now()
+	first := newWaitlistedUserSignup("first", now.Add(-2*time.Hour))
+	second := newWaitlistedUserSignup("second", now.Add(-time.Hour))
+	provisioned := newUserSignupCreatedAt("provisioned", now.Add(-3*time.Hour))
+	mur := newMasterUserRecordInCluster("bar", nameMember)
+	_, _, cl := prepareReconcile(t, "", second, provisioned, first)
+	mapper := waitlistMapper{client: cl}
+
+	// when
+	requests := mapper.Map(handler.MapObject{Meta: mur, Object: mur})
+
+	// then
+	assert.Equal(t, []reconcile.Request{newReconcileRequest("first"), newReconcileRequest("second")}, requests)
+}
+
+func newUserSignupCreatedAt(name string, created time.Time) *v1alpha1.UserSignup {
+	return &v1alpha1.UserSignup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         operatorNamespace,
+			UID:               types.UID(uuid.NewV4().String()),
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: v1alpha1.UserSignupSpec{
+			Username: name + "@redhat.com",
+		},
+	}
+}
+
+func newWaitlistedUserSignup(name string, created time.Time) *v1alpha1.UserSignup {
+	userSignup := newUserSignupCreatedAt(name, created)
+	userSignup.Status.Conditions = []v1alpha1.Condition{
+		{
+			Type:   v1alpha1.UserSignupApproved,
+			Status: v1.ConditionTrue,
+			Reason: "ApprovedAutomatically",
+		},
+		{
+			Type:    v1alpha1.UserSignupComplete,
+			Status:  v1.ConditionFalse,
+			Reason:  "Waitlisted",
+			Message: "waitlist position: 1",
+		},
+	}
+	return userSignup
+}
+
+func assertWaitlisted(t *testing.T, r *ReconcileUserSignup, userSignup *v1alpha1.UserSignup, message string) {
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: userSignup.Name, Namespace: userSignup.Namespace}, userSignup)
+	require.NoError(t, err)
+	test.AssertConditionsMatch(t, userSignup.Status.Conditions,
+		v1alpha1.Condition{
+			Type:   v1alpha1.UserSignupApproved,
+			Status: v1.ConditionTrue,
+			Reason: "ApprovedAutomatically",
+		},
+		v1alpha1.Condition{
+			Type:    v1alpha1.UserSignupComplete,
+			Status:  v1.ConditionFalse,
+			Reason:  "Waitlisted",
+			Message: message,
+		})
+}
+
+func assertMasterUserRecordCount(t *testing.T, r *ReconcileUserSignup, expected int) {
+	murs := &v1alpha1.MasterUserRecordList{}
+	err := r.client.List(context.TODO(), murs)
+	require.NoError(t, err)
+	require.Len(t, murs.Items, expected)
+}
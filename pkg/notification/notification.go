@@ -0,0 +1,91 @@
+package notification
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// Type the type of a notification
+type Type string
+
+const (
+	// Approved the notification sent when the signup of the user was approved
+	Approved Type = "approved"
+	// Provisioned the notification sent when the account of the user is ready
+	Provisioned Type = "provisioned"
+	// Expiring the notification sent when the account of the user is about to be deactivated
+	Expiring Type = "expiring"
+	// Deactivated the notification sent when the account of the user was deactivated
+	Deactivated Type = "deactivated"
+)
+
+// Notification a message to send to a user
+type Notification struct {
+	Type      Type
+	Recipient string
+	Subject   string
+	Body      string
+}
+
+// Notifier sends notifications to the users
+type Notifier interface {
+	Notify(notification Notification) error
+}
+
+// Context the values available in the templates of the notifications
+type Context struct {
+	// Username the compliant username of the user
+	Username string
+	// ExpiryTime the time at which the account of the user will be deactivated (if any)
+	ExpiryTime string
+}
+
+type messageTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+var messageTemplates = map[Type]messageTemplate{
+	Approved: newMessageTemplate("Your account request was approved",
+		"Hello {{.Username}},\n\nyour account request was approved and your account is being provisioned. "+
+			"You will receive another notification once it is ready.\n"),
+	Provisioned: newMessageTemplate("Your account is ready",
+		"Hello {{.Username}},\n\nyour account is provisioned and ready to use.\n"+
+			"{{if .ExpiryTime}}Please note that it will be deactivated on {{.ExpiryTime}}.\n{{end}}"),
+	Expiring: newMessageTemplate("Your account will be deactivated soon",
+		"Hello {{.Username}},\n\nyour account will be deactivated on {{.ExpiryTime}}.\n"),
+	Deactivated: newMessageTemplate("Your account was deactivated",
+		"Hello {{.Username}},\n\nyour account was deactivated.\n"),
+}
+
+func newMessageTemplate(subject, body string) messageTemplate {
+	return messageTemplate{
+		subject: template.Must(template.New("subject").Parse(subject)),
+		body:    template.Must(template.New("body").Parse(body)),
+	}
+}
+
+// New returns a new notification of the given type for the recipient, with a subject and a body rendered
+// from the template of the notification type and the given context
+func New(notificationType Type, recipient string, ctx Context) (Notification, error) {
+	tmpl, found := messageTemplates[notificationType]
+	if !found {
+		return Notification{}, errors.Errorf("unknown notification type '%s'", notificationType)
+	}
+	subject := &bytes.Buffer{}
+	if err := tmpl.subject.Execute(subject, ctx); err != nil {
+		return Notification{}, errors.Wrapf(err, "unable to render the subject of the '%s' notification", notificationType)
+	}
+	body := &bytes.Buffer{}
+	if err := tmpl.body.Execute(body, ctx); err != nil {
+		return Notification{}, errors.Wrapf(err, "unable to render the body of the '%s' notification", notificationType)
+	}
+	return Notification{
+		Type:      notificationType,
+		Recipient: recipient,
+		Subject:   subject.String(),
+		Body:      body.String(),
+	}, nil
+}
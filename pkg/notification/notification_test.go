@@ -0,0 +1,59 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+
+	t.Run("render approved notification", func(t *testing.T) {
+		// when
+		n, err := New(Approved, "foo@redhat.com", Context{Username: "foo"})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, Approved, n.Type)
+		assert.Equal(t, "foo@redhat.com", n.Recipient)
+		assert.Equal(t, "Your account request was approved", n.Subject)
+		assert.Contains(t, n.Body, "Hello foo,")
+	})
+
+	t.Run("render provisioned notification with expiry time", func(t *testing.T) {
+		// when
+		n, err := New(Provisioned, "foo@redhat.com", Context{Username: "foo", ExpiryTime: "2020-01-31T00:00:00Z"})
+
+		// then
+		require.NoError(t, err)
+		assert.Contains(t, n.Body, "deactivated on 2020-01-31T00:00:00Z")
+	})
+
+	t.Run("render provisioned notification without expiry time", func(t *testing.T) {
+		// when
+		n, err := New(Provisioned, "foo@redhat.com", Context{Username: "foo"})
+
+		// then
+		require.NoError(t, err)
+		assert.NotContains(t, n.Body, "deactivated")
+	})
+
+	t.Run("render expiring notification", func(t *testing.T) {
+		// when
+		n, err := New(Expiring, "foo@redhat.com", Context{Username: "foo", ExpiryTime: "2020-01-31T00:00:00Z"})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "Your account will be deactivated soon", n.Subject)
+		assert.Contains(t, n.Body, "2020-01-31T00:00:00Z")
+	})
+
+	t.Run("unknown notification type", func(t *testing.T) {
+		// when
+		_, err := New(Type("unknown"), "foo@redhat.com", Context{Username: "foo"})
+
+		// then
+		require.EqualError(t, err, "unknown notification type 'unknown'")
+	})
+}
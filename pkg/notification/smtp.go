@@ -0,0 +1,72 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/codeready-toolchain/host-operator/pkg/config"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SMTPNotifier sends the notifications by email, via an SMTP server
+type SMTPNotifier struct {
+	address string
+	sender  string
+	auth    smtp.Auth
+}
+
+var _ Notifier = &SMTPNotifier{}
+
+// NewSMTPNotifier returns a new SMTPNotifier which sends the emails via the SMTP server at the given address
+// (`host:port`), on behalf of the given sender. The auth can be nil if the SMTP server requires no authentication.
+func NewSMTPNotifier(address, sender string, auth smtp.Auth) *SMTPNotifier {
+	return &SMTPNotifier{
+		address: address,
+		sender:  sender,
+		auth:    auth,
+	}
+}
+
+// Notify sends the notification by email to its recipient
+func (n *SMTPNotifier) Notify(notification Notification) error {
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=\"utf-8\"\r\n\r\n%s",
+		headerValue(n.sender), headerValue(notification.Recipient), headerValue(notification.Subject),
+		strings.ReplaceAll(notification.Body, "\n", "\r\n"))
+	return smtp.SendMail(n.address, n.auth, n.sender, []string{notification.Recipient}, []byte(msg))
+}
+
+// headerValue removes the line breaks from the given value, so that it cannot be used to inject other headers
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
+
+// NewSMTPNotifierFromConfig returns a new SMTPNotifier configured with the SMTP server address and the sender
+// of the given toolchain configuration, and with the credentials in the notification Secret (if it exists) of the given namespace.
+// Returns nil if no SMTP server address is configured, meaning that no notification should be sent.
+func NewSMTPNotifierFromConfig(cl client.Client, namespace string, cfg config.ToolchainConfig) (Notifier, error) {
+	address := cfg.NotificationSMTPAddress()
+	if address == "" {
+		return nil, nil
+	}
+	var auth smtp.Auth
+	secret := &corev1.Secret{}
+	if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: config.NotificationSecretName}, secret); err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	} else {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		auth = smtp.PlainAuth("", string(secret.Data[config.NotificationSecretUsername]), string(secret.Data[config.NotificationSecretPassword]), host)
+	}
+	return NewSMTPNotifier(address, cfg.NotificationSender(), auth), nil
+}
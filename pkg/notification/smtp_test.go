@@ -0,0 +1,245 @@
+package notification
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/codeready-toolchain/host-operator/pkg/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSMTPNotifier(t *testing.T) {
+
+	t.Run("send email", func(t *testing.T) {
+		// given
+		server := newFakeSMTPServer(t)
+		defer server.close()
+		notifier := NewSMTPNotifier(server.address(), "noreply@redhat.com", nil)
+		n, err := New(Approved, "foo@redhat.com", Context{Username: "foo"})
+		require.NoError(t, err)
+
+		// when
+		err = notifier.Notify(n)
+
+		// then
+		require.NoError(t, err)
+		msg := <-server.messages
+		assert.Equal(t, "<noreply@redhat.com>", msg.from)
+		assert.Equal(t, []string{"<foo@redhat.com>"}, msg.recipients)
+		assert.Contains(t, msg.data, "Subject: Your account request was approved\r\n")
+		assert.Contains(t, msg.data, "Hello foo,\r\n")
+	})
+
+	t.Run("remove line breaks from headers", func(t *testing.T) {
+		// given
+		server := newFakeSMTPServer(t)
+		defer server.close()
+		notifier := NewSMTPNotifier(server.address(), "noreply@redhat.com", nil)
+
+		// when
+		err := notifier.Notify(Notification{
+			Type:      Approved,
+			Recipient: "foo@redhat.com",
+			Subject:   "subject\r\nBcc: bar@redhat.com",
+			Body:      "body",
+		})
+
+		// then
+		require.NoError(t, err)
+		msg := <-server.messages
+		assert.NotContains(t, msg.data, "\r\nBcc:")
+	})
+
+	t.Run("server unavailable", func(t *testing.T) {
+		// given
+		server := newFakeSMTPServer(t)
+		server.close()
+		notifier := NewSMTPNotifier(server.address(), "noreply@redhat.com", nil)
+
+		// when
+		err := notifier.Notify(Notification{Type: Approved, Recipient: "foo@redhat.com"})
+
+		// then
+		require.Error(t, err)
+	})
+}
+
+func TestNewSMTPNotifierFromConfig(t *testing.T) {
+
+	t.Run("no smtp address", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+
+		// when
+		notifier, err := NewSMTPNotifierFromConfig(cl, test.HostOperatorNs, config.NewToolchainConfig(nil))
+
+		// then
+		require.NoError(t, err)
+		assert.Nil(t, notifier)
+	})
+
+	t.Run("without credentials", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cfg := config.NewToolchainConfig(map[string]string{
+			config.ToolchainConfigMapNotificationSMTPAddress: "smtp.redhat.com:25",
+			config.ToolchainConfigMapNotificationSender:      "noreply@redhat.com",
+		})
+
+		// when
+		notifier, err := NewSMTPNotifierFromConfig(cl, test.HostOperatorNs, cfg)
+
+		// then
+		require.NoError(t, err)
+		require.IsType(t, &SMTPNotifier{}, notifier)
+		smtpNotifier := notifier.(*SMTPNotifier)
+		assert.Equal(t, "smtp.redhat.com:25", smtpNotifier.address)
+		assert.Equal(t, "noreply@redhat.com", smtpNotifier.sender)
+		assert.Nil(t, smtpNotifier.auth)
+	})
+
+	t.Run("with credentials", func(t *testing.T) {
+		// given
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      config.NotificationSecretName,
+				Namespace: test.HostOperatorNs,
+			},
+			Data: map[string][]byte{
+				config.NotificationSecretUsername: []byte("user"),
+				config.NotificationSecretPassword: []byte("secret"),
+			},
+		}
+		cl := test.NewFakeClient(t, secret)
+		cfg := config.NewToolchainConfig(map[string]string{
+			config.ToolchainConfigMapNotificationSMTPAddress: "smtp.redhat.com:587",
+			config.ToolchainConfigMapNotificationSender:      "noreply@redhat.com",
+		})
+
+		// when
+		notifier, err := NewSMTPNotifierFromConfig(cl, test.HostOperatorNs, cfg)
+
+		// then
+		require.NoError(t, err)
+		require.IsType(t, &SMTPNotifier{}, notifier)
+		assert.NotNil(t, notifier.(*SMTPNotifier).auth)
+	})
+
+	t.Run("invalid smtp address with credentials", func(t *testing.T) {
+		// given
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      config.NotificationSecretName,
+				Namespace: test.HostOperatorNs,
+			},
+		}
+		cl := test.NewFakeClient(t, secret)
+		cfg := config.NewToolchainConfig(map[string]string{
+			config.ToolchainConfigMapNotificationSMTPAddress: "smtp.redhat.com",
+		})
+
+		// when
+		_, err := NewSMTPNotifierFromConfig(cl, test.HostOperatorNs, cfg)
+
+		// then
+		require.Error(t, err)
+	})
+}
+
+type fakeSMTPMessage struct {
+	from       string
+	recipients []string
+	data       string
+}
+
+// fakeSMTPServer a minimal SMTP server which accepts all the messages and sends them to its `messages` channel
+type fakeSMTPServer struct {
+	listener net.Listener
+	messages chan fakeSMTPMessage
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &fakeSMTPServer{
+		listener: listener,
+		messages: make(chan fakeSMTPMessage, 10),
+	}
+	go server.serve()
+	return server
+}
+
+func (s *fakeSMTPServer) address() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeSMTPServer) close() {
+	s.listener.Close()
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		_, _ = conn.Write([]byte(line + "\r\n"))
+	}
+	reply("220 localhost fake SMTP server")
+	msg := fakeSMTPMessage{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			msg.from = line[len("MAIL FROM:"):]
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			msg.recipients = append(msg.recipients, line[len("RCPT TO:"):])
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			data := &strings.Builder{}
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			msg.data = data.String()
+			s.messages <- msg
+			msg = fakeSMTPMessage{}
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
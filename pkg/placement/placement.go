@@ -0,0 +1,293 @@
+package placement
+
+import (
+	"context"
+	"sort"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+	"sigs.k8s.io/kubefed/pkg/controller/util"
+)
+
+var (
+	// ErrNoMemberClusters is returned when there is no member cluster in the registry
+	ErrNoMemberClusters = errors.New("no member clusters found")
+	// ErrNoCapacity is returned when none of the member clusters is ready and able to host a new user
+	ErrNoCapacity = errors.New("no member cluster is ready and has capacity left")
+	// ErrNoMatchingCluster is returned when none of the member clusters has the labels required by the placement rule
+	ErrNoMatchingCluster = errors.New("no member cluster matches the placement rule")
+)
+
+const (
+	// UnschedulableAnnotationKey the annotation to set (with the `true` value) on a KubeFedCluster to cordon its member
+	// cluster, ie, to prevent new users from being provisioned in it. The existing users are not affected.
+	UnschedulableAnnotationKey = "toolchain.dev.openshift.com/unschedulable"
+	// DrainAnnotationKey the annotation to set (with the `true` value) on a KubeFedCluster to drain its member cluster,
+	// ie, to move its existing users to other member clusters. A draining member cluster is also unschedulable.
+	DrainAnnotationKey = "toolchain.dev.openshift.com/drain"
+)
+
+// IsUnschedulable returns true if no new users should be provisioned in the member cluster of the given KubeFedCluster,
+// ie, if the KubeFedCluster is cordoned or draining
+func IsUnschedulable(kubeFedCluster v1beta1.KubeFedCluster) bool {
+	return kubeFedCluster.Annotations[UnschedulableAnnotationKey] == "true" || IsDraining(kubeFedCluster)
+}
+
+// IsDraining returns true if the users of the member cluster of the given KubeFedCluster should be moved to other
+// member clusters
+func IsDraining(kubeFedCluster v1beta1.KubeFedCluster) bool {
+	return kubeFedCluster.Annotations[DrainAnnotationKey] == "true"
+}
+
+// Candidate a member cluster which may host a new user
+type Candidate struct {
+	// Name the name of the member cluster
+	Name string
+	// Ready true if the member cluster is ready (according to its KubeFedCluster status)
+	Ready bool
+	// UserAccounts the number of UserAccounts provisioned (or being provisioned) in the member cluster
+	UserAccounts int
+	// MaxUserAccounts the maximum number of UserAccounts allowed in the member cluster (0 means unlimited)
+	MaxUserAccounts int
+	// Labels the labels of the member cluster's KubeFedCluster resource
+	Labels map[string]string
+	// Unschedulable true if the member cluster is cordoned or draining (according to its KubeFedCluster annotations)
+	Unschedulable bool
+}
+
+// HasCapacity returns true if the candidate is ready, schedulable and has not reached its maximum number of UserAccounts
+func (c Candidate) HasCapacity() bool {
+	return c.Ready && !c.Unschedulable && (c.MaxUserAccounts <= 0 || c.UserAccounts < c.MaxUserAccounts)
+}
+
+// Strategy picks the target cluster among the given candidates (sorted by name).
+// It returns the name of the selected candidate, or an empty string if none of the candidates is eligible.
+type Strategy interface {
+	Select(candidates []Candidate) string
+}
+
+// StrategyFunc an adapter to allow the use of ordinary functions as a placement Strategy
+type StrategyFunc func(candidates []Candidate) string
+
+// Select calls f(candidates)
+func (f StrategyFunc) Select(candidates []Candidate) string {
+	return f(candidates)
+}
+
+// LeastUtilized selects the ready cluster with capacity left which hosts the lowest number of UserAccounts.
+// In case of a tie, the first candidate (by name) is selected.
+var LeastUtilized = StrategyFunc(func(candidates []Candidate) string {
+	selected := -1
+	for i, c := range candidates {
+		if !c.HasCapacity() {
+			continue
+		}
+		if selected < 0 || c.UserAccounts < candidates[selected].UserAccounts {
+			selected = i
+		}
+	}
+	if selected < 0 {
+		return ""
+	}
+	return candidates[selected].Name
+})
+
+// Placement selects the member cluster in which a new user should be provisioned
+type Placement struct {
+	client            client.Client
+	getMemberClusters func() []*cluster.FedCluster
+	strategy          Strategy
+}
+
+// New returns a new Placement which uses the given strategy to pick a cluster among the
+// member clusters returned by the `getMemberClusters` func
+func New(cl client.Client, getMemberClusters func() []*cluster.FedCluster, strategy Strategy) *Placement {
+	return &Placement{
+		client:            cl,
+		getMemberClusters: getMemberClusters,
+		strategy:          strategy,
+	}
+}
+
+// SelectTargetCluster returns the name of the member cluster in which a new user should be provisioned.
+// The number of UserAccounts per cluster is computed from the MasterUserRecords in the given namespace,
+// and `maxUserAccounts` is the maximum number of UserAccounts per member cluster (0 means unlimited).
+// If a placement rule is given, then only the member clusters which have the rule's required labels are
+// considered, and the ones with the most of the rule's preferred labels are selected in priority.
+// Returns ErrNoMemberClusters if there is no member cluster at all, ErrNoMatchingCluster if no member
+// cluster has the labels required by the rule, or ErrNoCapacity if no eligible member cluster is ready
+// and has capacity left.
+func (p *Placement) SelectTargetCluster(namespace string, maxUserAccounts int, rule *Rule) (string, error) {
+	candidates, err := p.Candidates(namespace, maxUserAccounts)
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) == 0 {
+		return "", ErrNoMemberClusters
+	}
+	if rule == nil {
+		return p.selectCandidate(candidates, nil, nil)
+	}
+	return p.selectCandidate(candidates, rule.RequiredClusterLabels, rule.PreferredClusterLabels)
+}
+
+// SelectTargetClusters returns the names of the member clusters in which the accounts of a new user should be
+// provisioned: one cluster per account declared in the placement rule, or a single cluster (as selected by
+// SelectTargetCluster) if the rule is nil or declares no account. Each account is provisioned in a distinct
+// member cluster, selected with the account's required and preferred labels.
+// Returns the same errors as SelectTargetCluster if no cluster can be selected for one of the accounts.
+func (p *Placement) SelectTargetClusters(namespace string, maxUserAccounts int, rule *Rule) ([]string, error) {
+	if rule == nil || len(rule.Accounts) == 0 {
+		selected, err := p.SelectTargetCluster(namespace, maxUserAccounts, rule)
+		if err != nil {
+			return nil, err
+		}
+		return []string{selected}, nil
+	}
+	candidates, err := p.Candidates(namespace, maxUserAccounts)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoMemberClusters
+	}
+	selected := make([]string, 0, len(rule.Accounts))
+	for _, account := range rule.Accounts {
+		name, err := p.selectCandidate(without(candidates, selected), account.RequiredClusterLabels, account.PreferredClusterLabels)
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, name)
+	}
+	return selected, nil
+}
+
+// SelectTargetClusterExcept returns the name of the member cluster to which the UserAccount of an existing user
+// should be moved, ignoring the given member clusters (typically, the ones in which the user already has a UserAccount).
+// Returns the same errors as SelectTargetCluster if no cluster can be selected.
+func (p *Placement) SelectTargetClusterExcept(namespace string, maxUserAccounts int, excluded []string) (string, error) {
+	candidates, err := p.Candidates(namespace, maxUserAccounts)
+	if err != nil {
+		return "", err
+	}
+	if candidates = without(candidates, excluded); len(candidates) == 0 {
+		return "", ErrNoMemberClusters
+	}
+	return p.selectCandidate(candidates, nil, nil)
+}
+
+// selectCandidate selects a candidate among the ones which have all the required labels, trying first the ones
+// with the most preferred labels
+func (p *Placement) selectCandidate(candidates []Candidate, required, preferred map[string]string) (string, error) {
+	if len(required) == 0 && len(preferred) == 0 {
+		if selected := p.strategy.Select(candidates); selected != "" {
+			return selected, nil
+		}
+		return "", ErrNoCapacity
+	}
+	candidates = filter(candidates, required)
+	if len(candidates) == 0 {
+		return "", ErrNoMatchingCluster
+	}
+	// group the candidates by score, and try the groups with the highest score first
+	groups := map[int][]Candidate{}
+	scores := []int{}
+	for _, c := range candidates {
+		s := score(c, preferred)
+		if _, exists := groups[s]; !exists {
+			scores = append(scores, s)
+		}
+		groups[s] = append(groups[s], c)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(scores)))
+	for _, s := range scores {
+		if selected := p.strategy.Select(groups[s]); selected != "" {
+			return selected, nil
+		}
+	}
+	return "", ErrNoCapacity
+}
+
+// without returns the candidates whose name is not in the given list
+func without(candidates []Candidate, names []string) []Candidate {
+	remaining := []Candidate{}
+	for _, c := range candidates {
+		excluded := false
+		for _, name := range names {
+			if c.Name == name {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			remaining = append(remaining, c)
+		}
+	}
+	return remaining
+}
+
+// Candidates returns all the member clusters (sorted by name) along with their readiness, their schedulability and
+// their number of UserAccounts, as computed from the MasterUserRecords in the given namespace
+func (p *Placement) Candidates(namespace string, maxUserAccounts int) ([]Candidate, error) {
+	members := p.getMemberClusters()
+	if len(members) == 0 {
+		return nil, nil
+	}
+	userAccounts, err := p.countUserAccounts(namespace)
+	if err != nil {
+		return nil, err
+	}
+	kubeFedClusters, err := p.kubeFedClusters(namespace)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]Candidate, len(members))
+	for i, member := range members {
+		kubeFedCluster := kubeFedClusters[member.Name]
+		candidates[i] = Candidate{
+			Name:            member.Name,
+			Ready:           member.ClusterStatus != nil && util.IsClusterReady(member.ClusterStatus),
+			UserAccounts:    userAccounts[member.Name],
+			MaxUserAccounts: maxUserAccounts,
+			Labels:          kubeFedCluster.Labels,
+			Unschedulable:   IsUnschedulable(kubeFedCluster),
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Name < candidates[j].Name
+	})
+	return candidates, nil
+}
+
+// countUserAccounts returns the number of UserAccounts per target cluster, based on the
+// MasterUserRecords in the given namespace
+func (p *Placement) countUserAccounts(namespace string) (map[string]int, error) {
+	murs := &toolchainv1alpha1.MasterUserRecordList{}
+	if err := p.client.List(context.TODO(), murs, client.InNamespace(namespace)); err != nil {
+		return nil, errors.Wrap(err, "unable to list the MasterUserRecords")
+	}
+	counts := map[string]int{}
+	for _, mur := range murs.Items {
+		for _, ua := range mur.Spec.UserAccounts {
+			counts[ua.TargetCluster]++
+		}
+	}
+	return counts, nil
+}
+
+// kubeFedClusters returns the KubeFedClusters in the given namespace, indexed by cluster name
+func (p *Placement) kubeFedClusters(namespace string) (map[string]v1beta1.KubeFedCluster, error) {
+	kubeFedClusters := &v1beta1.KubeFedClusterList{}
+	if err := p.client.List(context.TODO(), kubeFedClusters, client.InNamespace(namespace)); err != nil {
+		return nil, errors.Wrap(err, "unable to list the KubeFedClusters")
+	}
+	byName := make(map[string]v1beta1.KubeFedCluster, len(kubeFedClusters.Items))
+	for _, kubeFedCluster := range kubeFedClusters.Items {
+		byName[kubeFedCluster.Name] = kubeFedCluster
+	}
+	return byName, nil
+}
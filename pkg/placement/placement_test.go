@@ -0,0 +1,495 @@
+package placement
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/apis"
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kubefed/pkg/apis/core/common"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+)
+
+func TestLeastUtilized(t *testing.T) {
+
+	t.Run("select cluster with lowest number of user accounts", func(t *testing.T) {
+		// when
+		selected := LeastUtilized.Select([]Candidate{
+			{Name: "member1", Ready: true, UserAccounts: 3},
+			{Name: "member2", Ready: true, UserAccounts: 1},
+			{Name: "member3", Ready: true, UserAccounts: 2},
+		})
+		// then
+		assert.Equal(t, "member2", selected)
+	})
+
+	t.Run("select first cluster in case of tie", func(t *testing.T) {
+		// when
+		selected := LeastUtilized.Select([]Candidate{
+			{Name: "member1", Ready: true, UserAccounts: 1},
+			{Name: "member2", Ready: true, UserAccounts: 1},
+		})
+		// then
+		assert.Equal(t, "member1", selected)
+	})
+
+	t.Run("skip clusters which are not ready or full", func(t *testing.T) {
+		// when
+		selected := LeastUtilized.Select([]Candidate{
+			{Name: "member1", Ready: false, UserAccounts: 0},
+			{Name: "member2", Ready: true, UserAccounts: 2, MaxUserAccounts: 2},
+			{Name: "member3", Ready: true, UserAccounts: 5, MaxUserAccounts: 10},
+		})
+		// then
+		assert.Equal(t, "member3", selected)
+	})
+
+	t.Run("no cluster eligible", func(t *testing.T) {
+		// when
+		selected := LeastUtilized.Select([]Candidate{
+			{Name: "member1", Ready: false},
+			{Name: "member2", Ready: true, UserAccounts: 2, MaxUserAccounts: 2},
+		})
+		// then
+		assert.Empty(t, selected)
+	})
+}
+
+func TestSelectTargetCluster(t *testing.T) {
+	// given
+	s := scheme.Scheme
+	err := apis.AddToScheme(s)
+	require.NoError(t, err)
+	err = v1beta1.AddToScheme(s)
+	require.NoError(t, err)
+	murs := []runtime.Object{
+		newMasterUserRecord("john", "member1"),
+		newMasterUserRecord("jane", "member1"),
+		newMasterUserRecord("jack", "member2"),
+	}
+
+	t.Run("select least utilized cluster", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, murs...)
+		p := New(cl, memberClusters(
+			newFedCluster("member1", v1.ConditionTrue),
+			newFedCluster("member2", v1.ConditionTrue),
+			newFedCluster("member3", v1.ConditionTrue)), LeastUtilized)
+
+		// when
+		selected, err := p.SelectTargetCluster(test.HostOperatorNs, 0, nil)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "member3", selected)
+	})
+
+	t.Run("skip cluster which is not ready", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, murs...)
+		p := New(cl, memberClusters(
+			newFedCluster("member1", v1.ConditionTrue),
+			newFedCluster("member2", v1.ConditionTrue),
+			newFedCluster("member3", v1.ConditionFalse)), LeastUtilized)
+
+		// when
+		selected, err := p.SelectTargetCluster(test.HostOperatorNs, 0, nil)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "member2", selected)
+	})
+
+	t.Run("skip cluster which is full", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, murs...)
+		p := New(cl, memberClusters(
+			newFedCluster("member1", v1.ConditionTrue),
+			newFedCluster("member2", v1.ConditionTrue)), StrategyFunc(func(candidates []Candidate) string {
+			// select the first cluster with capacity left, regardless of its utilization
+			for _, c := range candidates {
+				if c.HasCapacity() {
+					return c.Name
+				}
+			}
+			return ""
+		}))
+
+		// when
+		selected, err := p.SelectTargetCluster(test.HostOperatorNs, 2, nil)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "member2", selected) // member1 already hosts 2 users
+	})
+
+	t.Run("skip clusters which are cordoned or draining", func(t *testing.T) {
+		// given
+		objs := append([]runtime.Object{
+			newAnnotatedKubeFedCluster("member2", map[string]string{UnschedulableAnnotationKey: "true"}),
+			newAnnotatedKubeFedCluster("member3", map[string]string{DrainAnnotationKey: "true"}),
+		}, murs...)
+		cl := test.NewFakeClient(t, objs...)
+		p := New(cl, memberClusters(
+			newFedCluster("member1", v1.ConditionTrue),
+			newFedCluster("member2", v1.ConditionTrue),
+			newFedCluster("member3", v1.ConditionTrue)), LeastUtilized)
+
+		// when
+		selected, err := p.SelectTargetCluster(test.HostOperatorNs, 0, nil)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "member1", selected) // even if it is the most utilized cluster
+	})
+
+	t.Run("all clusters are cordoned", func(t *testing.T) {
+		// given
+		objs := append([]runtime.Object{
+			newAnnotatedKubeFedCluster("member1", map[string]string{UnschedulableAnnotationKey: "true"}),
+		}, murs...)
+		cl := test.NewFakeClient(t, objs...)
+		p := New(cl, memberClusters(newFedCluster("member1", v1.ConditionTrue)), LeastUtilized)
+
+		// when
+		_, err := p.SelectTargetCluster(test.HostOperatorNs, 0, nil)
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, ErrNoCapacity, err)
+	})
+
+	t.Run("all clusters are full", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, murs...)
+		p := New(cl, memberClusters(
+			newFedCluster("member1", v1.ConditionTrue),
+			newFedCluster("member2", v1.ConditionTrue)), LeastUtilized)
+
+		// when
+		_, err := p.SelectTargetCluster(test.HostOperatorNs, 1, nil)
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, ErrNoCapacity, err)
+	})
+
+	t.Run("no member clusters", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, murs...)
+		p := New(cl, memberClusters(), LeastUtilized)
+
+		// when
+		_, err := p.SelectTargetCluster(test.HostOperatorNs, 0, nil)
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, ErrNoMemberClusters, err)
+	})
+
+	t.Run("failed to list the MasterUserRecords", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, murs...)
+		cl.MockList = func(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+			return errors.New("unable to list")
+		}
+		p := New(cl, memberClusters(newFedCluster("member1", v1.ConditionTrue)), LeastUtilized)
+
+		// when
+		_, err := p.SelectTargetCluster(test.HostOperatorNs, 0, nil)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to list the MasterUserRecords")
+	})
+}
+
+func TestSelectTargetClusterWithRule(t *testing.T) {
+	// given
+	s := scheme.Scheme
+	err := apis.AddToScheme(s)
+	require.NoError(t, err)
+	err = v1beta1.AddToScheme(s)
+	require.NoError(t, err)
+	objs := []runtime.Object{
+		newMasterUserRecord("john", "member1"),
+		newKubeFedCluster("member1", map[string]string{"region": "emea", "hardware": "gpu"}),
+		newKubeFedCluster("member2", map[string]string{"region": "emea"}),
+		newKubeFedCluster("member3", map[string]string{"region": "apac"}),
+	}
+	members := memberClusters(
+		newFedCluster("member1", v1.ConditionTrue),
+		newFedCluster("member2", v1.ConditionTrue),
+		newFedCluster("member3", v1.ConditionTrue))
+
+	t.Run("select cluster with required labels", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, objs...)
+		p := New(cl, members, LeastUtilized)
+		rule := &Rule{
+			Name:                  "apac",
+			RequiredClusterLabels: map[string]string{"region": "apac"},
+		}
+
+		// when
+		selected, err := p.SelectTargetCluster(test.HostOperatorNs, 0, rule)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "member3", selected)
+	})
+
+	t.Run("select cluster with preferred labels", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, objs...)
+		p := New(cl, members, LeastUtilized)
+		rule := &Rule{
+			Name:                   "emea-gpu",
+			RequiredClusterLabels:  map[string]string{"region": "emea"},
+			PreferredClusterLabels: map[string]string{"hardware": "gpu"},
+		}
+
+		// when
+		selected, err := p.SelectTargetCluster(test.HostOperatorNs, 0, rule)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "member1", selected) // even if it is not the least utilized cluster
+	})
+
+	t.Run("fall back to cluster without preferred labels when full", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, objs...)
+		p := New(cl, members, LeastUtilized)
+		rule := &Rule{
+			Name:                   "emea-gpu",
+			RequiredClusterLabels:  map[string]string{"region": "emea"},
+			PreferredClusterLabels: map[string]string{"hardware": "gpu"},
+		}
+
+		// when
+		selected, err := p.SelectTargetCluster(test.HostOperatorNs, 1, rule)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "member2", selected)
+	})
+
+	t.Run("no cluster with required labels", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, objs...)
+		p := New(cl, members, LeastUtilized)
+		rule := &Rule{
+			Name:                  "us",
+			RequiredClusterLabels: map[string]string{"region": "us"},
+		}
+
+		// when
+		_, err := p.SelectTargetCluster(test.HostOperatorNs, 0, rule)
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, ErrNoMatchingCluster, err)
+	})
+}
+
+func TestSelectTargetClusters(t *testing.T) {
+	// given
+	s := scheme.Scheme
+	err := apis.AddToScheme(s)
+	require.NoError(t, err)
+	err = v1beta1.AddToScheme(s)
+	require.NoError(t, err)
+	objs := []runtime.Object{
+		newMasterUserRecord("john", "member1"),
+		newKubeFedCluster("member1", map[string]string{"env": "dev", "region": "emea"}),
+		newKubeFedCluster("member2", map[string]string{"env": "dev", "region": "apac"}),
+		newKubeFedCluster("member3", map[string]string{"env": "prod", "region": "emea"}),
+	}
+	members := memberClusters(
+		newFedCluster("member1", v1.ConditionTrue),
+		newFedCluster("member2", v1.ConditionTrue),
+		newFedCluster("member3", v1.ConditionTrue))
+
+	t.Run("single cluster without rule", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, objs...)
+		p := New(cl, members, LeastUtilized)
+
+		// when
+		selected, err := p.SelectTargetClusters(test.HostOperatorNs, 0, nil)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{"member2"}, selected)
+	})
+
+	t.Run("one cluster per account", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, objs...)
+		p := New(cl, members, LeastUtilized)
+		rule := &Rule{
+			Name: "dev-and-prod",
+			Accounts: []AccountPlacement{
+				{
+					RequiredClusterLabels:  map[string]string{"env": "dev"},
+					PreferredClusterLabels: map[string]string{"region": "emea"},
+				},
+				{
+					RequiredClusterLabels: map[string]string{"env": "prod"},
+				},
+			},
+		}
+
+		// when
+		selected, err := p.SelectTargetClusters(test.HostOperatorNs, 0, rule)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{"member1", "member3"}, selected)
+	})
+
+	t.Run("distinct cluster for each account", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, objs...)
+		p := New(cl, members, LeastUtilized)
+		rule := &Rule{
+			Name: "two-dev",
+			Accounts: []AccountPlacement{
+				{RequiredClusterLabels: map[string]string{"env": "dev"}},
+				{RequiredClusterLabels: map[string]string{"env": "dev"}},
+			},
+		}
+
+		// when
+		selected, err := p.SelectTargetClusters(test.HostOperatorNs, 0, rule)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{"member2", "member1"}, selected)
+	})
+
+	t.Run("no cluster for one of the accounts", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, objs...)
+		p := New(cl, members, LeastUtilized)
+		rule := &Rule{
+			Name: "two-prod",
+			Accounts: []AccountPlacement{
+				{RequiredClusterLabels: map[string]string{"env": "prod"}},
+				{RequiredClusterLabels: map[string]string{"env": "prod"}},
+			},
+		}
+
+		// when
+		_, err := p.SelectTargetClusters(test.HostOperatorNs, 0, rule)
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, ErrNoMatchingCluster, err)
+	})
+}
+
+func TestSelectTargetClusterExcept(t *testing.T) {
+	// given
+	s := scheme.Scheme
+	err := apis.AddToScheme(s)
+	require.NoError(t, err)
+	err = v1beta1.AddToScheme(s)
+	require.NoError(t, err)
+	objs := []runtime.Object{
+		newMasterUserRecord("john", "member1"),
+		newMasterUserRecord("jane", "member2"),
+		newAnnotatedKubeFedCluster("member1", map[string]string{DrainAnnotationKey: "true"}),
+	}
+	members := memberClusters(
+		newFedCluster("member1", v1.ConditionTrue),
+		newFedCluster("member2", v1.ConditionTrue),
+		newFedCluster("member3", v1.ConditionTrue))
+
+	t.Run("select least utilized cluster among the remaining ones", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, objs...)
+		p := New(cl, members, LeastUtilized)
+
+		// when
+		selected, err := p.SelectTargetClusterExcept(test.HostOperatorNs, 0, []string{"member1", "member3"})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "member2", selected)
+	})
+
+	t.Run("no cluster left", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, objs...)
+		p := New(cl, members, LeastUtilized)
+
+		// when
+		_, err := p.SelectTargetClusterExcept(test.HostOperatorNs, 0, []string{"member2", "member3"})
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, ErrNoCapacity, err) // member1 is draining
+	})
+}
+
+func memberClusters(clusters ...*cluster.FedCluster) func() []*cluster.FedCluster {
+	return func() []*cluster.FedCluster {
+		return clusters
+	}
+}
+
+func newFedCluster(name string, status v1.ConditionStatus) *cluster.FedCluster {
+	return &cluster.FedCluster{
+		Name:              name,
+		Type:              cluster.Member,
+		OperatorNamespace: test.MemberOperatorNs,
+		OwnerClusterName:  test.HostClusterName,
+		ClusterStatus: &v1beta1.KubeFedClusterStatus{
+			Conditions: []v1beta1.ClusterCondition{{
+				Type:   common.ClusterReady,
+				Status: status,
+			}},
+		},
+	}
+}
+
+func newKubeFedCluster(name string, labels map[string]string) *v1beta1.KubeFedCluster {
+	return &v1beta1.KubeFedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: test.HostOperatorNs,
+			Labels:    labels,
+		},
+	}
+}
+
+func newAnnotatedKubeFedCluster(name string, annotations map[string]string) *v1beta1.KubeFedCluster {
+	kubeFedCluster := newKubeFedCluster(name, nil)
+	kubeFedCluster.Annotations = annotations
+	return kubeFedCluster
+}
+
+func newMasterUserRecord(name, targetCluster string) *toolchainv1alpha1.MasterUserRecord {
+	return &toolchainv1alpha1.MasterUserRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: test.HostOperatorNs,
+		},
+		Spec: toolchainv1alpha1.MasterUserRecordSpec{
+			UserAccounts: []toolchainv1alpha1.UserAccountEmbedded{{TargetCluster: targetCluster}},
+		},
+	}
+}
@@ -0,0 +1,119 @@
+package placement
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Rule a placement rule which maps some attributes of a UserSignup to the labels of the KubeFedClusters
+// in which the user should be provisioned
+type Rule struct {
+	// Name the name of the rule, recorded on the UserSignup when the rule was applied
+	Name string `yaml:"name"`
+	// EmailDomains the rule matches a UserSignup whose email belongs to one of these domains (if any)
+	EmailDomains []string `yaml:"emailDomains,omitempty"`
+	// Annotations the rule matches a UserSignup which has all these annotations (if any)
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+	// RequiredClusterLabels the labels that a KubeFedCluster must have to be selected
+	RequiredClusterLabels map[string]string `yaml:"requiredClusterLabels,omitempty"`
+	// PreferredClusterLabels the labels that a KubeFedCluster should have to be selected in priority
+	PreferredClusterLabels map[string]string `yaml:"preferredClusterLabels,omitempty"`
+	// Accounts the placement of each account of the user, when the user should get accounts in several member
+	// clusters (eg: one per region, or one on a dev and one on a prod cluster). When specified, the cluster labels
+	// of the rule itself are ignored, and each account is provisioned in a distinct member cluster.
+	Accounts []AccountPlacement `yaml:"accounts,omitempty"`
+}
+
+// AccountPlacement the labels of the KubeFedCluster in which one of the user's accounts should be provisioned
+type AccountPlacement struct {
+	// RequiredClusterLabels the labels that a KubeFedCluster must have to be selected
+	RequiredClusterLabels map[string]string `yaml:"requiredClusterLabels,omitempty"`
+	// PreferredClusterLabels the labels that a KubeFedCluster should have to be selected in priority
+	PreferredClusterLabels map[string]string `yaml:"preferredClusterLabels,omitempty"`
+}
+
+// ParseRules parses the given YAML content into a list of placement rules
+func ParseRules(content string) ([]Rule, error) {
+	rules := []Rule{}
+	if err := yaml.Unmarshal([]byte(content), &rules); err != nil {
+		return nil, errors.Wrap(err, "unable to parse the placement rules")
+	}
+	for i, rule := range rules {
+		if rule.Name == "" {
+			return nil, errors.Errorf("placement rule at index %d has no name", i)
+		}
+	}
+	return rules, nil
+}
+
+// MatchRule returns the first rule which matches the given email and annotations, or nil if none matches
+func MatchRule(rules []Rule, email string, annotations map[string]string) *Rule {
+	for i := range rules {
+		if rules[i].Matches(email, annotations) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// Matches returns true if the given email and annotations match the rule, ie, if the email belongs to one
+// of the rule's domains (when specified) and all the rule's annotations are present.
+// A rule without any email domain or annotation never matches.
+func (r Rule) Matches(email string, annotations map[string]string) bool {
+	if len(r.EmailDomains) == 0 && len(r.Annotations) == 0 {
+		return false
+	}
+	if len(r.EmailDomains) > 0 && !EmailInDomains(email, r.EmailDomains) {
+		return false
+	}
+	return hasAll(annotations, r.Annotations)
+}
+
+// EmailInDomains returns true if the given email belongs to one of the given domains (case insensitive).
+func EmailInDomains(email string, domains []string) bool {
+	i := strings.LastIndex(email, "@")
+	if i < 0 {
+		return false
+	}
+	emailDomain := strings.ToLower(email[i+1:])
+	for _, domain := range domains {
+		if strings.ToLower(domain) == emailDomain {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the candidates which have all the required labels
+func filter(candidates []Candidate, required map[string]string) []Candidate {
+	filtered := []Candidate{}
+	for _, c := range candidates {
+		if hasAll(c.Labels, required) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// score returns the number of preferred labels that the candidate has
+func score(c Candidate, preferred map[string]string) int {
+	matches := 0
+	for k, v := range preferred {
+		if value, ok := c.Labels[k]; ok && value == v {
+			matches++
+		}
+	}
+	return matches
+}
+
+// hasAll returns true if `values` contains all the entries in `expected`
+func hasAll(values, expected map[string]string) bool {
+	for k, v := range expected {
+		if value, ok := values[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,142 @@
+package placement
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRules(t *testing.T) {
+
+	t.Run("valid rules", func(t *testing.T) {
+		// given
+		content := `
+- name: emea
+  emailDomains:
+  - redhat.com
+  annotations:
+    toolchain.dev.openshift.com/requested-region: emea
+  requiredClusterLabels:
+    region: emea
+  preferredClusterLabels:
+    hardware: gpu
+- name: apac
+  annotations:
+    toolchain.dev.openshift.com/requested-region: apac
+  requiredClusterLabels:
+    region: apac`
+
+		// when
+		rules, err := ParseRules(content)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, rules, 2)
+		assert.Equal(t, Rule{
+			Name:                   "emea",
+			EmailDomains:           []string{"redhat.com"},
+			Annotations:            map[string]string{"toolchain.dev.openshift.com/requested-region": "emea"},
+			RequiredClusterLabels:  map[string]string{"region": "emea"},
+			PreferredClusterLabels: map[string]string{"hardware": "gpu"},
+		}, rules[0])
+		assert.Equal(t, "apac", rules[1].Name)
+	})
+
+	t.Run("rule with several accounts", func(t *testing.T) {
+		// given
+		content := `
+- name: dev-and-prod
+  emailDomains:
+  - redhat.com
+  accounts:
+  - requiredClusterLabels:
+      env: dev
+  - requiredClusterLabels:
+      env: prod
+    preferredClusterLabels:
+      region: emea`
+
+		// when
+		rules, err := ParseRules(content)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+		assert.Equal(t, []AccountPlacement{
+			{
+				RequiredClusterLabels: map[string]string{"env": "dev"},
+			},
+			{
+				RequiredClusterLabels:  map[string]string{"env": "prod"},
+				PreferredClusterLabels: map[string]string{"region": "emea"},
+			},
+		}, rules[0].Accounts)
+	})
+
+	t.Run("empty content", func(t *testing.T) {
+		// when
+		rules, err := ParseRules("")
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, rules)
+	})
+
+	t.Run("invalid content", func(t *testing.T) {
+		// when
+		_, err := ParseRules("foo: bar")
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to parse the placement rules")
+	})
+
+	t.Run("missing name", func(t *testing.T) {
+		// when
+		_, err := ParseRules("- emailDomains: [redhat.com]")
+
+		// then
+		require.EqualError(t, err, "placement rule at index 0 has no name")
+	})
+}
+
+func TestMatchRule(t *testing.T) {
+	// given
+	rules := []Rule{
+		{
+			Name:         "redhat",
+			EmailDomains: []string{"redhat.com"},
+		},
+		{
+			Name:        "apac",
+			Annotations: map[string]string{"toolchain.dev.openshift.com/requested-region": "apac"},
+		},
+		{
+			Name: "no-criteria",
+		},
+	}
+
+	t.Run("match by email domain", func(t *testing.T) {
+		rule := MatchRule(rules, "foo@RedHat.com", nil)
+		require.NotNil(t, rule)
+		assert.Equal(t, "redhat", rule.Name)
+	})
+
+	t.Run("match by annotation", func(t *testing.T) {
+		rule := MatchRule(rules, "foo@example.com", map[string]string{"toolchain.dev.openshift.com/requested-region": "apac"})
+		require.NotNil(t, rule)
+		assert.Equal(t, "apac", rule.Name)
+	})
+
+	t.Run("first matching rule wins", func(t *testing.T) {
+		rule := MatchRule(rules, "foo@redhat.com", map[string]string{"toolchain.dev.openshift.com/requested-region": "apac"})
+		require.NotNil(t, rule)
+		assert.Equal(t, "redhat", rule.Name)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		rule := MatchRule(rules, "foo@example.com", map[string]string{"toolchain.dev.openshift.com/requested-region": "emea"})
+		assert.Nil(t, rule)
+	})
+}
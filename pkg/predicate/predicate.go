@@ -0,0 +1,120 @@
+package predicate
+
+import (
+	"reflect"
+
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+	"sigs.k8s.io/kubefed/pkg/controller/util"
+)
+
+var log = logf.Log.WithName("predicate")
+
+// GenerationOrAnnotationsChangedPredicate implements an update predicate function which triggers a reconcile
+// when the generation (ie, the spec) or the annotations of the object changed.
+// Annotations are used to request some operations (eg: the promotion or the deactivation of a user) which are not part of the spec.
+// The changes of the IgnoredAnnotations (eg: the annotations set by the controller itself) do not trigger a reconcile.
+// When WatchedAnnotations are set, only the changes of these annotations trigger a reconcile.
+type GenerationOrAnnotationsChangedPredicate struct {
+	predicate.Funcs
+	IgnoredAnnotations []string
+	WatchedAnnotations []string
+}
+
+// Update implements default UpdateEvent filter for validating generation or annotations change
+func (p GenerationOrAnnotationsChangedPredicate) Update(e event.UpdateEvent) bool {
+	if e.MetaOld == nil {
+		log.Error(nil, "Update event has no old metadata", "event", e)
+		return false
+	}
+	if e.ObjectOld == nil {
+		log.Error(nil, "Update event has no old runtime object to update", "event", e)
+		return false
+	}
+	if e.ObjectNew == nil {
+		log.Error(nil, "Update event has no new runtime object for update", "event", e)
+		return false
+	}
+	if e.MetaNew == nil {
+		log.Error(nil, "Update event has no new metadata", "event", e)
+		return false
+	}
+	return e.MetaNew.GetGeneration() != e.MetaOld.GetGeneration() ||
+		!reflect.DeepEqual(p.relevantAnnotations(e.MetaNew.GetAnnotations()), p.relevantAnnotations(e.MetaOld.GetAnnotations()))
+}
+
+// relevantAnnotations returns the given annotations without the ignored ones (and only the watched ones, if any)
+func (p GenerationOrAnnotationsChangedPredicate) relevantAnnotations(annotations map[string]string) map[string]string {
+	relevant := make(map[string]string, len(annotations))
+	for key, value := range annotations {
+		relevant[key] = value
+	}
+	if len(p.WatchedAnnotations) > 0 {
+		relevant = make(map[string]string, len(p.WatchedAnnotations))
+		for _, key := range p.WatchedAnnotations {
+			if value, exists := annotations[key]; exists {
+				relevant[key] = value
+			}
+		}
+	}
+	for _, key := range p.IgnoredAnnotations {
+		delete(relevant, key)
+	}
+	return relevant
+}
+
+// OnDeletePredicate implements a predicate function which only triggers a reconcile when the object was deleted
+type OnDeletePredicate struct {
+	predicate.Funcs
+}
+
+// Create implements default CreateEvent filter to ignore all creations
+func (OnDeletePredicate) Create(_ event.CreateEvent) bool {
+	return false
+}
+
+// Update implements default UpdateEvent filter to ignore all updates
+func (OnDeletePredicate) Update(_ event.UpdateEvent) bool {
+	return false
+}
+
+// Generic implements default GenericEvent filter to ignore all generic events
+func (OnDeletePredicate) Generic(_ event.GenericEvent) bool {
+	return false
+}
+
+// ClusterBecameReadyPredicate implements a predicate function which only triggers a reconcile when a KubeFedCluster
+// became ready, ie, when its status changed from not ready to ready
+type ClusterBecameReadyPredicate struct {
+	predicate.Funcs
+}
+
+// Create implements default CreateEvent filter to ignore all creations
+func (ClusterBecameReadyPredicate) Create(_ event.CreateEvent) bool {
+	return false
+}
+
+// Update implements default UpdateEvent filter for validating the readiness change
+func (ClusterBecameReadyPredicate) Update(e event.UpdateEvent) bool {
+	oldCluster, ok := e.ObjectOld.(*v1beta1.KubeFedCluster)
+	if !ok {
+		return false
+	}
+	newCluster, ok := e.ObjectNew.(*v1beta1.KubeFedCluster)
+	if !ok {
+		return false
+	}
+	return !util.IsClusterReady(&oldCluster.Status) && util.IsClusterReady(&newCluster.Status)
+}
+
+// Delete implements default DeleteEvent filter to ignore all deletions
+func (ClusterBecameReadyPredicate) Delete(_ event.DeleteEvent) bool {
+	return false
+}
+
+// Generic implements default GenericEvent filter to ignore all generic events
+func (ClusterBecameReadyPredicate) Generic(_ event.GenericEvent) bool {
+	return false
+}
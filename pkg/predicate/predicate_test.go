@@ -0,0 +1,129 @@
+package predicate
+
+import (
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/kubefed/pkg/apis/core/common"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+)
+
+func TestGenerationOrAnnotationsChangedPredicate(t *testing.T) {
+	// given
+	p := GenerationOrAnnotationsChangedPredicate{}
+	newUpdateEvent := func(oldObj, newObj *toolchainv1alpha1.UserSignup) event.UpdateEvent {
+		return event.UpdateEvent{
+			MetaOld:   oldObj,
+			ObjectOld: oldObj,
+			MetaNew:   newObj,
+			ObjectNew: newObj,
+		}
+	}
+	newUserSignup := func(generation int64, annotations map[string]string) *toolchainv1alpha1.UserSignup {
+		return &toolchainv1alpha1.UserSignup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "foo",
+				Generation:  generation,
+				Annotations: annotations,
+			},
+		}
+	}
+
+	t.Run("generation changed", func(t *testing.T) {
+		assert.True(t, p.Update(newUpdateEvent(newUserSignup(1, nil), newUserSignup(2, nil))))
+	})
+
+	t.Run("annotations changed", func(t *testing.T) {
+		assert.True(t, p.Update(newUpdateEvent(newUserSignup(1, nil), newUserSignup(1, map[string]string{"foo": "bar"}))))
+		assert.True(t, p.Update(newUpdateEvent(newUserSignup(1, map[string]string{"foo": "bar"}), newUserSignup(1, map[string]string{"foo": "baz"}))))
+	})
+
+	t.Run("nothing changed", func(t *testing.T) {
+		assert.False(t, p.Update(newUpdateEvent(newUserSignup(1, map[string]string{"foo": "bar"}), newUserSignup(1, map[string]string{"foo": "bar"}))))
+	})
+
+	t.Run("ignored annotations changed", func(t *testing.T) {
+		p := GenerationOrAnnotationsChangedPredicate{IgnoredAnnotations: []string{"progress"}}
+		assert.False(t, p.Update(newUpdateEvent(newUserSignup(1, nil), newUserSignup(1, map[string]string{"progress": "1"}))))
+		assert.False(t, p.Update(newUpdateEvent(newUserSignup(1, map[string]string{"foo": "bar", "progress": "1"}), newUserSignup(1, map[string]string{"foo": "bar", "progress": "2"}))))
+		assert.True(t, p.Update(newUpdateEvent(newUserSignup(1, map[string]string{"progress": "1"}), newUserSignup(1, map[string]string{"foo": "bar", "progress": "2"}))))
+	})
+
+	t.Run("watched annotations changed", func(t *testing.T) {
+		p := GenerationOrAnnotationsChangedPredicate{WatchedAnnotations: []string{"url"}}
+		assert.True(t, p.Update(newUpdateEvent(newUserSignup(1, nil), newUserSignup(1, map[string]string{"url": "foo"}))))
+		assert.True(t, p.Update(newUpdateEvent(newUserSignup(1, map[string]string{"url": "foo"}), newUserSignup(1, nil))))
+		assert.True(t, p.Update(newUpdateEvent(newUserSignup(1, nil), newUserSignup(2, nil))))
+		assert.False(t, p.Update(newUpdateEvent(newUserSignup(1, map[string]string{"url": "foo"}), newUserSignup(1, map[string]string{"url": "foo", "progress": "1"}))))
+	})
+
+	t.Run("missing metadata", func(t *testing.T) {
+		assert.False(t, p.Update(event.UpdateEvent{}))
+	})
+}
+
+func TestOnDeletePredicate(t *testing.T) {
+	// given
+	p := OnDeletePredicate{}
+	obj := &toolchainv1alpha1.MasterUserRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "foo",
+		},
+	}
+
+	// then
+	assert.False(t, p.Create(event.CreateEvent{Meta: obj, Object: obj}))
+	assert.False(t, p.Update(event.UpdateEvent{MetaOld: obj, ObjectOld: obj, MetaNew: obj, ObjectNew: obj}))
+	assert.False(t, p.Generic(event.GenericEvent{Meta: obj, Object: obj}))
+	assert.True(t, p.Delete(event.DeleteEvent{Meta: obj, Object: obj}))
+}
+
+func TestClusterBecameReadyPredicate(t *testing.T) {
+	// given
+	p := ClusterBecameReadyPredicate{}
+	newKubeFedCluster := func(status corev1.ConditionStatus) *v1beta1.KubeFedCluster {
+		return &v1beta1.KubeFedCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "member",
+			},
+			Status: v1beta1.KubeFedClusterStatus{
+				Conditions: []v1beta1.ClusterCondition{{
+					Type:   common.ClusterReady,
+					Status: status,
+				}},
+			},
+		}
+	}
+	newUpdateEvent := func(oldObj, newObj *v1beta1.KubeFedCluster) event.UpdateEvent {
+		return event.UpdateEvent{
+			MetaOld:   oldObj,
+			ObjectOld: oldObj,
+			MetaNew:   newObj,
+			ObjectNew: newObj,
+		}
+	}
+
+	t.Run("cluster became ready", func(t *testing.T) {
+		assert.True(t, p.Update(newUpdateEvent(newKubeFedCluster(corev1.ConditionFalse), newKubeFedCluster(corev1.ConditionTrue))))
+	})
+
+	t.Run("cluster is still ready", func(t *testing.T) {
+		assert.False(t, p.Update(newUpdateEvent(newKubeFedCluster(corev1.ConditionTrue), newKubeFedCluster(corev1.ConditionTrue))))
+	})
+
+	t.Run("cluster became not ready", func(t *testing.T) {
+		assert.False(t, p.Update(newUpdateEvent(newKubeFedCluster(corev1.ConditionTrue), newKubeFedCluster(corev1.ConditionFalse))))
+	})
+
+	t.Run("other events", func(t *testing.T) {
+		obj := newKubeFedCluster(corev1.ConditionTrue)
+		assert.False(t, p.Create(event.CreateEvent{Meta: obj, Object: obj}))
+		assert.False(t, p.Delete(event.DeleteEvent{Meta: obj, Object: obj}))
+		assert.False(t, p.Generic(event.GenericEvent{Meta: obj, Object: obj}))
+	})
+}
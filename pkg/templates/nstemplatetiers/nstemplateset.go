@@ -0,0 +1,21 @@
+package nstemplatetiers
+
+import (
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+)
+
+// NewNSTemplateSet returns the NSTemplateSet spec to embed in a UserAccount for the given NSTemplateTier,
+// ie, the name of the tier along with the type and the revision of each of its namespaces
+func NewNSTemplateSet(tier toolchainv1alpha1.NSTemplateTier) toolchainv1alpha1.NSTemplateSetSpec {
+	namespaces := make([]toolchainv1alpha1.NSTemplateSetNamespace, len(tier.Spec.Namespaces))
+	for i, ns := range tier.Spec.Namespaces {
+		namespaces[i] = toolchainv1alpha1.NSTemplateSetNamespace{
+			Type:     ns.Type,
+			Revision: ns.Revision,
+		}
+	}
+	return toolchainv1alpha1.NSTemplateSetSpec{
+		TierName:   tier.Name,
+		Namespaces: namespaces,
+	}
+}
@@ -0,0 +1,56 @@
+package nstemplatetiers_test
+
+import (
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/templates/nstemplatetiers"
+
+	templatev1 "github.com/openshift/api/template/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewNSTemplateSet(t *testing.T) {
+	// given
+	tier := toolchainv1alpha1.NSTemplateTier{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "advanced",
+		},
+		Spec: toolchainv1alpha1.NSTemplateTierSpec{
+			Namespaces: []toolchainv1alpha1.NSTemplateTierNamespace{
+				{
+					Type:     "code",
+					Revision: "123456a",
+					Template: templatev1.Template{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "advanced-code",
+						},
+					},
+				},
+				{
+					Type:     "dev",
+					Revision: "123456b",
+				},
+			},
+		},
+	}
+
+	// when
+	nsTemplateSet := nstemplatetiers.NewNSTemplateSet(tier)
+
+	// then
+	assert.Equal(t, toolchainv1alpha1.NSTemplateSetSpec{
+		TierName: "advanced",
+		Namespaces: []toolchainv1alpha1.NSTemplateSetNamespace{
+			{
+				Type:     "code",
+				Revision: "123456a",
+			},
+			{
+				Type:     "dev",
+				Revision: "123456b",
+			},
+		},
+	}, nsTemplateSet)
+}